@@ -38,17 +38,49 @@ var (
 		"oc-binary",
 		"oc binary to use",
 	).Default("oc").String()
+	backendFlag = app.Flag(
+		"backend",
+		"How to talk to the cluster: via the oc binary (oc) or directly via the API server using kubeconfig credentials (api).",
+	).Default("oc").Enum("oc", "api")
+	kubeconfigFlag = app.Flag(
+		"kubeconfig",
+		"Path to kubeconfig file used by the api backend (defaults to $KUBECONFIG or ~/.kube/config).",
+	).String()
+	localProcessingFlag = app.Flag(
+		"local-processing",
+		"Process templates in-process instead of via 'oc process' (or the API server).",
+	).Bool()
+	kindFlag = app.Flag(
+		"kind",
+		"Additional kind(s) to manage, e.g. NetworkPolicy (repeatable or comma-separated). Unless served by the cluster, aliases can be given as Kind:alias.",
+	).PlaceHolder("NetworkPolicy:netpol").Strings()
+	ownerFlag = app.Flag(
+		"owner",
+		"ID of this Tailor instance. If given, managed resources are marked with it, and only resources carrying the marker are deleted.",
+	).String()
+	ownerKeyFlag = app.Flag(
+		"owner-key",
+		"Key of the label (or annotation) marking owned resources.",
+	).Default("tailor.opendevstack.org/owner").String()
+	ownerAnnotationFlag = app.Flag(
+		"owner-annotation",
+		"Mark owned resources with an annotation instead of a label.",
+	).Bool()
 	fileFlag = app.Flag(
 		"file",
 		"Tailorfile with flags.",
 	).Short('f').Default("Tailorfile").String()
+	envFlag = app.Flag(
+		"env",
+		"Profile of the Tailorfile to use (e.g. prod for the settings below '[prod]').",
+	).String()
 	forceFlag = app.Flag(
 		"force",
 		"Force to continue despite warning (e.g. deleting all resources).",
 	).Bool()
 	namespaceFlag = app.Flag(
 		"namespace",
-		"Namespace (omit to use current)",
+		"Namespace (omit to use current). diff and apply also accept a comma-separated list or a glob (e.g. 'tenant-*').",
 	).Short('n').String()
 	selectorFlag = app.Flag(
 		"selector",
@@ -84,10 +116,23 @@ var (
 		"Show version",
 	)
 
+	configCommand = app.Command(
+		"config",
+		"Show the resolved options of a command and their origin instead of running it, e.g. 'tailor config apply -n foo'",
+	)
+
 	diffCommand = app.Command(
 		"diff",
 		"Show diff between remote and local",
 	).Alias("status")
+	diffNamespaceSelectorFlag = diffCommand.Flag(
+		"namespace-selector",
+		"Compare all namespaces matching this label selector (can be combined with a list or glob of namespaces).",
+	).String()
+	diffNamespaceParallelismFlag = diffCommand.Flag(
+		"namespace-parallelism",
+		"Number of namespaces to compare concurrently if several namespaces are targeted.",
+	).Default("1").String()
 	diffLabelsFlag = diffCommand.Flag(
 		"labels",
 		"Label to set in all resources for this template.",
@@ -106,7 +151,7 @@ var (
 	).PlaceHolder("bc:foobar:/spec/output/to/name").Strings()
 	diffPreservePathFlag = diffCommand.Flag(
 		"preserve",
-		"Path(s) per kind/name for which to preserve current state (e.g. because they are externally modified) in RFC 6901 format.",
+		"Path(s) per kind/name for which to preserve current state (e.g. because they are externally modified) in RFC 6901 format, optionally with * segments and selectors such as containers[name=app].",
 	).PlaceHolder("bc:foobar:/spec/output/to/name").Strings()
 	diffPreserveImmutableFieldsFlag = diffCommand.Flag(
 		"preserve-immutable-fields",
@@ -124,10 +169,34 @@ var (
 		"allow-recreate",
 		"Allow to recreate the whole resource when an immutable field is changed.",
 	).Bool()
+	diffAllowProtectedDeletionFlag = diffCommand.Flag(
+		"allow-protected-deletion",
+		"Allow to delete or recreate resources annotated with tailor.opendevstack.org/protect=true.",
+	).Bool()
 	diffRevealSecretsFlag = diffCommand.Flag(
 		"reveal-secrets",
 		"Reveal drift of Secret resources (might show secret values in clear text).",
 	).Bool()
+	diffRedactSecretsFlag = diffCommand.Flag(
+		"redact-secrets",
+		"Show drift of Secret resources with values replaced by salted hashes.",
+	).Bool()
+	diffServerDryRunFlag = diffCommand.Flag(
+		"server-dry-run",
+		"Validate changes to create or update with a server-side dry-run apply.",
+	).Bool()
+	diffSchemaFlag = diffCommand.Flag(
+		"schema",
+		"Validate processed templates against an OpenAPI schema before comparing, either a bundled one (openshift-3.11, openshift-4) or a local file.",
+	).String()
+	diffOutputFlag = diffCommand.Flag(
+		"output",
+		"Output format of the drift (text or json).",
+	).Default("text").Enum("text", "json")
+	diffOutPlanFlag = diffCommand.Flag(
+		"out-plan",
+		"Write the calculated changes to a plan file, which can be applied later via 'apply --plan'.",
+	).PlaceHolder("tailor.plan").String()
 	diffResourceArg = diffCommand.Arg(
 		"resource", "Remote resource (defaults to all)",
 	).String()
@@ -136,6 +205,14 @@ var (
 		"apply",
 		"Update remote with local",
 	).Alias("update")
+	applyNamespaceSelectorFlag = applyCommand.Flag(
+		"namespace-selector",
+		"Apply to all namespaces matching this label selector (can be combined with a list or glob of namespaces).",
+	).String()
+	applyNamespaceParallelismFlag = applyCommand.Flag(
+		"namespace-parallelism",
+		"Number of namespaces to compare concurrently if several namespaces are targeted (changes are applied one namespace after another).",
+	).Default("1").String()
 	applyLabelsFlag = applyCommand.Flag(
 		"labels",
 		"Label to set in all resources for this template.",
@@ -154,7 +231,7 @@ var (
 	).PlaceHolder("bc:foobar:/spec/output/to/name").Strings()
 	applyPreservePathFlag = applyCommand.Flag(
 		"preserve",
-		"Path(s) per kind for which to preserve current state (e.g. because they are externally modified) in RFC 6901 format.",
+		"Path(s) per kind for which to preserve current state (e.g. because they are externally modified) in RFC 6901 format, optionally with * segments and selectors such as containers[name=app].",
 	).PlaceHolder("bc:foobar:/spec/output/to/name").Strings()
 	applyPreserveImmutableFieldsFlag = applyCommand.Flag(
 		"preserve-immutable-fields",
@@ -172,18 +249,150 @@ var (
 		"allow-recreate",
 		"Allow to recreate the whole resource when an immutable field is changed.",
 	).Bool()
+	applyAllowProtectedDeletionFlag = applyCommand.Flag(
+		"allow-protected-deletion",
+		"Allow to delete or recreate resources annotated with tailor.opendevstack.org/protect=true.",
+	).Bool()
+	applyMaxDeletionsFlag = applyCommand.Flag(
+		"max-deletions",
+		"Abort if more resources would be deleted, either a number (e.g. 5) or a percentage of the targeted resources (e.g. 10%).",
+	).String()
+	applyParallelismFlag = applyCommand.Flag(
+		"parallelism",
+		"Number of changes to apply concurrently. Changes depending on each other are still applied one after another.",
+	).Default("1").String()
+	applyContinueOnErrorFlag = applyCommand.Flag(
+		"continue-on-error",
+		"Attempt all changes even if some of them fail, and print a result per change at the end.",
+	).Bool()
+	applyReportFlag = applyCommand.Flag(
+		"report",
+		"Write the result of each change as JSON to given file.",
+	).PlaceHolder("report.json").String()
+	applySchemaFlag = applyCommand.Flag(
+		"schema",
+		"Validate processed templates against an OpenAPI schema before comparing, either a bundled one (openshift-3.11, openshift-4) or a local file.",
+	).String()
 	applyRevealSecretsFlag = applyCommand.Flag(
 		"reveal-secrets",
 		"Reveal drift of Secret resources (might show secret values in clear text).",
 	).Bool()
+	applyRedactSecretsFlag = applyCommand.Flag(
+		"redact-secrets",
+		"Show drift of Secret resources with values replaced by salted hashes.",
+	).Bool()
 	applyVerifyFlag = applyCommand.Flag(
 		"verify",
 		"Verify if resources are in sync after changes are applied.",
 	).Bool()
+	applyWaitFlag = applyCommand.Flag(
+		"wait",
+		"Wait for the rollout of created or updated workloads (DeploymentConfig, Deployment, StatefulSet, DaemonSet) after changes are applied.",
+	).Bool()
+	applyWaitTimeoutFlag = applyCommand.Flag(
+		"wait-timeout",
+		"How long to wait for rollouts when --wait is given.",
+	).Default("5m").String()
+	applyBackupDirFlag = applyCommand.Flag(
+		"backup-dir",
+		"Directory to save a snapshot of the current state of all touched resources to before changes are applied.",
+	).Default(cli.DefaultBackupDir()).String()
+	applyPlanFlag = applyCommand.Flag(
+		"plan",
+		"Apply exactly the changes of a plan file created by 'diff --out-plan'. Refuses to run if targeted resources changed in the meantime.",
+	).PlaceHolder("tailor.plan").String()
 	applyResourceArg = applyCommand.Arg(
 		"resource", "Remote resource (defaults to all)",
 	).String()
 
+	renderCommand = app.Command(
+		"render",
+		"Print processed templates (desired state)",
+	)
+	renderLabelsFlag = renderCommand.Flag(
+		"labels",
+		"Label to set in all resources for this template.",
+	).String()
+	renderParamFlag = renderCommand.Flag(
+		"param",
+		"Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template.",
+	).Strings()
+	renderParamFileFlag = renderCommand.Flag(
+		"param-file",
+		"File(s) containing template parameter values to set/override in the template.",
+	).Strings()
+	renderIgnoreUnknownParametersFlag = renderCommand.Flag(
+		"ignore-unknown-parameters",
+		"If true, will not stop processing if a provided parameter does not exist in the template.",
+	).Bool()
+	renderFormatFlag = renderCommand.Flag(
+		"format",
+		"Output format of the rendered resources (yaml or json).",
+	).Default("yaml").Enum("yaml", "json")
+	renderOutputDirFlag = renderCommand.Flag(
+		"output-dir",
+		"Write one file per resource into this directory instead of printing to STDOUT.",
+	).String()
+	renderResourceArg = renderCommand.Arg(
+		"resource", "Local resource (defaults to all)",
+	).String()
+
+	validateCommand = app.Command(
+		"validate",
+		"Validate processed templates (desired state) against an OpenAPI schema",
+	)
+	validateLabelsFlag = validateCommand.Flag(
+		"labels",
+		"Label to set in all resources for this template.",
+	).String()
+	validateParamFlag = validateCommand.Flag(
+		"param",
+		"Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template.",
+	).Strings()
+	validateParamFileFlag = validateCommand.Flag(
+		"param-file",
+		"File(s) containing template parameter values to set/override in the template.",
+	).Strings()
+	validateIgnoreUnknownParametersFlag = validateCommand.Flag(
+		"ignore-unknown-parameters",
+		"If true, will not stop processing if a provided parameter does not exist in the template.",
+	).Bool()
+	validateSchemaFlag = validateCommand.Flag(
+		"schema",
+		"OpenAPI schema to validate against, either a bundled one (openshift-3.11, openshift-4) or a local file. Defaults to openshift-4.",
+	).String()
+	validateResourceArg = validateCommand.Arg(
+		"resource", "Local resource (defaults to all)",
+	).String()
+
+	rollbackCommand = app.Command(
+		"rollback",
+		"Restore a snapshot taken before apply. A rollback takes a snapshot itself, which then is the latest one, so 'rollback latest' run twice undoes the first rollback",
+	)
+	rollbackBackupDirFlag = rollbackCommand.Flag(
+		"backup-dir",
+		"Directory containing the snapshots.",
+	).Default(cli.DefaultBackupDir()).String()
+	rollbackRevealSecretsFlag = rollbackCommand.Flag(
+		"reveal-secrets",
+		"Reveal drift of Secret resources (might show secret values in clear text).",
+	).Bool()
+	rollbackRedactSecretsFlag = rollbackCommand.Flag(
+		"redact-secrets",
+		"Show drift of Secret resources with values replaced by salted hashes.",
+	).Bool()
+	rollbackSnapshotArg = rollbackCommand.Arg(
+		"snapshot", "Snapshot to restore, either a file or 'latest' (lists available snapshots if omitted)",
+	).String()
+
+	adoptCommand = app.Command(
+		"adopt",
+		"Mark existing resources as owned by this Tailor instance (see --owner)",
+	)
+	adoptResourceArg = adoptCommand.Arg(
+		"resource", "Remote resource (defaults to all)",
+	).String()
+
 	exportCommand = app.Command(
 		"export",
 		"Export remote state as template",
@@ -200,6 +409,14 @@ var (
 		"trim-annotation",
 		"Annotation (prefix) to trim on top of annotations trimmed by default. ",
 	).PlaceHolder("template.openshift.io/").Strings()
+	exportOutputDirFlag = exportCommand.Flag(
+		"output-dir",
+		"Write one template per group (see --group-by) plus a param file skeleton for each into this directory instead of printing to STDOUT.",
+	).String()
+	exportGroupByFlag = exportCommand.Flag(
+		"group-by",
+		"How to group resources into templates with --output-dir: by app label (app) or by kind (kind).",
+	).Default("app").Enum("app", "kind")
 	exportResourceArg = exportCommand.Arg(
 		"resource", "Remote resource (defaults to all)",
 	).String()
@@ -255,7 +472,25 @@ func main() {
 		}
 	}()
 
-	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+	// "tailor config <command> [<flags>]" shows the resolved options of the
+	// command instead of running it.
+	args := os.Args[1:]
+	showConfig := len(args) > 0 && args[0] == configCommand.FullCommand()
+	if showConfig {
+		args = args[1:]
+		if len(args) == 0 {
+			log.Fatalln("Pass the command to show the options of, e.g. 'tailor config apply'.")
+		}
+	}
+
+	command := kingpin.MustParse(app.Parse(args))
+
+	if command == configCommand.FullCommand() {
+		log.Fatalln("Pass the command to show the options of directly after config, e.g. 'tailor config apply'.")
+	}
+	if showConfig && !configurableCommand(command) {
+		log.Fatalf("Options of '%s' cannot be shown.\n", command)
+	}
 
 	if command == versionCommand.FullCommand() {
 		fmt.Println(Version)
@@ -266,17 +501,33 @@ func main() {
 	if command == editCommand.FullCommand() ||
 		command == revealCommand.FullCommand() ||
 		command == reEncryptCommand.FullCommand() ||
-		command == generateKeyCommand.FullCommand() {
+		command == generateKeyCommand.FullCommand() ||
+		command == renderCommand.FullCommand() ||
+		command == validateCommand.FullCommand() {
+		// render and validate require a cluster only without local
+		// processing, which is checked by their options.
+		clusterRequired = false
+	}
+	if showConfig {
 		clusterRequired = false
 	}
 
 	globalOptions, err := cli.NewGlobalOptions(
 		clusterRequired,
+		command,
 		*fileFlag,
+		*envFlag,
 		*verboseFlag,
 		*debugFlag,
 		*nonInteractiveFlag,
 		*ocBinaryFlag,
+		*backendFlag,
+		*kubeconfigFlag,
+		*localProcessingFlag,
+		*kindFlag,
+		*ownerFlag,
+		*ownerKeyFlag,
+		*ownerAnnotationFlag,
 		*forceFlag,
 	)
 	if err != nil {
@@ -295,6 +546,10 @@ func main() {
 		if err != nil {
 			log.Fatalln("Options could not be processed:", err)
 		}
+		if showConfig {
+			printConfig(globalOptions, args, secretsOptions)
+			return
+		}
 		err = commands.Edit(secretsOptions, *editFileArg)
 		if err != nil {
 			log.Fatalf("Failed to edit file: %s.", err)
@@ -311,6 +566,10 @@ func main() {
 		if err != nil {
 			log.Fatalln("Options could not be processed:", err)
 		}
+		if showConfig {
+			printConfig(globalOptions, args, secretsOptions)
+			return
+		}
 		err = commands.ReEncrypt(secretsOptions, *reEncryptFileArg)
 		if err != nil {
 			log.Fatalf("Failed to re-encrypt: %s.", err)
@@ -327,6 +586,10 @@ func main() {
 		if err != nil {
 			log.Fatalln("Options could not be processed:", err)
 		}
+		if showConfig {
+			printConfig(globalOptions, args, secretsOptions)
+			return
+		}
 		err = commands.Reveal(secretsOptions, *revealFileArg)
 		if err != nil {
 			log.Fatalf("Failed to reveal file: %s.", err)
@@ -343,6 +606,10 @@ func main() {
 		if err != nil {
 			log.Fatalln("Options could not be processed:", err)
 		}
+		if showConfig {
+			printConfig(globalOptions, args, secretsOptions)
+			return
+		}
 		err = commands.GenerateKey(secretsOptions, *generateKeyEmailArg, *generateKeyNameFlag)
 		if err != nil {
 			log.Fatalf("Failed to generate keypair: %s.", err)
@@ -351,33 +618,48 @@ func main() {
 	case diffCommand.FullCommand():
 		preservePathFlag := *diffPreservePathFlag
 		preservePathFlag = append(preservePathFlag, *diffIgnorePathFlag...)
-		compareOptions, err := cli.NewCompareOptions(
-			globalOptions,
-			*namespaceFlag,
-			*selectorFlag,
-			*excludeFlag,
-			*templateDirFlag,
-			*paramDirFlag,
-			*publicKeyDirFlag,
-			*privateKeyFlag,
-			*passphraseFlag,
-			*diffLabelsFlag,
-			*diffParamFlag,
-			*diffParamFileFlag,
-			preservePathFlag,
-			*diffPreserveImmutableFieldsFlag,
-			*diffIgnoreUnknownParametersFlag,
-			*diffUpsertOnlyFlag,
-			*diffAllowRecreateFlag,
-			*diffRevealSecretsFlag,
-			false, // verification only when changes are applied
-			*diffResourceArg,
-		)
+		compareOptions, err := cli.NewCompareOptions(globalOptions, &cli.CompareFlags{
+			Namespace:               *namespaceFlag,
+			NamespaceSelector:       *diffNamespaceSelectorFlag,
+			Selector:                *selectorFlag,
+			Excludes:                *excludeFlag,
+			TemplateDir:             *templateDirFlag,
+			ParamDir:                *paramDirFlag,
+			PrivateKey:              *privateKeyFlag,
+			Passphrase:              *passphraseFlag,
+			Labels:                  *diffLabelsFlag,
+			Params:                  *diffParamFlag,
+			ParamFiles:              *diffParamFileFlag,
+			PreservePaths:           preservePathFlag,
+			PreserveImmutableFields: *diffPreserveImmutableFieldsFlag,
+			IgnoreUnknownParameters: *diffIgnoreUnknownParametersFlag,
+			UpsertOnly:              *diffUpsertOnlyFlag,
+			AllowRecreate:           *diffAllowRecreateFlag,
+			AllowProtectedDeletion:  *diffAllowProtectedDeletionFlag,
+			RevealSecrets:           *diffRevealSecretsFlag,
+			RedactSecrets:           *diffRedactSecretsFlag,
+			ServerDryRun:            *diffServerDryRunFlag,
+			NamespaceParallelism:    *diffNamespaceParallelismFlag,
+			Schema:                  *diffSchemaFlag,
+			Output:                  *diffOutputFlag,
+			OutPlan:                 *diffOutPlanFlag,
+			Resource:                *diffResourceArg,
+		})
 		if err != nil {
 			log.Fatalln("Options could not be processed:", err)
 		}
 
-		driftDectected, err := commands.Diff(compareOptions)
+		if showConfig {
+			printConfig(globalOptions, args, compareOptions)
+			return
+		}
+
+		var driftDectected bool
+		if len(compareOptions.Namespaces) > 0 {
+			driftDectected, err = commands.DiffNamespaces(compareOptions)
+		} else {
+			driftDectected, err = commands.Diff(compareOptions)
+		}
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -388,33 +670,67 @@ func main() {
 	case applyCommand.FullCommand():
 		preservePathFlag := *applyPreservePathFlag
 		preservePathFlag = append(preservePathFlag, *applyIgnorePathFlag...)
-		compareOptions, err := cli.NewCompareOptions(
-			globalOptions,
-			*namespaceFlag,
-			*selectorFlag,
-			*excludeFlag,
-			*templateDirFlag,
-			*paramDirFlag,
-			*publicKeyDirFlag,
-			*privateKeyFlag,
-			*passphraseFlag,
-			*applyLabelsFlag,
-			*applyParamFlag,
-			*applyParamFileFlag,
-			preservePathFlag,
-			*applyPreserveImmutableFieldsFlag,
-			*applyIgnoreUnknownParametersFlag,
-			*applyUpsertOnlyFlag,
-			*applyAllowRecreateFlag,
-			*applyRevealSecretsFlag,
-			*applyVerifyFlag,
-			*applyResourceArg,
-		)
+		compareOptions, err := cli.NewCompareOptions(globalOptions, &cli.CompareFlags{
+			Namespace:               *namespaceFlag,
+			NamespaceSelector:       *applyNamespaceSelectorFlag,
+			Selector:                *selectorFlag,
+			Excludes:                *excludeFlag,
+			TemplateDir:             *templateDirFlag,
+			ParamDir:                *paramDirFlag,
+			PrivateKey:              *privateKeyFlag,
+			Passphrase:              *passphraseFlag,
+			Labels:                  *applyLabelsFlag,
+			Params:                  *applyParamFlag,
+			ParamFiles:              *applyParamFileFlag,
+			PreservePaths:           preservePathFlag,
+			PreserveImmutableFields: *applyPreserveImmutableFieldsFlag,
+			IgnoreUnknownParameters: *applyIgnoreUnknownParametersFlag,
+			UpsertOnly:              *applyUpsertOnlyFlag,
+			AllowRecreate:           *applyAllowRecreateFlag,
+			AllowProtectedDeletion:  *applyAllowProtectedDeletionFlag,
+			RevealSecrets:           *applyRevealSecretsFlag,
+			RedactSecrets:           *applyRedactSecretsFlag,
+			Verify:                  *applyVerifyFlag,
+			Wait:                    *applyWaitFlag,
+			ContinueOnError:         *applyContinueOnErrorFlag,
+			WaitTimeout:             *applyWaitTimeoutFlag,
+			BackupDir:               *applyBackupDirFlag,
+			MaxDeletions:            *applyMaxDeletionsFlag,
+			Parallelism:             *applyParallelismFlag,
+			NamespaceParallelism:    *applyNamespaceParallelismFlag,
+			Report:                  *applyReportFlag,
+			Schema:                  *applySchemaFlag,
+			Plan:                    *applyPlanFlag,
+			Resource:                *applyResourceArg,
+		})
 		if err != nil {
 			log.Fatalln("Options could not be processed:", err)
 		}
 
-		ocClient := cli.NewOcClient(compareOptions.Namespace)
+		if showConfig {
+			printConfig(globalOptions, args, compareOptions)
+			return
+		}
+
+		if len(compareOptions.Namespaces) > 0 {
+			driftDectected, err := commands.ApplyNamespaces(
+				globalOptions.NonInteractive,
+				compareOptions,
+				os.Stdin,
+			)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if driftDectected {
+				os.Exit(3)
+			}
+			return
+		}
+
+		ocClient, err := cli.NewClient(compareOptions.Namespace)
+		if err != nil {
+			log.Fatalln(err)
+		}
 		driftDectected, err := commands.Apply(
 			globalOptions.NonInteractive,
 			compareOptions,
@@ -428,6 +744,135 @@ func main() {
 			os.Exit(3)
 		}
 
+	case renderCommand.FullCommand():
+		compareOptions, err := cli.NewCompareOptions(globalOptions, &cli.CompareFlags{
+			Namespace:               *namespaceFlag,
+			Selector:                *selectorFlag,
+			Excludes:                *excludeFlag,
+			TemplateDir:             *templateDirFlag,
+			ParamDir:                *paramDirFlag,
+			PrivateKey:              *privateKeyFlag,
+			Passphrase:              *passphraseFlag,
+			Labels:                  *renderLabelsFlag,
+			Params:                  *renderParamFlag,
+			ParamFiles:              *renderParamFileFlag,
+			IgnoreUnknownParameters: *renderIgnoreUnknownParametersFlag,
+			Resource:                *renderResourceArg,
+		})
+		if err != nil {
+			log.Fatalln("Options could not be processed:", err)
+		}
+		if showConfig {
+			printConfig(globalOptions, args, compareOptions)
+			return
+		}
+		renderOptions, err := cli.NewRenderOptions(
+			compareOptions,
+			*renderFormatFlag,
+			*renderOutputDirFlag,
+		)
+		if err != nil {
+			log.Fatalln("Options could not be processed:", err)
+		}
+		err = commands.Render(renderOptions)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+	case validateCommand.FullCommand():
+		compareOptions, err := cli.NewCompareOptions(globalOptions, &cli.CompareFlags{
+			Namespace:               *namespaceFlag,
+			Selector:                *selectorFlag,
+			Excludes:                *excludeFlag,
+			TemplateDir:             *templateDirFlag,
+			ParamDir:                *paramDirFlag,
+			PrivateKey:              *privateKeyFlag,
+			Passphrase:              *passphraseFlag,
+			Labels:                  *validateLabelsFlag,
+			Params:                  *validateParamFlag,
+			ParamFiles:              *validateParamFileFlag,
+			IgnoreUnknownParameters: *validateIgnoreUnknownParametersFlag,
+			Schema:                  *validateSchemaFlag,
+			Resource:                *validateResourceArg,
+		})
+		if err != nil {
+			log.Fatalln("Options could not be processed:", err)
+		}
+		if showConfig {
+			printConfig(globalOptions, args, compareOptions)
+			return
+		}
+		validateOptions, err := cli.NewValidateOptions(compareOptions)
+		if err != nil {
+			log.Fatalln("Options could not be processed:", err)
+		}
+		err = commands.Validate(validateOptions)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+	case rollbackCommand.FullCommand():
+		compareOptions, err := cli.NewCompareOptions(globalOptions, &cli.CompareFlags{
+			Namespace:     *namespaceFlag,
+			Selector:      *selectorFlag,
+			Excludes:      *excludeFlag,
+			TemplateDir:   *templateDirFlag,
+			ParamDir:      *paramDirFlag,
+			PrivateKey:    *privateKeyFlag,
+			Passphrase:    *passphraseFlag,
+			RevealSecrets: *rollbackRevealSecretsFlag,
+			RedactSecrets: *rollbackRedactSecretsFlag,
+			BackupDir:     *rollbackBackupDirFlag,
+		})
+		if err != nil {
+			log.Fatalln("Options could not be processed:", err)
+		}
+		rollbackOptions, err := cli.NewRollbackOptions(
+			compareOptions,
+			*rollbackSnapshotArg,
+		)
+		if err != nil {
+			log.Fatalln("Options could not be processed:", err)
+		}
+		ocClient, err := cli.NewClient(rollbackOptions.Namespace)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = commands.Rollback(
+			globalOptions.NonInteractive,
+			rollbackOptions,
+			ocClient,
+			os.Stdin,
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+	case adoptCommand.FullCommand():
+		adoptOptions, err := cli.NewAdoptOptions(
+			globalOptions,
+			*namespaceFlag,
+			*selectorFlag,
+			*excludeFlag,
+			*adoptResourceArg,
+		)
+		if err != nil {
+			log.Fatalln("Options could not be processed:", err)
+		}
+		ocClient, err := cli.NewClient(adoptOptions.Namespace)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = commands.Adopt(
+			globalOptions.NonInteractive,
+			adoptOptions,
+			ocClient,
+			os.Stdin,
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
 	case exportCommand.FullCommand():
 		exportOptions, err := cli.NewExportOptions(
 			globalOptions,
@@ -439,14 +884,74 @@ func main() {
 			*exportWithAnnotationsFlag,
 			*exportWithHardcodedNamespaceFlag,
 			*exportTrimAnnotationFlag,
+			*exportOutputDirFlag,
+			*exportGroupByFlag,
 			*exportResourceArg,
 		)
 		if err != nil {
 			log.Fatalln("Options could not be processed:", err)
 		}
+		if showConfig {
+			printConfig(globalOptions, args, exportOptions)
+			return
+		}
 		err = commands.Export(exportOptions)
 		if err != nil {
 			log.Fatalln(err)
 		}
 	}
 }
+
+// configurableCommand returns true if "tailor config" can show the options
+// of given command.
+func configurableCommand(command string) bool {
+	for _, c := range []*kingpin.CmdClause{
+		diffCommand, applyCommand, renderCommand, validateCommand, exportCommand,
+		editCommand, reEncryptCommand, revealCommand, generateKeyCommand,
+	} {
+		if command == c.FullCommand() {
+			return true
+		}
+	}
+	return false
+}
+
+// flagOrigins returns the origin of each flag given in args or via an
+// environment variable.
+func flagOrigins(args []string) map[string]string {
+	origins := map[string]string{}
+	context, err := app.ParseContext(args)
+	if err != nil {
+		return origins
+	}
+	for _, element := range context.Elements {
+		if flag, ok := element.Clause.(*kingpin.FlagClause); ok {
+			origins[flag.Model().Name] = cli.FlagOrigin(flag.Model().Name, "")
+		}
+	}
+	flags := app.Model().Flags
+	if context.SelectedCommand != nil {
+		flags = append(flags, context.SelectedCommand.Model().Flags...)
+	}
+	for _, flag := range flags {
+		if _, ok := origins[flag.Name]; ok || len(flag.Envar) == 0 {
+			continue
+		}
+		if _, ok := os.LookupEnv(flag.Envar); ok {
+			origins[flag.Name] = cli.FlagOrigin(flag.Name, flag.Envar)
+		}
+	}
+	return origins
+}
+
+// printConfig prints the resolved options instead of running the command.
+func printConfig(globalOptions *cli.GlobalOptions, args []string, options interface{}) {
+	configOptions, err := cli.NewConfigOptions(globalOptions, *namespaceFlag, flagOrigins(args), options)
+	if err != nil {
+		log.Fatalln("Options could not be processed:", err)
+	}
+	err = commands.Config(configOptions)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
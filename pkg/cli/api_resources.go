@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIResource describes a kind of resource served by the cluster.
+type APIResource struct {
+	Kind string
+	// Name is the plural name of the resource, e.g. "networkpolicies".
+	Name string
+	// GroupVersion is e.g. "networking.k8s.io/v1". Old "oc" versions only
+	// report the group.
+	GroupVersion string
+	ShortNames   []string
+	Namespaced   bool
+}
+
+// OcClientDiscoverer allows to discover the resources served by the cluster.
+type OcClientDiscoverer interface {
+	APIResources() ([]APIResource, error)
+}
+
+// APIResources returns the resources served by the cluster, as reported by
+// "oc api-resources".
+func (c *OcClient) APIResources() ([]APIResource, error) {
+	cmd := c.execPlainOcCmd([]string{"api-resources"})
+	outBytes, errBytes, err := c.runCmd(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to discover API resources: %s", strings.TrimSpace(string(errBytes)))
+	}
+	return parseAPIResourcesTable(string(outBytes))
+}
+
+// parseAPIResourcesTable parses the table printed by "oc api-resources".
+// Columns are located via the header, as the SHORTNAMES column may be blank.
+func parseAPIResourcesTable(table string) ([]APIResource, error) {
+	lines := strings.Split(strings.TrimRight(table, "\n"), "\n")
+	if len(lines) == 0 || len(strings.TrimSpace(lines[0])) == 0 {
+		return []APIResource{}, nil
+	}
+	header := lines[0]
+	columns := strings.Fields(header)
+	starts := []int{}
+	offset := 0
+	for _, col := range columns {
+		idx := strings.Index(header[offset:], col) + offset
+		starts = append(starts, idx)
+		offset = idx + len(col)
+	}
+	for _, required := range []string{"NAME", "NAMESPACED", "KIND"} {
+		found := false
+		for _, col := range columns {
+			if col == required {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unexpected output of 'oc api-resources', missing column %s", required)
+		}
+	}
+
+	resources := []APIResource{}
+	for _, line := range lines[1:] {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		r := APIResource{}
+		for i, col := range columns {
+			start := starts[i]
+			end := len(line)
+			if i+1 < len(starts) && starts[i+1] < end {
+				end = starts[i+1]
+			}
+			if start >= end {
+				continue
+			}
+			value := strings.TrimSpace(line[start:end])
+			switch col {
+			case "NAME":
+				r.Name = value
+			case "SHORTNAMES":
+				if len(value) > 0 {
+					r.ShortNames = strings.Split(value, ",")
+				}
+			case "APIVERSION", "APIGROUP":
+				r.GroupVersion = value
+			case "NAMESPACED":
+				r.Namespaced = value == "true"
+			case "KIND":
+				r.Kind = value
+			}
+		}
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+// APIResources returns the resources served by the cluster, using the
+// discovery API. Only the preferred version of each group is considered. The
+// result is cached, a failed discovery is retried on the next call.
+func (c *KubeClient) APIResources() ([]APIResource, error) {
+	c.discoverMu.Lock()
+	defer c.discoverMu.Unlock()
+	if c.discovered != nil {
+		return c.discovered, nil
+	}
+	groupVersions := []string{"v1"}
+	body, status, err := c.do(http.MethodGet, "/apis", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, apiError(status, body)
+	}
+	groupList := struct {
+		Groups []struct {
+			PreferredVersion struct {
+				GroupVersion string `json:"groupVersion"`
+			} `json:"preferredVersion"`
+		} `json:"groups"`
+	}{}
+	err = json.Unmarshal(body, &groupList)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groupList.Groups {
+		groupVersions = append(groupVersions, g.PreferredVersion.GroupVersion)
+	}
+
+	resources := []APIResource{}
+	for _, gv := range groupVersions {
+		path := "/apis/" + gv
+		if gv == "v1" {
+			path = "/api/v1"
+		}
+		body, status, err := c.do(http.MethodGet, path, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK {
+			// Aggregated APIs might be unavailable, which should not
+			// prevent working with all other resources.
+			DebugMsg("Could not discover resources of", gv+":", apiError(status, body).Error())
+			continue
+		}
+		resourceList := struct {
+			Resources []struct {
+				Name       string   `json:"name"`
+				Kind       string   `json:"kind"`
+				Namespaced bool     `json:"namespaced"`
+				ShortNames []string `json:"shortNames"`
+			} `json:"resources"`
+		}{}
+		err = json.Unmarshal(body, &resourceList)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resourceList.Resources {
+			// Skip subresources such as "deployments/scale".
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			resources = append(resources, APIResource{
+				Kind:         r.Kind,
+				Name:         r.Name,
+				GroupVersion: gv,
+				ShortNames:   r.ShortNames,
+				Namespaced:   r.Namespaced,
+			})
+		}
+	}
+	c.discovered = resources
+	return resources, nil
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseAPIResourcesTable(t *testing.T) {
+	tests := map[string]struct {
+		table string
+		want  []APIResource
+	}{
+		"with APIVERSION column": {
+			table: `NAME              SHORTNAMES   APIVERSION                     NAMESPACED   KIND
+configmaps        cm           v1                             true         ConfigMap
+namespaces        ns           v1                             false        Namespace
+networkpolicies   netpol       networking.k8s.io/v1           true         NetworkPolicy
+roles                          rbac.authorization.k8s.io/v1   true         Role
+`,
+			want: []APIResource{
+				{Kind: "ConfigMap", Name: "configmaps", GroupVersion: "v1", ShortNames: []string{"cm"}, Namespaced: true},
+				{Kind: "Namespace", Name: "namespaces", GroupVersion: "v1", ShortNames: []string{"ns"}, Namespaced: false},
+				{Kind: "NetworkPolicy", Name: "networkpolicies", GroupVersion: "networking.k8s.io/v1", ShortNames: []string{"netpol"}, Namespaced: true},
+				{Kind: "Role", Name: "roles", GroupVersion: "rbac.authorization.k8s.io/v1", Namespaced: true},
+			},
+		},
+		"with APIGROUP column": {
+			table: `NAME                 SHORTNAMES   APIGROUP              NAMESPACED   KIND
+bindings                                                true         Binding
+poddisruptionbudgets pdb          policy                true         PodDisruptionBudget
+`,
+			want: []APIResource{
+				{Kind: "Binding", Name: "bindings", Namespaced: true},
+				{Kind: "PodDisruptionBudget", Name: "poddisruptionbudgets", GroupVersion: "policy", ShortNames: []string{"pdb"}, Namespaced: true},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseAPIResourcesTable(tc.table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Resources mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := parseAPIResourcesTable("FOO BAR\nbaz qux\n"); err == nil {
+		t.Fatal("Want error for unexpected table")
+	}
+}
+
+func TestKubeClientAPIResources(t *testing.T) {
+	server := &fakeAPIServer{objects: map[string]map[string]interface{}{
+		"/apis/networking.k8s.io/v1/namespaces/foo/networkpolicies/deny-all": {
+			"metadata": map[string]interface{}{"name": "deny-all"},
+		},
+	}}
+	c := newFakeKubeClient(t, server)
+	got, err := c.APIResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []APIResource{
+		{Kind: "ConfigMap", Name: "configmaps", GroupVersion: "v1", ShortNames: []string{"cm"}, Namespaced: true},
+		{Kind: "Namespace", Name: "namespaces", GroupVersion: "v1", ShortNames: []string{"ns"}, Namespaced: false},
+		{Kind: "NetworkPolicy", Name: "networkpolicies", GroupVersion: "networking.k8s.io/v1", ShortNames: []string{"netpol"}, Namespaced: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Resources mismatch (-want +got):\n%s", diff)
+	}
+
+	// Kinds which are not built-in are exported via discovery.
+	out, err := c.Export("NetworkPolicy", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOut := `apiVersion: v1
+items:
+- apiVersion: networking.k8s.io/v1
+  kind: NetworkPolicy
+  metadata:
+    name: deny-all
+kind: List
+`
+	if diff := cmp.Diff(wantOut, string(out)); diff != "" {
+		t.Fatalf("Export mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestKubeClientAPIResourcesConcurrently(t *testing.T) {
+	server := &fakeAPIServer{objects: map[string]map[string]interface{}{}}
+	c := newFakeKubeClient(t, server)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Export("NetworkPolicy", "")
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -16,6 +16,9 @@ import (
 var verbose bool
 var debug bool
 var ocBinary string
+var backend string
+var kubeconfigPath string
+var localProcessing bool
 
 // PrintGreenf prints in green.
 var PrintGreenf func(format string, a ...interface{})
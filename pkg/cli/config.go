@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConfigOptions are the options of "tailor config", which shows the resolved
+// options of another command and where each value comes from.
+type ConfigOptions struct {
+	*GlobalOptions
+	// Options are the resolved options of the command, one of
+	// *CompareOptions, *ExportOptions and *SecretsOptions.
+	Options interface{}
+	// File is the Tailorfile which was read, empty if there is none.
+	File string
+	// FlagOrigins maps the flags given on the command line or via environment
+	// variables to their origin, e.g. "flag --namespace".
+	FlagOrigins map[string]string
+	// FileOrigins maps the settings of the Tailorfile to the file and lines
+	// they are set in, e.g. "Tailorfile:3".
+	FileOrigins map[string]string
+}
+
+// NewConfigOptions returns new options for the config command, showing the
+// given resolved options.
+func NewConfigOptions(
+	globalOptions *GlobalOptions,
+	namespaceFlag string,
+	flagOrigins map[string]string,
+	options interface{}) (*ConfigOptions, error) {
+	o := &ConfigOptions{
+		GlobalOptions: globalOptions,
+		Options:       options,
+		FlagOrigins:   flagOrigins,
+		FileOrigins:   map[string]string{},
+	}
+	switch options.(type) {
+	case *CompareOptions, *ExportOptions:
+	case *SecretsOptions:
+		namespaceFlag = "" // namespace does not make sense for secrets
+	default:
+		return o, fmt.Errorf("Options of type %T cannot be shown", options)
+	}
+
+	_, fileLines, filename, err := readFileFlags(o.resolvedFile(namespaceFlag), o.Env, o.Command, false)
+	if err != nil {
+		return o, err
+	}
+	o.File = filename
+	for key, lines := range fileLines {
+		l := []string{}
+		for _, line := range lines {
+			l = append(l, fmt.Sprintf("%d", line))
+		}
+		o.FileOrigins[key] = filename + ":" + strings.Join(l, ",")
+	}
+
+	DebugMsg(fmt.Sprintf("%#v", o))
+
+	return o, nil
+}
+
+// Origin returns where the option configured by given flag (or Tailorfile
+// key) comes from. Flags take precedence over the Tailorfile. If neither
+// sets the option, fallback is returned (e.g. "default").
+func (o *ConfigOptions) Origin(flag string, fallback string) string {
+	origins := []string{}
+	if origin, ok := o.FlagOrigins[flag]; ok {
+		origins = append(origins, origin)
+	}
+	// Params of the Tailorfile are merged with params given as flags.
+	if origin, ok := o.FileOrigins[flag]; ok && (len(origins) == 0 || flag == "param") {
+		origins = append(origins, origin)
+	}
+	if len(origins) == 0 {
+		return fallback
+	}
+	return strings.Join(origins, ", ")
+}
+
+// FlagOrigin describes a flag given on the command line or via environment
+// variable, as used for ConfigOptions.FlagOrigins.
+func FlagOrigin(flag string, envar string) string {
+	if len(envar) > 0 {
+		return "env " + envar
+	}
+	return "flag --" + flag
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestConfigOptionsOrigin(t *testing.T) {
+	tests := map[string]struct {
+		file        string
+		content     string
+		env         string
+		command     string
+		flagOrigins map[string]string
+		want        map[string]string
+	}{
+		"line-based Tailorfile with profile": {
+			file: "Tailorfile",
+			content: `template-dir templates
+param FOO=common
+upsert-only true
+
+[prod]
+param BAR=prod
+upsert-only false
+`,
+			env:     "prod",
+			command: "apply",
+			flagOrigins: map[string]string{
+				"param":        "flag --param",
+				"template-dir": "env TAILOR_TEMPLATE_DIR",
+			},
+			want: map[string]string{
+				"template-dir": "env TAILOR_TEMPLATE_DIR",
+				"param":        "flag --param, Tailorfile:2,6",
+				"upsert-only":  "Tailorfile:7",
+				"wait":         "default",
+			},
+		},
+		"YAML Tailorfile with command section and profile": {
+			file: "Tailorfile.yml",
+			content: `template-dir: templates
+param:
+  - FOO=common
+apply:
+  wait: true
+diff:
+  upsert-only: true
+profiles:
+  prod:
+    apply:
+      param:
+        - BAR=prod
+`,
+			env:     "prod",
+			command: "apply",
+			want: map[string]string{
+				"template-dir": "Tailorfile.yml:1",
+				"param":        "Tailorfile.yml:2,11",
+				"upsert-only":  "default",
+				"wait":         "Tailorfile.yml:5",
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := os.WriteFile(filepath.Join(dir, tc.file), []byte(tc.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wd, _ := os.Getwd()
+			defer os.Chdir(wd)
+			os.Chdir(dir)
+
+			globalOptions := InitGlobalOptions(&utils.OsFS{})
+			globalOptions.File = tc.file
+			globalOptions.Env = tc.env
+			globalOptions.Command = tc.command
+			flagOrigins := tc.flagOrigins
+			if flagOrigins == nil {
+				flagOrigins = map[string]string{}
+			}
+			o, err := NewConfigOptions(globalOptions, "", flagOrigins, &CompareOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for flag := range tc.want {
+				got[flag] = o.Origin(flag, "default")
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Origins mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Events hooks can be registered for.
+const (
+	HookPreApply     = "pre-apply"
+	HookPostApply    = "post-apply"
+	HookPreResource  = "pre-resource"
+	HookPostResource = "post-resource"
+)
+
+// hookEvents are all events in the order they are configured in the
+// Tailorfile, e.g. "pre-apply-hook ./migrate.sh".
+var hookEvents = []string{HookPreApply, HookPostApply, HookPreResource, HookPostResource}
+
+// jobHookPrefix marks hooks which run a Job defined in a file instead of a
+// command.
+const jobHookPrefix = "job:"
+
+// Hook is a command or Job which is run before or after changes are applied.
+// Resource hooks have a target selecting the resources they run for, which is
+// either a kind (e.g. "dc") or a kind and a name (e.g. "dc:foo").
+type Hook struct {
+	Event  string
+	Target string
+	Run    string
+}
+
+// IsJob returns true if the hook runs a Job instead of a command.
+func (h *Hook) IsJob() bool {
+	return strings.HasPrefix(h.Run, jobHookPrefix)
+}
+
+// JobFile returns the file defining the Job of a Job hook.
+func (h *Hook) JobFile() string {
+	return strings.TrimPrefix(h.Run, jobHookPrefix)
+}
+
+// String returns a description of the hook, used in output.
+func (h *Hook) String() string {
+	if len(h.Target) > 0 {
+		return fmt.Sprintf("%s hook for %s (%s)", h.Event, h.Target, h.Run)
+	}
+	return fmt.Sprintf("%s hook (%s)", h.Event, h.Run)
+}
+
+// isHookFileFlag returns true if given Tailorfile key configures hooks. As
+// commands may contain commas, multiple hooks are separated by newlines.
+func isHookFileFlag(key string) bool {
+	for _, event := range hookEvents {
+		if key == event+"-hook" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHooks reads the hooks configured in the Tailorfile. Relative Job files
+// are resolved against dir, the directory of the Tailorfile.
+func parseHooks(fileFlags map[string]string, dir string) ([]*Hook, error) {
+	hooks := []*Hook{}
+	for _, event := range hookEvents {
+		val, ok := fileFlags[event+"-hook"]
+		if !ok {
+			continue
+		}
+		for _, line := range strings.Split(val, "\n") {
+			h := &Hook{Event: event, Run: line}
+			if event == HookPreResource || event == HookPostResource {
+				parts := strings.SplitN(line, " ", 2)
+				if len(parts) != 2 {
+					return nil, fmt.Errorf("%s-hook '%s' needs a target and a command, e.g. 'dc:foo ./migrate.sh'", event, line)
+				}
+				h.Target = parts[0]
+				h.Run = strings.TrimSpace(parts[1])
+			}
+			if h.IsJob() && len(h.JobFile()) == 0 {
+				return nil, fmt.Errorf("%s-hook '%s' needs a file defining the Job, e.g. 'job:migrate.yml'", event, line)
+			}
+			if h.IsJob() && !filepath.IsAbs(h.JobFile()) {
+				h.Run = jobHookPrefix + filepath.Join(dir, h.JobFile())
+			}
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks, nil
+}
+
+// HooksFor returns the hooks configured for given event.
+func (o *CompareOptions) HooksFor(event string) []*Hook {
+	hooks := []*Hook{}
+	for _, h := range o.Hooks {
+		if h.Event == event {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
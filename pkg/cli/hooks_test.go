@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseHooks(t *testing.T) {
+	tests := map[string]struct {
+		fileFlags map[string]string
+		dir       string
+		want      []*Hook
+		wantErr   bool
+	}{
+		"no hooks": {
+			fileFlags: map[string]string{"param": "FOO=bar"},
+			want:      []*Hook{},
+		},
+		"apply hooks": {
+			fileFlags: map[string]string{
+				"pre-apply-hook":  "./migrate.sh a,b\njob:migrate.yml",
+				"post-apply-hook": "./smoke-test.sh",
+			},
+			want: []*Hook{
+				{Event: HookPreApply, Run: "./migrate.sh a,b"},
+				{Event: HookPreApply, Run: "job:migrate.yml"},
+				{Event: HookPostApply, Run: "./smoke-test.sh"},
+			},
+		},
+		"resource hooks": {
+			fileFlags: map[string]string{
+				"pre-resource-hook":  "dc:foo ./migrate.sh --all",
+				"post-resource-hook": "svc echo done",
+			},
+			want: []*Hook{
+				{Event: HookPreResource, Target: "dc:foo", Run: "./migrate.sh --all"},
+				{Event: HookPostResource, Target: "svc", Run: "echo done"},
+			},
+		},
+		"resource hook without command": {
+			fileFlags: map[string]string{"pre-resource-hook": "dc:foo"},
+			wantErr:   true,
+		},
+		"job files relative to Tailorfile": {
+			fileFlags: map[string]string{
+				"pre-apply-hook":  "job:migrate.yml",
+				"post-apply-hook": "job:/tmp/smoke-test.yml",
+			},
+			dir: "ocp",
+			want: []*Hook{
+				{Event: HookPreApply, Run: "job:ocp/migrate.yml"},
+				{Event: HookPostApply, Run: "job:/tmp/smoke-test.yml"},
+			},
+		},
+		"job hook without file": {
+			fileFlags: map[string]string{"post-apply-hook": "job:"},
+			wantErr:   true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseHooks(tc.fileFlags, tc.dir)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Want error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Hooks mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,750 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// kubeResource describes how a kind is exposed by the API server.
+type kubeResource struct {
+	// GroupVersions are tried in order when listing resources.
+	GroupVersions []string
+	Plural        string
+}
+
+var kubeResources = map[string]kubeResource{
+	"Service":                 {[]string{"v1"}, "services"},
+	"Route":                   {[]string{"route.openshift.io/v1"}, "routes"},
+	"DeploymentConfig":        {[]string{"apps.openshift.io/v1"}, "deploymentconfigs"},
+	"Deployment":              {[]string{"apps/v1"}, "deployments"},
+	"BuildConfig":             {[]string{"build.openshift.io/v1"}, "buildconfigs"},
+	"ImageStream":             {[]string{"image.openshift.io/v1"}, "imagestreams"},
+	"PersistentVolumeClaim":   {[]string{"v1"}, "persistentvolumeclaims"},
+	"Template":                {[]string{"template.openshift.io/v1"}, "templates"},
+	"ConfigMap":               {[]string{"v1"}, "configmaps"},
+	"Secret":                  {[]string{"v1"}, "secrets"},
+	"RoleBinding":             {[]string{"rbac.authorization.k8s.io/v1"}, "rolebindings"},
+	"ServiceAccount":          {[]string{"v1"}, "serviceaccounts"},
+	"CronJob":                 {[]string{"batch/v1", "batch/v1beta1"}, "cronjobs"},
+	"Job":                     {[]string{"batch/v1"}, "jobs"},
+	"LimitRange":              {[]string{"v1"}, "limitranges"},
+	"ResourceQuota":           {[]string{"v1"}, "resourcequotas"},
+	"HorizontalPodAutoscaler": {[]string{"autoscaling/v1"}, "horizontalpodautoscalers"},
+	"StatefulSet":             {[]string{"apps/v1"}, "statefulsets"},
+}
+
+// KubeClient talks to the API server directly, using the credentials of the
+// current context in the kubeconfig file. It is an alternative to OcClient
+// which does not depend on the "oc" binary.
+type KubeClient struct {
+	namespace      string
+	kubeconfigPath string
+	connectOnce    sync.Once
+	connectErr     error
+	credentials    *kubeCredentials
+	httpClient     *http.Client
+	// discoverMu guards discovered, as resources of non built-in kinds are
+	// looked up by concurrently applied changes.
+	discoverMu sync.Mutex
+	discovered []APIResource
+}
+
+// NewKubeClient creates a new KubeClient based on given kubeconfig file.
+// If namespace is blank, the namespace of the current context is used.
+func NewKubeClient(namespace string, kubeconfigPath string) (*KubeClient, error) {
+	c := newLazyKubeClient(namespace, kubeconfigPath)
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newLazyKubeClient creates a KubeClient which reads the kubeconfig file only
+// once the cluster is accessed, so that commands which do not need a cluster
+// work without one.
+func newLazyKubeClient(namespace string, kubeconfigPath string) *KubeClient {
+	return &KubeClient{namespace: namespace, kubeconfigPath: kubeconfigPath}
+}
+
+// connect reads the credentials from the kubeconfig file, once.
+func (c *KubeClient) connect() error {
+	c.connectOnce.Do(func() {
+		creds, err := loadKubeCredentials(resolveKubeconfigPath(c.kubeconfigPath))
+		if err != nil {
+			c.connectErr = err
+			return
+		}
+		if len(c.namespace) == 0 {
+			c.namespace = creds.Namespace
+		}
+		c.credentials = creds
+		c.httpClient = &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: creds.TLSConfig, Proxy: http.ProxyFromEnvironment},
+		}
+	})
+	return c.connectErr
+}
+
+// Version returns the server version in a format similar to "oc version".
+func (c *KubeClient) Version() ([]byte, []byte, error) {
+	var out bytes.Buffer
+	body, status, err := c.do(http.MethodGet, "/version", "", nil)
+	if err != nil {
+		return nil, []byte(err.Error()), err
+	}
+	if status != http.StatusOK {
+		return nil, body, fmt.Errorf("Unexpected status %d", status)
+	}
+	v := map[string]string{}
+	_ = json.Unmarshal(body, &v)
+	fmt.Fprintf(&out, "kubernetes %s\n", v["gitVersion"])
+	// OpenShift 3.x exposes its own version separately.
+	body, status, err = c.do(http.MethodGet, "/version/openshift", "", nil)
+	if err == nil && status == http.StatusOK {
+		v = map[string]string{}
+		_ = json.Unmarshal(body, &v)
+		fmt.Fprintf(&out, "openshift %s\n", v["gitVersion"])
+	}
+	return out.Bytes(), []byte{}, nil
+}
+
+// CurrentProject returns the namespace of the current context.
+func (c *KubeClient) CurrentProject() (string, error) {
+	if err := c.connect(); err != nil {
+		return "", err
+	}
+	if len(c.credentials.Namespace) == 0 {
+		return "", errors.New("No namespace set in current kubeconfig context")
+	}
+	return c.credentials.Namespace, nil
+}
+
+// CheckProjectExists returns true if the given project (namespace) exists.
+func (c *KubeClient) CheckProjectExists(p string) (bool, error) {
+	// Regular users might not be allowed to get namespaces, but projects.
+	for _, path := range []string{
+		"/apis/project.openshift.io/v1/projects/" + p,
+		"/api/v1/namespaces/" + p,
+	} {
+		body, status, err := c.do(http.MethodGet, path, "", nil)
+		if err != nil {
+			return false, err
+		}
+		if status == http.StatusOK {
+			return true, nil
+		}
+		if status != http.StatusNotFound && status != http.StatusForbidden {
+			return false, apiError(status, body)
+		}
+	}
+	return false, fmt.Errorf("No such project: %s", p)
+}
+
+// ListProjects returns the names of all projects (namespaces) the user can
+// access, limited to those matching the label selector if given.
+func (c *KubeClient) ListProjects(selector string) ([]string, error) {
+	query := ""
+	if len(selector) > 0 {
+		query = "?labelSelector=" + url.QueryEscape(selector)
+	}
+	// Regular users might not be allowed to list namespaces, but projects.
+	for _, path := range []string{
+		"/apis/project.openshift.io/v1/projects",
+		"/api/v1/namespaces",
+	} {
+		body, status, err := c.do(http.MethodGet, path+query, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		if status == http.StatusNotFound || status == http.StatusForbidden {
+			continue
+		}
+		if status != http.StatusOK {
+			return nil, apiError(status, body)
+		}
+		var list struct {
+			Items []struct {
+				Metadata struct {
+					Name string `json:"name"`
+				} `json:"metadata"`
+			} `json:"items"`
+		}
+		err = json.Unmarshal(body, &list)
+		if err != nil {
+			return nil, err
+		}
+		projects := []string{}
+		for _, item := range list.Items {
+			projects = append(projects, item.Metadata.Name)
+		}
+		return projects, nil
+	}
+	return nil, errors.New("Not allowed to list projects")
+}
+
+// CheckLoggedIn returns true if the credentials are accepted by the server.
+func (c *KubeClient) CheckLoggedIn() (bool, error) {
+	body, status, err := c.do(http.MethodGet, "/api/v1", "", nil)
+	if err != nil {
+		return false, err
+	}
+	if status < 200 || status > 299 {
+		return false, apiError(status, body)
+	}
+	return true, nil
+}
+
+// Process processes an OpenShift template via the processedtemplates API.
+// It accepts the same arguments as "oc process".
+func (c *KubeClient) Process(args []string) ([]byte, []byte, error) {
+	pa, err := parseProcessArgs(args)
+	if err != nil {
+		return nil, []byte("error: " + err.Error()), err
+	}
+	template, err := pa.readTemplate()
+	if err != nil {
+		return nil, []byte("error: " + err.Error()), err
+	}
+	err = pa.setParameterValues(template)
+	if err != nil {
+		return nil, []byte("error: " + err.Error()), err
+	}
+
+	if len(pa.Labels) > 0 {
+		templateLabels, _ := template["labels"].(map[string]interface{})
+		if templateLabels == nil {
+			templateLabels = map[string]interface{}{}
+		}
+		for k, v := range pa.Labels {
+			templateLabels[k] = v
+		}
+		template["labels"] = templateLabels
+	}
+
+	template["apiVersion"] = "template.openshift.io/v1"
+	template["kind"] = "Template"
+	requestBody, err := json.Marshal(template)
+	if err != nil {
+		return nil, []byte(err.Error()), err
+	}
+	path := c.collectionPath("template.openshift.io/v1", "processedtemplates")
+	body, status, err := c.do(http.MethodPost, path, "application/json", requestBody)
+	if err != nil {
+		return nil, []byte(err.Error()), err
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		err := apiError(status, body)
+		return nil, []byte("error: " + err.Error()), err
+	}
+	var processed map[string]interface{}
+	err = json.Unmarshal(body, &processed)
+	if err != nil {
+		return nil, []byte(err.Error()), err
+	}
+	objects, _ := processed["objects"].([]interface{})
+	if objects == nil {
+		objects = []interface{}{}
+	}
+	out, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      objects,
+	})
+	if err != nil {
+		return nil, []byte(err.Error()), err
+	}
+	return out, []byte{}, nil
+}
+
+// Export lists resources of the given kinds (comma-separated) in the
+// namespace and returns them as a YAML list.
+func (c *KubeClient) Export(target string, label string) ([]byte, error) {
+	items := []interface{}{}
+	for _, kind := range strings.Split(target, ",") {
+		kindItems, err := c.list(kind, label)
+		if err != nil {
+			return []byte{}, fmt.Errorf("Failed to export %s resources.\n%s\n", kind, err)
+		}
+		items = append(items, kindItems...)
+	}
+	if len(items) == 0 {
+		return []byte{}, nil
+	}
+	return yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	})
+}
+
+// Apply creates or updates the given resource configuration. Similar to
+// "oc apply", the configuration is recorded in the
+// "kubectl.kubernetes.io/last-applied-configuration" annotation, which is
+// used to compute fields to remove on subsequent updates.
+func (c *KubeClient) Apply(config string, selector string) ([]byte, error) {
+	return c.apply(config, selector, false)
+}
+
+// DryRunApply sends given resource configuration through a server-side
+// dry-run apply, which validates it (including admission) without persisting
+// it.
+func (c *KubeClient) DryRunApply(config string, selector string) ([]byte, error) {
+	return c.apply(config, selector, true)
+}
+
+func (c *KubeClient) apply(config string, selector string, dryRun bool) ([]byte, error) {
+	query := ""
+	if dryRun {
+		query = "?dryRun=All"
+	}
+	var desired map[string]interface{}
+	err := yaml.Unmarshal([]byte(config), &desired)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+	kind, _ := desired["kind"].(string)
+	apiVersion, _ := desired["apiVersion"].(string)
+	metadata, _ := desired["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		desired["metadata"] = metadata
+	}
+	name, _ := metadata["name"].(string)
+
+	if len(selector) > 0 {
+		matches, err := matchesSelector(metadata, selector)
+		if err != nil {
+			return []byte(err.Error()), err
+		}
+		if !matches {
+			VerboseMsg("Skipping", kind+"/"+name, "as it does not match selector", selector)
+			return []byte{}, nil
+		}
+	}
+
+	res, err := c.resourceFor(kind)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+	}
+	delete(annotations, lastAppliedConfigAnnotation)
+	metadata["annotations"] = annotations
+	lastApplied, err := json.Marshal(desired)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+	annotations[lastAppliedConfigAnnotation] = string(lastApplied)
+
+	itemPath := c.collectionPath(apiVersion, res.Plural) + "/" + name
+	body, status, err := c.do(http.MethodGet, itemPath, "", nil)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+
+	if status == http.StatusNotFound {
+		requestBody, err := json.Marshal(desired)
+		if err != nil {
+			return []byte(err.Error()), err
+		}
+		body, status, err = c.do(http.MethodPost, c.collectionPath(apiVersion, res.Plural)+query, "application/json", requestBody)
+		if err != nil {
+			return []byte(err.Error()), err
+		}
+		if status != http.StatusCreated && status != http.StatusOK {
+			err := apiError(status, body)
+			return []byte(err.Error()), err
+		}
+		return []byte{}, nil
+	}
+	if status != http.StatusOK {
+		err := apiError(status, body)
+		return []byte(err.Error()), err
+	}
+
+	var current map[string]interface{}
+	err = json.Unmarshal(body, &current)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+	previouslyApplied := map[string]interface{}{}
+	if currentMetadata, ok := current["metadata"].(map[string]interface{}); ok {
+		if currentAnnotations, ok := currentMetadata["annotations"].(map[string]interface{}); ok {
+			if s, ok := currentAnnotations[lastAppliedConfigAnnotation].(string); ok {
+				_ = json.Unmarshal([]byte(s), &previouslyApplied)
+			}
+		}
+	}
+	patch := threeWayMergePatch(previouslyApplied, current, desired)
+	if len(patch) == 0 {
+		return []byte{}, nil
+	}
+	requestBody, err := json.Marshal(patch)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+	body, status, err = c.do(http.MethodPatch, itemPath+query, "application/merge-patch+json", requestBody)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+	if status != http.StatusOK {
+		err := apiError(status, body)
+		return []byte(err.Error()), err
+	}
+	return []byte{}, nil
+}
+
+// Delete deletes given resource. Like "oc delete", dependents such as the
+// pods of a job are deleted in the background instead of being orphaned.
+func (c *KubeClient) Delete(kind string, name string) ([]byte, error) {
+	res, err := c.resourceFor(kind)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+	deleteOptions := []byte(`{"kind":"DeleteOptions","apiVersion":"v1","propagationPolicy":"Background"}`)
+	for _, gv := range res.GroupVersions {
+		path := c.collectionPath(gv, res.Plural) + "/" + name
+		body, status, err := c.do(http.MethodDelete, path, "application/json", deleteOptions)
+		if err != nil {
+			return []byte(err.Error()), err
+		}
+		if status == http.StatusOK || status == http.StatusAccepted {
+			return []byte{}, nil
+		}
+		if status != http.StatusNotFound {
+			err := apiError(status, body)
+			return []byte(err.Error()), err
+		}
+	}
+	err = fmt.Errorf("%s %q not found", kind, name)
+	return []byte(err.Error()), err
+}
+
+// Patch applies given JSON merge patch to given resource.
+func (c *KubeClient) Patch(kind string, name string, patch string) ([]byte, error) {
+	res, err := c.resourceFor(kind)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+	for _, gv := range res.GroupVersions {
+		path := c.collectionPath(gv, res.Plural) + "/" + name
+		body, status, err := c.do(http.MethodPatch, path, "application/merge-patch+json", []byte(patch))
+		if err != nil {
+			return []byte(err.Error()), err
+		}
+		if status == http.StatusOK {
+			return []byte{}, nil
+		}
+		if status != http.StatusNotFound {
+			err := apiError(status, body)
+			return []byte(err.Error()), err
+		}
+	}
+	err = fmt.Errorf("%s %q not found", kind, name)
+	return []byte(err.Error()), err
+}
+
+// Get retrieves given resource as JSON.
+func (c *KubeClient) Get(kind string, name string) ([]byte, error) {
+	res, err := c.resourceFor(kind)
+	if err != nil {
+		return nil, err
+	}
+	for _, gv := range res.GroupVersions {
+		path := c.collectionPath(gv, res.Plural) + "/" + name
+		body, status, err := c.do(http.MethodGet, path, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		if status == http.StatusOK {
+			return body, nil
+		}
+		if status != http.StatusNotFound {
+			return nil, apiError(status, body)
+		}
+	}
+	return nil, fmt.Errorf("%s %q not found", kind, name)
+}
+
+func (c *KubeClient) list(kind string, label string) ([]interface{}, error) {
+	res, err := c.resourceFor(kind)
+	if err != nil {
+		return nil, err
+	}
+	for _, gv := range res.GroupVersions {
+		path := c.collectionPath(gv, res.Plural)
+		if len(label) > 0 {
+			path = path + "?labelSelector=" + url.QueryEscape(label)
+		}
+		body, status, err := c.do(http.MethodGet, path, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		if status == http.StatusNotFound {
+			DebugMsg("Resource", gv+"/"+res.Plural, "is not served by the cluster")
+			continue
+		}
+		if status != http.StatusOK {
+			return nil, apiError(status, body)
+		}
+		var list map[string]interface{}
+		err = json.Unmarshal(body, &list)
+		if err != nil {
+			return nil, err
+		}
+		items, _ := list["items"].([]interface{})
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				// Items of a list response do not carry kind and apiVersion.
+				m["kind"] = kind
+				m["apiVersion"] = gv
+			}
+		}
+		return items, nil
+	}
+	return []interface{}{}, nil
+}
+
+func (c *KubeClient) collectionPath(groupVersion string, plural string) string {
+	// A failure to connect is reported by the request using the path.
+	_ = c.connect()
+	prefix := "/apis/" + groupVersion
+	if !strings.Contains(groupVersion, "/") {
+		prefix = "/api/" + groupVersion
+	}
+	return prefix + "/namespaces/" + c.namespace + "/" + plural
+}
+
+func (c *KubeClient) do(method string, path string, contentType string, requestBody []byte) ([]byte, int, error) {
+	if err := c.connect(); err != nil {
+		return nil, 0, err
+	}
+	VerboseMsg(method, c.credentials.Server+path)
+	var reader io.Reader
+	if requestBody != nil {
+		reader = bytes.NewReader(requestBody)
+	}
+	req, err := http.NewRequest(method, c.credentials.Server+path, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(c.credentials.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.credentials.Token)
+	} else if len(c.credentials.Username) > 0 {
+		req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
+
+// resourceFor returns how kind is exposed by the API server. Kinds which are
+// not built-in are looked up via the discovery API.
+func (c *KubeClient) resourceFor(kind string) (kubeResource, error) {
+	if res, ok := kubeResources[kind]; ok {
+		return res, nil
+	}
+	discovered, err := c.APIResources()
+	if err != nil {
+		return kubeResource{}, fmt.Errorf("Unknown kind %s, discovery failed: %s", kind, err)
+	}
+	res := kubeResource{}
+	for _, r := range discovered {
+		if r.Kind == kind && r.Namespaced {
+			res.GroupVersions = append(res.GroupVersions, r.GroupVersion)
+			res.Plural = r.Name
+		}
+	}
+	if len(res.GroupVersions) == 0 {
+		return res, fmt.Errorf("Unknown kind: %s", kind)
+	}
+	return res, nil
+}
+
+// apiError extracts the message of a Status object returned by the server.
+func apiError(status int, body []byte) error {
+	s := struct {
+		Message string `json:"message"`
+	}{}
+	if err := json.Unmarshal(body, &s); err == nil && len(s.Message) > 0 {
+		return errors.New(s.Message)
+	}
+	return fmt.Errorf("Server responded with status %d: %s", status, strings.TrimSpace(string(body)))
+}
+
+var (
+	selectorSetRegex   = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+	selectorKeyRegex   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]*[A-Za-z0-9])?$`)
+	selectorValueRegex = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+)
+
+// labelRequirement is a single requirement of a label selector, e.g.
+// "app=foo", "tier in (web,api)" or "!legacy". The operator is one of "=",
+// "!=", "in", "notin", "exists" or "!".
+type labelRequirement struct {
+	key      string
+	operator string
+	values   []string
+}
+
+// parseSelector parses a label selector in the same grammar as "oc": a
+// comma-separated list of equality-based (=, ==, !=), set-based (in, notin)
+// and existence (key, !key) requirements.
+func parseSelector(selector string) ([]labelRequirement, error) {
+	requirements := []labelRequirement{}
+	for _, term := range splitSelector(selector) {
+		term = strings.TrimSpace(term)
+		r, err := parseRequirement(term)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid selector '%s': %s", selector, err)
+		}
+		requirements = append(requirements, r)
+	}
+	return requirements, nil
+}
+
+// splitSelector splits selector at commas which are not within parentheses.
+func splitSelector(selector string) []string {
+	terms := []string{}
+	depth := 0
+	start := 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+func parseRequirement(term string) (labelRequirement, error) {
+	r := labelRequirement{}
+	if strings.HasPrefix(term, "!") {
+		r.key = strings.TrimSpace(term[1:])
+		r.operator = "!"
+	} else if m := selectorSetRegex.FindStringSubmatch(term); m != nil {
+		r.key = m[1]
+		r.operator = m[2]
+		for _, v := range strings.Split(m[3], ",") {
+			r.values = append(r.values, strings.TrimSpace(v))
+		}
+	} else if i := strings.Index(term, "!="); i > -1 {
+		r.key, r.operator, r.values = term[:i], "!=", []string{term[i+2:]}
+	} else if i := strings.Index(term, "=="); i > -1 {
+		r.key, r.operator, r.values = term[:i], "=", []string{term[i+2:]}
+	} else if i := strings.Index(term, "="); i > -1 {
+		r.key, r.operator, r.values = term[:i], "=", []string{term[i+1:]}
+	} else {
+		r.key = term
+		r.operator = "exists"
+	}
+	r.key = strings.TrimSpace(r.key)
+	if !selectorKeyRegex.MatchString(r.key) {
+		return r, fmt.Errorf("invalid label key '%s'", r.key)
+	}
+	for i, v := range r.values {
+		r.values[i] = strings.TrimSpace(v)
+		if !selectorValueRegex.MatchString(r.values[i]) {
+			return r, fmt.Errorf("invalid label value '%s'", r.values[i])
+		}
+	}
+	return r, nil
+}
+
+func (r labelRequirement) matches(labels map[string]interface{}) bool {
+	label, exists := labels[r.key]
+	value := fmt.Sprintf("%v", label)
+	switch r.operator {
+	case "exists":
+		return exists
+	case "!":
+		return !exists
+	case "=", "in":
+		return exists && utils.Includes(r.values, value)
+	case "!=", "notin":
+		return !exists || !utils.Includes(r.values, value)
+	}
+	return false
+}
+
+// matchesSelector checks whether the labels of given metadata satisfy all
+// requirements of selector.
+func matchesSelector(metadata map[string]interface{}, selector string) (bool, error) {
+	requirements, err := parseSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	labels, _ := metadata["labels"].(map[string]interface{})
+	for _, r := range requirements {
+		if !r.matches(labels) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// threeWayMergePatch computes a JSON merge patch (RFC 7386) which brings
+// current to desired. Fields which were previously applied but are no longer
+// desired are removed. Fields which were never applied (e.g. set by the
+// server) are kept.
+func threeWayMergePatch(previouslyApplied, current, desired map[string]interface{}) map[string]interface{} {
+	patch := map[string]interface{}{}
+	for k, dv := range desired {
+		cv, inCurrent := current[k]
+		dm, desiredIsMap := dv.(map[string]interface{})
+		cm, currentIsMap := cv.(map[string]interface{})
+		if inCurrent && desiredIsMap && currentIsMap {
+			pm, _ := previouslyApplied[k].(map[string]interface{})
+			if sub := threeWayMergePatch(pm, cm, dm); len(sub) > 0 {
+				patch[k] = sub
+			}
+			continue
+		}
+		if !inCurrent || !reflect.DeepEqual(cv, dv) {
+			patch[k] = dv
+		}
+	}
+	for k := range previouslyApplied {
+		if _, inDesired := desired[k]; inDesired {
+			continue
+		}
+		if _, inCurrent := current[k]; inCurrent {
+			patch[k] = nil
+		}
+	}
+	return patch
+}
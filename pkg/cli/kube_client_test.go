@@ -0,0 +1,492 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeAPIServer is a minimal in-memory API server for namespaced resources.
+type fakeAPIServer struct {
+	mu       sync.Mutex
+	objects  map[string]map[string]interface{}
+	requests []string
+	patches  []map[string]interface{}
+	deletes  []map[string]interface{}
+	// status, if set, is returned for all requests.
+	status int
+}
+
+func (s *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if s.status > 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.URL.Path == "/version":
+		_, _ = w.Write([]byte(`{"gitVersion": "v1.11.0+d4cacc0"}`))
+		return
+	case r.URL.Path == "/api/v1":
+		_, _ = w.Write([]byte(`{"resources": [
+			{"name": "configmaps", "kind": "ConfigMap", "namespaced": true, "shortNames": ["cm"]},
+			{"name": "namespaces", "kind": "Namespace", "namespaced": false, "shortNames": ["ns"]}
+		]}`))
+		return
+	case r.URL.Path == "/apis":
+		_, _ = w.Write([]byte(`{"groups": [
+			{"name": "networking.k8s.io", "preferredVersion": {"groupVersion": "networking.k8s.io/v1"}},
+			{"name": "metrics.k8s.io", "preferredVersion": {"groupVersion": "metrics.k8s.io/v1beta1"}}
+		]}`))
+		return
+	case r.URL.Path == "/apis/networking.k8s.io/v1":
+		_, _ = w.Write([]byte(`{"resources": [
+			{"name": "networkpolicies", "kind": "NetworkPolicy", "namespaced": true, "shortNames": ["netpol"]},
+			{"name": "networkpolicies/status", "kind": "NetworkPolicy", "namespaced": true}
+		]}`))
+		return
+	case r.URL.Path == "/apis/metrics.k8s.io/v1beta1":
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	case strings.HasSuffix(r.URL.Path, "/processedtemplates"):
+		var t map[string]interface{}
+		_ = json.Unmarshal(body, &t)
+		_ = json.NewEncoder(w).Encode(t)
+		return
+	}
+
+	if r.URL.Query().Get("dryRun") == "All" {
+		if strings.Contains(string(body), "invalid") {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"message": "admission webhook denied the request"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if o, ok := s.objects[r.URL.Path]; ok {
+			_ = json.NewEncoder(w).Encode(o)
+			return
+		}
+		if r.URL.Path == "/api/v1/namespaces" {
+			items := []interface{}{}
+			for p, o := range s.objects {
+				name := strings.TrimPrefix(p, r.URL.Path+"/")
+				labels, _ := o["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+				selector := strings.SplitN(r.URL.Query().Get("labelSelector"), "=", 2)
+				if strings.HasPrefix(p, r.URL.Path+"/") && !strings.Contains(name, "/") &&
+					(len(selector) < 2 || labels[selector[0]] == selector[1]) {
+					items = append(items, o)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/configmaps") || strings.HasSuffix(r.URL.Path, "/networkpolicies") {
+			items := []interface{}{}
+			for p, o := range s.objects {
+				if strings.HasPrefix(p, r.URL.Path+"/") {
+					items = append(items, o)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "not found"}`))
+	case http.MethodPost:
+		var o map[string]interface{}
+		_ = json.Unmarshal(body, &o)
+		name := o["metadata"].(map[string]interface{})["name"].(string)
+		s.objects[r.URL.Path+"/"+name] = o
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPatch:
+		var patch map[string]interface{}
+		_ = json.Unmarshal(body, &patch)
+		s.patches = append(s.patches, patch)
+		_ = json.NewEncoder(w).Encode(s.objects[r.URL.Path])
+	case http.MethodDelete:
+		var deleteOptions map[string]interface{}
+		_ = json.Unmarshal(body, &deleteOptions)
+		s.deletes = append(s.deletes, deleteOptions)
+		if _, ok := s.objects[r.URL.Path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.objects, r.URL.Path)
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+func newFakeKubeClient(t *testing.T, server *fakeAPIServer) *KubeClient {
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	kubeconfigFile := t.TempDir() + "/config"
+	kubeconfig := `apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: local
+  cluster:
+    server: ` + ts.URL + `
+users:
+- name: tester
+  user:
+    token: s3cr3t
+contexts:
+- name: test
+  context:
+    cluster: local
+    user: tester
+    namespace: foo
+`
+	err := os.WriteFile(kubeconfigFile, []byte(kubeconfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewKubeClient("", kubeconfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestKubeClientSession(t *testing.T) {
+	c := newFakeKubeClient(t, &fakeAPIServer{objects: map[string]map[string]interface{}{}})
+	loggedIn, err := c.CheckLoggedIn()
+	if err != nil || !loggedIn {
+		t.Fatalf("Want to be logged in, got: %v", err)
+	}
+	p, err := c.CurrentProject()
+	if err != nil || p != "foo" {
+		t.Fatalf("Want current project foo, got: %s (%v)", p, err)
+	}
+	out, _, err := c.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "kubernetes v1.11.0+d4cacc0\n"; string(out) != want {
+		t.Fatalf("Want version %q, got: %q", want, string(out))
+	}
+}
+
+func TestKubeClientCheckLoggedIn(t *testing.T) {
+	tests := map[string]struct {
+		status       int
+		wantLoggedIn bool
+		wantErr      bool
+	}{
+		"accepted": {
+			status:       0,
+			wantLoggedIn: true,
+		},
+		"forbidden": {
+			status:  http.StatusForbidden,
+			wantErr: true,
+		},
+		"server error": {
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newFakeKubeClient(t, &fakeAPIServer{objects: map[string]map[string]interface{}{}, status: tc.status})
+			loggedIn, err := c.CheckLoggedIn()
+			if loggedIn != tc.wantLoggedIn {
+				t.Fatalf("Want logged in to be %v, got: %v", tc.wantLoggedIn, loggedIn)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Want error to be %v, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestKubeClientExport(t *testing.T) {
+	server := &fakeAPIServer{objects: map[string]map[string]interface{}{
+		"/api/v1/namespaces/foo/configmaps/bar": {
+			"metadata": map[string]interface{}{"name": "bar"},
+			"data":     map[string]interface{}{"a": "b"},
+		},
+	}}
+	c := newFakeKubeClient(t, server)
+	out, err := c.Export("ConfigMap", "app=bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `apiVersion: v1
+items:
+- apiVersion: v1
+  data:
+    a: b
+  kind: ConfigMap
+  metadata:
+    name: bar
+kind: List
+`
+	if diff := cmp.Diff(want, string(out)); diff != "" {
+		t.Fatalf("Export mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestKubeClientApplyAndDelete(t *testing.T) {
+	server := &fakeAPIServer{objects: map[string]map[string]interface{}{}}
+	c := newFakeKubeClient(t, server)
+	itemPath := "/api/v1/namespaces/foo/configmaps/bar"
+
+	// Create
+	_, err := c.Apply("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: bar\ndata:\n  a: b\n  c: d\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, ok := server.objects[itemPath]
+	if !ok {
+		t.Fatal("Want resource to be created")
+	}
+	annotations := created["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	if _, ok := annotations[lastAppliedConfigAnnotation]; !ok {
+		t.Fatal("Want last applied configuration to be recorded")
+	}
+
+	// Update removes previously applied field, and changes another
+	_, err = c.Apply("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: bar\ndata:\n  a: x\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.patches) != 1 {
+		t.Fatalf("Want one patch, got: %d", len(server.patches))
+	}
+	gotData := server.patches[0]["data"]
+	wantData := map[string]interface{}{"a": "x", "c": nil}
+	if diff := cmp.Diff(wantData, gotData); diff != "" {
+		t.Fatalf("Patch mismatch (-want +got):\n%s", diff)
+	}
+
+	// Selector not matching
+	_, err = c.Apply("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: baz\n", "app=baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.objects["/api/v1/namespaces/foo/configmaps/baz"]; ok {
+		t.Fatal("Want resource not matching selector to be skipped")
+	}
+
+	// Delete
+	_, err = c.Delete("ConfigMap", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.objects[itemPath]; ok {
+		t.Fatal("Want resource to be deleted")
+	}
+	if policy := server.deletes[0]["propagationPolicy"]; policy != "Background" {
+		t.Fatalf("Want background propagation policy, got: %v", policy)
+	}
+	_, err = c.Delete("ConfigMap", "bar")
+	if err == nil {
+		t.Fatal("Want error when deleting non-existent resource")
+	}
+}
+
+func TestKubeClientProcess(t *testing.T) {
+	c := newFakeKubeClient(t, &fakeAPIServer{objects: map[string]map[string]interface{}{}})
+	templateFile := t.TempDir() + "/template.yml"
+	template := `apiVersion: v1
+kind: Template
+parameters:
+- name: FOO
+objects:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: bar
+`
+	err := os.WriteFile(templateFile, []byte(template), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, _, err := c.Process([]string{"--filename=" + templateFile, "--output=yaml", "--param=FOO=bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list map[string]interface{}
+	err = yaml.Unmarshal(out, &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list["kind"] != "List" || len(list["items"].([]interface{})) != 1 {
+		t.Fatalf("Want list with one item, got: %s", string(out))
+	}
+
+	_, errBytes, err := c.Process([]string{"--filename=" + templateFile, "--param=BAZ=qux"})
+	if err == nil || !strings.Contains(string(errBytes), "unknown parameter name") {
+		t.Fatalf("Want unknown parameter error, got: %v", err)
+	}
+	_, _, err = c.Process([]string{"--filename=" + templateFile, "--param=BAZ=qux", "--ignore-unknown-parameters=true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThreeWayMergePatch(t *testing.T) {
+	previouslyApplied := map[string]interface{}{
+		"spec": map[string]interface{}{"a": "1", "b": "2"},
+	}
+	current := map[string]interface{}{
+		"spec": map[string]interface{}{"a": "1", "b": "2", "serverDefault": "x"},
+	}
+	desired := map[string]interface{}{
+		"spec": map[string]interface{}{"a": "3"},
+	}
+	want := map[string]interface{}{
+		"spec": map[string]interface{}{"a": "3", "b": nil},
+	}
+	got := threeWayMergePatch(previouslyApplied, current, desired)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Patch mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMatchesSelector(t *testing.T) {
+	metadata := map[string]interface{}{
+		"labels": map[string]interface{}{"app": "foo", "tier": "web"},
+	}
+	tests := map[string]struct {
+		selector string
+		want     bool
+		wantErr  string
+	}{
+		"equal":                 {selector: "app=foo", want: true},
+		"double equal":          {selector: "app==foo", want: true},
+		"equal other value":     {selector: "app=bar", want: false},
+		"not equal":             {selector: "app!=bar", want: true},
+		"not equal same value":  {selector: "app!=foo", want: false},
+		"not equal missing":     {selector: "env!=prod", want: true},
+		"exists":                {selector: "app", want: true},
+		"exists missing":        {selector: "env", want: false},
+		"not exists":            {selector: "!env", want: true},
+		"not exists present":    {selector: "!app", want: false},
+		"in":                    {selector: "tier in (api, web)", want: true},
+		"in other values":       {selector: "tier in (api,db)", want: false},
+		"in missing":            {selector: "env in (prod)", want: false},
+		"notin":                 {selector: "tier notin (api,db)", want: true},
+		"notin same value":      {selector: "tier notin (api,web)", want: false},
+		"notin missing":         {selector: "env notin (prod)", want: true},
+		"multiple":              {selector: "app=foo,tier in (web),!env", want: true},
+		"multiple one mismatch": {selector: "app=foo, tier notin (web)", want: false},
+		"invalid key":           {selector: "app foo", wantErr: "Invalid selector 'app foo': invalid label key 'app foo'"},
+		"invalid value":         {selector: "app=(foo)", wantErr: "Invalid selector 'app=(foo)': invalid label value '(foo)'"},
+		"empty requirement":     {selector: "app=foo,", wantErr: "Invalid selector 'app=foo,': invalid label key ''"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := matchesSelector(metadata, tc.selector)
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("Want %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestKubeClientDryRunApply(t *testing.T) {
+	server := &fakeAPIServer{objects: map[string]map[string]interface{}{}}
+	c := newFakeKubeClient(t, server)
+
+	_, err := c.DryRunApply("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: bar\ndata:\n  a: b\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.objects["/api/v1/namespaces/foo/configmaps/bar"]; ok {
+		t.Fatal("Want resource not to be created by dry-run")
+	}
+
+	errBytes, err := c.DryRunApply("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: bar\ndata:\n  a: invalid\n", "")
+	if err == nil {
+		t.Fatal("Want error for rejected resource")
+	}
+	if diff := cmp.Diff("admission webhook denied the request", string(errBytes)); diff != "" {
+		t.Fatalf("Error mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestKubeClientListProjects(t *testing.T) {
+	server := &fakeAPIServer{objects: map[string]map[string]interface{}{
+		"/api/v1/namespaces/foo": {
+			"metadata": map[string]interface{}{"name": "foo", "labels": map[string]interface{}{"tenant": "true"}},
+		},
+		"/api/v1/namespaces/bar": {
+			"metadata": map[string]interface{}{"name": "bar"},
+		},
+		"/api/v1/namespaces/foo/configmaps/baz": {
+			"metadata": map[string]interface{}{"name": "baz"},
+		},
+	}}
+	c := newFakeKubeClient(t, server)
+	tests := map[string]struct {
+		selector string
+		want     []string
+	}{
+		"all":      {selector: "", want: []string{"bar", "foo"}},
+		"selected": {selector: "tenant=true", want: []string{"foo"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := c.ListProjects(tc.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Projects mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewClientLocalProcessingWithoutKubeconfig(t *testing.T) {
+	previousBackend, previousKubeconfigPath, previousLocalProcessing := backend, kubeconfigPath, localProcessing
+	t.Cleanup(func() {
+		backend, kubeconfigPath, localProcessing = previousBackend, previousKubeconfigPath, previousLocalProcessing
+	})
+	backend, kubeconfigPath, localProcessing = "api", t.TempDir()+"/missing", true
+
+	c, err := NewClient("foo")
+	if err != nil {
+		t.Fatalf("Want client without kubeconfig, got: %v", err)
+	}
+	if _, err := c.CurrentProject(); err == nil {
+		t.Fatal("Want error once the cluster is accessed")
+	}
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// kubeconfig is the subset of a kubeconfig file which is relevant to Tailor.
+type kubeconfig struct {
+	CurrentContext string `json:"current-context"`
+	Clusters       []struct {
+		Name    string `json:"name"`
+		Cluster struct {
+			Server                   string `json:"server"`
+			CertificateAuthority     string `json:"certificate-authority"`
+			CertificateAuthorityData string `json:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+		} `json:"cluster"`
+	} `json:"clusters"`
+	Users []struct {
+		Name string `json:"name"`
+		User struct {
+			Token                 string `json:"token"`
+			TokenFile             string `json:"tokenFile"`
+			ClientCertificate     string `json:"client-certificate"`
+			ClientCertificateData string `json:"client-certificate-data"`
+			ClientKey             string `json:"client-key"`
+			ClientKeyData         string `json:"client-key-data"`
+			Username              string `json:"username"`
+			Password              string `json:"password"`
+		} `json:"user"`
+	} `json:"users"`
+	Contexts []struct {
+		Name    string `json:"name"`
+		Context struct {
+			Cluster   string `json:"cluster"`
+			User      string `json:"user"`
+			Namespace string `json:"namespace"`
+		} `json:"context"`
+	} `json:"contexts"`
+}
+
+// kubeCredentials are the resolved connection details of the current context.
+type kubeCredentials struct {
+	Server    string
+	Namespace string
+	Token     string
+	Username  string
+	Password  string
+	TLSConfig *tls.Config
+}
+
+// resolveKubeconfigPath returns the given path, or if blank, the first path
+// in $KUBECONFIG, or ~/.kube/config.
+func resolveKubeconfigPath(path string) string {
+	if len(path) > 0 {
+		return path
+	}
+	if env := os.Getenv("KUBECONFIG"); len(env) > 0 {
+		return strings.Split(env, string(os.PathListSeparator))[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".kube", "config")
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+// loadKubeCredentials reads the kubeconfig at path and resolves the
+// credentials of the current context.
+func loadKubeCredentials(path string) (*kubeCredentials, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read kubeconfig '%s': %s", path, err)
+	}
+	kc := &kubeconfig{}
+	err = yaml.Unmarshal(b, kc)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse kubeconfig '%s': %s", path, err)
+	}
+	if len(kc.CurrentContext) == 0 {
+		return nil, fmt.Errorf("No current-context set in kubeconfig '%s'", path)
+	}
+
+	creds := &kubeCredentials{TLSConfig: &tls.Config{}}
+	clusterName, userName := "", ""
+	for _, c := range kc.Contexts {
+		if c.Name == kc.CurrentContext {
+			clusterName = c.Context.Cluster
+			userName = c.Context.User
+			creds.Namespace = c.Context.Namespace
+		}
+	}
+	if len(clusterName) == 0 {
+		return nil, fmt.Errorf("No such context in kubeconfig '%s': %s", path, kc.CurrentContext)
+	}
+
+	clusterFound := false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		clusterFound = true
+		creds.Server = strings.TrimSuffix(c.Cluster.Server, "/")
+		creds.TLSConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		caData, err := readDataOrFile(c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read certificate authority: %s", err)
+		}
+		if len(caData) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caData) {
+				return nil, errors.New("Could not parse certificate authority")
+			}
+			creds.TLSConfig.RootCAs = pool
+		}
+	}
+	if !clusterFound {
+		return nil, fmt.Errorf("No such cluster in kubeconfig '%s': %s", path, clusterName)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		creds.Token = u.User.Token
+		if len(creds.Token) == 0 && len(u.User.TokenFile) > 0 {
+			t, err := os.ReadFile(u.User.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("Could not read token file: %s", err)
+			}
+			creds.Token = strings.TrimSpace(string(t))
+		}
+		creds.Username = u.User.Username
+		creds.Password = u.User.Password
+		certData, err := readDataOrFile(u.User.ClientCertificateData, u.User.ClientCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read client certificate: %s", err)
+		}
+		keyData, err := readDataOrFile(u.User.ClientKeyData, u.User.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read client key: %s", err)
+		}
+		if len(certData) > 0 && len(keyData) > 0 {
+			cert, err := tls.X509KeyPair(certData, keyData)
+			if err != nil {
+				return nil, fmt.Errorf("Could not load client certificate: %s", err)
+			}
+			creds.TLSConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	return creds, nil
+}
+
+// readDataOrFile returns the base64-decoded data if given, otherwise the
+// content of file (if given).
+func readDataOrFile(data string, file string) ([]byte, error) {
+	if len(data) > 0 {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if len(file) > 0 {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
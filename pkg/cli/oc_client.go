@@ -8,9 +8,23 @@ import (
 	"strings"
 )
 
+// Client allows to perform all operations against the cluster. It is
+// implemented by OcClient and KubeClient.
+type Client interface {
+	ClientApplier
+	OcClientPatcher
+	OcClientVersioner
+	OcClientProjecter
+	OcClientDiscoverer
+}
+
+// ClientApplier allows to process templates, export, modify and get resources,
+// and to validate changes with a server-side dry-run.
 type ClientApplier interface {
 	ClientProcessorExporter
 	ClientModifier
+	OcClientGetter
+	OcClientDryRunner
 }
 
 // ClientProcessorExporter allows to process templates and export resources.
@@ -19,12 +33,25 @@ type ClientProcessorExporter interface {
 	OcClientExporter
 }
 
+// ClientComparer allows to process templates, export resources and validate
+// changes with a server-side dry-run.
+type ClientComparer interface {
+	ClientProcessorExporter
+	OcClientDryRunner
+}
+
 // ClientModifier allows to delete and create/update resources.
 type ClientModifier interface {
 	OcClientApplier
 	OcClientDeleter
 }
 
+// ClientModifierGetter allows to delete, create/update and retrieve resources.
+type ClientModifierGetter interface {
+	ClientModifier
+	OcClientGetter
+}
+
 // OcClientProcessor is a stop-gap solution only ... should have a better API.
 type OcClientProcessor interface {
 	Process(args []string) ([]byte, []byte, error)
@@ -45,11 +72,78 @@ type OcClientApplier interface {
 	Apply(config string, selector string) ([]byte, error)
 }
 
+// OcClientDryRunner allows to validate a create/update of a resource on the
+// server without persisting it.
+type OcClientDryRunner interface {
+	DryRunApply(config string, selector string) ([]byte, error)
+}
+
+// ClientPatcherExporter allows to export and patch resources.
+type ClientPatcherExporter interface {
+	OcClientExporter
+	OcClientPatcher
+}
+
+// OcClientPatcher allows to patch a resource with a JSON merge patch.
+type OcClientPatcher interface {
+	Patch(kind string, name string, patch string) ([]byte, error)
+}
+
+// OcClientGetter allows to retrieve a single resource as JSON.
+type OcClientGetter interface {
+	Get(kind string, name string) ([]byte, error)
+}
+
 // OcClientVersioner allows to retrieve the OpenShift version..
 type OcClientVersioner interface {
 	Version() ([]byte, []byte, error)
 }
 
+// OcClientProjecter allows to work with projects (namespaces) and sessions.
+type OcClientProjecter interface {
+	CurrentProject() (string, error)
+	CheckProjectExists(p string) (bool, error)
+	ListProjects(selector string) ([]string, error)
+	CheckLoggedIn() (bool, error)
+}
+
+// NewClient creates a new client for the backend selected via global options.
+// If local processing is enabled, templates are processed in-process
+// regardless of the backend.
+//
+// With local processing, the API backend connects only once the cluster is
+// accessed, so that e.g. rendering works without a kubeconfig file.
+func NewClient(namespace string) (Client, error) {
+	if localProcessing {
+		var c Client
+		if backend == "api" {
+			c = newLazyKubeClient(namespace, kubeconfigPath)
+		} else {
+			c = NewOcClient(namespace)
+		}
+		return &localProcessingClient{Client: c, processor: NewLocalProcessor()}, nil
+	}
+	if backend == "api" {
+		kc, err := NewKubeClient(namespace, kubeconfigPath)
+		if err != nil {
+			return nil, err
+		}
+		return kc, nil
+	}
+	return NewOcClient(namespace), nil
+}
+
+// localProcessingClient delegates everything but processing to its backend.
+type localProcessingClient struct {
+	Client
+	processor *LocalProcessor
+}
+
+// Process processes the template in-process.
+func (c *localProcessingClient) Process(args []string) ([]byte, []byte, error) {
+	return c.processor.Process(args)
+}
+
 // OcClient is a wrapper around the "oc" binary (client).
 type OcClient struct {
 	namespace string
@@ -81,6 +175,27 @@ func (c *OcClient) CheckProjectExists(p string) (bool, error) {
 	return err == nil, err
 }
 
+// ListProjects returns the names of all projects (namespaces) the user can
+// access, limited to those matching the label selector if given.
+func (c *OcClient) ListProjects(selector string) ([]string, error) {
+	args := []string{"get", "projects", "--output=name"}
+	if len(selector) > 0 {
+		args = append(args, "--selector="+selector)
+	}
+	cmd := c.execPlainOcCmd(args)
+	outBytes, errBytes, err := c.runCmd(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("Could not list projects: %s", strings.TrimSpace(string(errBytes)))
+	}
+	projects := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(outBytes)), "\n") {
+		if len(line) > 0 {
+			projects = append(projects, line[strings.LastIndex(line, "/")+1:])
+		}
+	}
+	return projects, nil
+}
+
 // CheckLoggedIn returns true if the given project (namespace) exists.
 func (c *OcClient) CheckLoggedIn() (bool, error) {
 	cmd := exec.Command(ocBinary, "whoami")
@@ -126,7 +241,17 @@ func (c *OcClient) Export(target string, label string) ([]byte, error) {
 
 // Apply applies given resource configuration.
 func (c *OcClient) Apply(config string, selector string) ([]byte, error) {
-	args := []string{"apply", "-f", "-"}
+	return c.apply(config, selector)
+}
+
+// DryRunApply sends given resource configuration through a server-side
+// dry-run apply, which validates it without persisting it.
+func (c *OcClient) DryRunApply(config string, selector string) ([]byte, error) {
+	return c.apply(config, selector, "--dry-run=server")
+}
+
+func (c *OcClient) apply(config string, selector string, extraArgs ...string) ([]byte, error) {
+	args := append([]string{"apply", "-f", "-"}, extraArgs...)
 	cmd := c.execOcCmd(
 		args,
 		c.namespace,
@@ -156,6 +281,33 @@ func (c *OcClient) Delete(kind string, name string) ([]byte, error) {
 	return errBytes, err
 }
 
+// Patch applies given JSON merge patch to given resource.
+func (c *OcClient) Patch(kind string, name string, patch string) ([]byte, error) {
+	args := []string{"patch", kind, name, "--type=merge", "--patch=" + patch}
+	cmd := c.execOcCmd(
+		args,
+		c.namespace,
+		"", // empty as name and selector is not allowed
+	)
+	_, errBytes, err := c.runCmd(cmd)
+	return errBytes, err
+}
+
+// Get retrieves given resource as JSON.
+func (c *OcClient) Get(kind string, name string) ([]byte, error) {
+	args := []string{"get", kind, name, "--output=json"}
+	cmd := c.execOcCmd(
+		args,
+		c.namespace,
+		"", // empty as name and selector is not allowed
+	)
+	outBytes, errBytes, err := c.runCmd(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get %s %s: %s", kind, name, strings.TrimSpace(string(errBytes)))
+	}
+	return outBytes, nil
+}
+
 func (c *OcClient) execOcCmd(args []string, namespace string, selector string) *exec.Cmd {
 	if len(namespace) > 0 {
 		args = append(args, "--namespace="+namespace)
@@ -5,27 +5,74 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/opendevstack/tailor/pkg/utils"
 )
 
+// labelValueRegex matches valid values of Kubernetes labels.
+var labelValueRegex = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+
+// maxDeletionsRegex matches an absolute number or a percentage.
+var maxDeletionsRegex = regexp.MustCompile(`^[0-9]+%?$`)
+
+// defaultSchema is the bundled schema used by validate if none is configured.
+const defaultSchema = "openshift-4"
+
+// DefaultBackupDir returns the directory snapshots are saved to by default.
+// As snapshots contain Secret data in clear text, it is located in the state
+// directory of the user (see the XDG base directory specification) instead of
+// the working directory, which usually is a git repository.
+func DefaultBackupDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); len(dir) > 0 {
+		return filepath.Join(dir, "tailor", "backups")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".tailor", "backups")
+	}
+	return filepath.Join(home, ".local", "state", "tailor", "backups")
+}
+
 // GlobalOptions are app-wide.
 type GlobalOptions struct {
 	Verbose         bool
 	Debug           bool
 	NonInteractive  bool
 	OcBinary        string
+	Backend         string
+	Kubeconfig      string
+	LocalProcessing bool
+	Kinds           []string
+	Owner           string
+	OwnerKey        string
+	OwnerAnnotation bool
 	File            string
+	Env             string
+	// Command selects the command section of a YAML Tailorfile.
+	Command         string
 	Force           bool
 	IsLoggedIn      bool
 	ClusterRequired bool
-	fs              utils.FileStater
+	// apiResources are discovered from the cluster (if required).
+	apiResources []APIResource
+	fs           utils.FileStater
 }
 
 // NamespaceOptions define which namespace Tailor works against.
 type NamespaceOptions struct {
-	Namespace         string
+	Namespace string
+	// NamespaceSelector is a label selector for namespaces to target.
+	NamespaceSelector string
+	// Namespaces are the namespaces targeted if Namespace is a list or glob,
+	// or NamespaceSelector is set. Namespace is not used then.
+	Namespaces        []string
 	CheckedNamespaces []string
 }
 
@@ -47,11 +94,45 @@ type CompareOptions struct {
 	IgnoreUnknownParameters bool
 	UpsertOnly              bool
 	AllowRecreate           bool
+	AllowProtectedDeletion  bool
 	RevealSecrets           bool
+	RedactSecrets           bool
+	ServerDryRun            bool
 	Verify                  bool
+	Wait                    bool
+	ContinueOnError         bool
+	WaitTimeout             time.Duration
+	BackupDir               string
+	MaxDeletions            string
+	Parallelism             int
+	NamespaceParallelism    int
+	Report                  string
+	Schema                  string
+	Hooks                   []*Hook
+	Output                  string
+	OutPlan                 string
+	Plan                    string
 	Resource                string
 }
 
+// RenderOptions define how the desired state should be rendered.
+type RenderOptions struct {
+	*CompareOptions
+	Format    string
+	OutputDir string
+}
+
+// ValidateOptions define how the desired state should be validated.
+type ValidateOptions struct {
+	*CompareOptions
+}
+
+// RollbackOptions define which snapshot should be restored.
+type RollbackOptions struct {
+	*CompareOptions
+	Snapshot string
+}
+
 // ExportOptions define how the export should be done.
 type ExportOptions struct {
 	*GlobalOptions
@@ -63,7 +144,21 @@ type ExportOptions struct {
 	WithAnnotations        bool
 	WithHardcodedNamespace bool
 	TrimAnnotations        []string
-	Resource               string
+	// OutputDir receives one template per group (see GroupBy) instead of
+	// printing a single template to STDOUT.
+	OutputDir string
+	// GroupBy is either "app" (the app label) or "kind".
+	GroupBy  string
+	Resource string
+}
+
+// AdoptOptions define which resources should be adopted.
+type AdoptOptions struct {
+	*GlobalOptions
+	*NamespaceOptions
+	Selector string
+	Excludes []string
+	Resource string
 }
 
 // SecretsOptions define how to work with encrypted files.
@@ -84,16 +179,27 @@ func InitGlobalOptions(fs utils.FileStater) *GlobalOptions {
 // Those options are shared across all commands.
 func NewGlobalOptions(
 	clusterRequired bool,
+	commandFlag string,
 	fileFlag string,
+	envFlag string,
 	verboseFlag bool,
 	debugFlag bool,
 	nonInteractiveFlag bool,
 	ocBinaryFlag string,
+	backendFlag string,
+	kubeconfigFlag string,
+	localProcessingFlag bool,
+	kindFlag []string,
+	ownerFlag string,
+	ownerKeyFlag string,
+	ownerAnnotationFlag bool,
 	forceFlag bool) (*GlobalOptions, error) {
 	o := InitGlobalOptions(&utils.OsFS{})
 	o.ClusterRequired = clusterRequired
+	o.Command = commandFlag
+	o.Env = envFlag
 
-	fileFlags, err := getFileFlags(fileFlag, verbose)
+	fileFlags, err := getFileFlags(fileFlag, o.Env, o.Command, verbose)
 	if err != nil {
 		return o, fmt.Errorf("Could not read %s: %s", fileFlag, err)
 	}
@@ -126,6 +232,53 @@ func NewGlobalOptions(
 		o.OcBinary = val
 	}
 
+	o.Backend = "oc"
+	if len(backendFlag) > 0 && backendFlag != "oc" {
+		o.Backend = backendFlag
+	} else if val, ok := fileFlags["backend"]; ok {
+		o.Backend = val
+	}
+
+	if len(kubeconfigFlag) > 0 {
+		o.Kubeconfig = kubeconfigFlag
+	} else if val, ok := fileFlags["kubeconfig"]; ok {
+		o.Kubeconfig = val
+	}
+
+	if localProcessingFlag {
+		o.LocalProcessing = true
+	} else if fileFlags["local-processing"] == "true" {
+		o.LocalProcessing = true
+	}
+
+	o.Kinds = []string{}
+	if len(kindFlag) > 0 {
+		for _, val := range kindFlag {
+			o.Kinds = append(o.Kinds, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["kind"]; ok {
+		o.Kinds = splitFileFlag(val)
+	}
+
+	if len(ownerFlag) > 0 {
+		o.Owner = ownerFlag
+	} else if val, ok := fileFlags["owner"]; ok {
+		o.Owner = val
+	}
+
+	o.OwnerKey = "tailor.opendevstack.org/owner"
+	if len(ownerKeyFlag) > 0 && ownerKeyFlag != "tailor.opendevstack.org/owner" {
+		o.OwnerKey = ownerKeyFlag
+	} else if val, ok := fileFlags["owner-key"]; ok {
+		o.OwnerKey = val
+	}
+
+	if ownerAnnotationFlag {
+		o.OwnerAnnotation = true
+	} else if fileFlags["owner-annotation"] == "true" {
+		o.OwnerAnnotation = true
+	}
+
 	if forceFlag {
 		o.Force = true
 	} else if fileFlags["force"] == "true" {
@@ -135,109 +288,137 @@ func NewGlobalOptions(
 	verbose = o.Verbose || o.Debug
 	debug = o.Debug
 	ocBinary = o.OcBinary
+	backend = o.Backend
+	kubeconfigPath = o.Kubeconfig
+	localProcessing = o.LocalProcessing
 
 	DebugMsg(fmt.Sprintf("%#v", o))
 
 	return o, o.check(clusterRequired)
 }
 
+// CompareFlags holds the flags of the commands based on CompareOptions. Flags
+// which are not set (zero value) are taken from the Tailorfile, if present.
+type CompareFlags struct {
+	Namespace               string
+	NamespaceSelector       string
+	Selector                string
+	Excludes                []string
+	TemplateDir             string
+	ParamDir                string
+	PrivateKey              string
+	Passphrase              string
+	Labels                  string
+	Params                  []string
+	ParamFiles              []string
+	PreservePaths           []string
+	PreserveImmutableFields bool
+	IgnoreUnknownParameters bool
+	UpsertOnly              bool
+	AllowRecreate           bool
+	AllowProtectedDeletion  bool
+	RevealSecrets           bool
+	RedactSecrets           bool
+	ServerDryRun            bool
+	Verify                  bool
+	Wait                    bool
+	ContinueOnError         bool
+	WaitTimeout             string
+	BackupDir               string
+	MaxDeletions            string
+	Parallelism             string
+	NamespaceParallelism    string
+	Report                  string
+	Schema                  string
+	Output                  string
+	OutPlan                 string
+	Plan                    string
+	Resource                string
+}
+
 // NewCompareOptions returns new options for the diff/apply command based on file/flags.
-func NewCompareOptions(
-	globalOptions *GlobalOptions,
-	namespaceFlag string,
-	selectorFlag string,
-	excludeFlag []string,
-	templateDirFlag string,
-	paramDirFlag string,
-	publicKeyDirFlag string,
-	privateKeyFlag string,
-	passphraseFlag string,
-	labelsFlag string,
-	paramFlag []string,
-	paramFileFlag []string,
-	preserveFlag []string,
-	preserveImmutableFieldsFlag bool,
-	ignoreUnknownParametersFlag bool,
-	upsertOnlyFlag bool,
-	allowRecreateFlag bool,
-	revealSecretsFlag bool,
-	verifyFlag bool,
-	resourceArg string) (*CompareOptions, error) {
+func NewCompareOptions(globalOptions *GlobalOptions, flags *CompareFlags) (*CompareOptions, error) {
 	o := &CompareOptions{
 		GlobalOptions:    globalOptions,
 		NamespaceOptions: &NamespaceOptions{},
 	}
-	filename := o.resolvedFile(namespaceFlag)
+	filename := o.resolvedFile(flags.Namespace)
 
-	fileFlags, err := getFileFlags(filename, verbose)
+	fileFlags, _, tailorfile, err := readFileFlags(filename, o.Env, o.Command, verbose)
 	if err != nil {
 		return o, fmt.Errorf("Could not read '%s': %s", filename, err)
 	}
 
-	if len(namespaceFlag) > 0 {
-		o.Namespace = namespaceFlag
+	if len(flags.Namespace) > 0 {
+		o.Namespace = flags.Namespace
 	} else if val, ok := fileFlags["namespace"]; ok {
 		o.Namespace = val
 	}
 
-	if len(selectorFlag) > 0 {
-		o.Selector = selectorFlag
+	if len(flags.NamespaceSelector) > 0 {
+		o.NamespaceSelector = flags.NamespaceSelector
+	} else if val, ok := fileFlags["namespace-selector"]; ok {
+		o.NamespaceSelector = val
+	}
+
+	if len(flags.Selector) > 0 {
+		o.Selector = flags.Selector
 	} else if val, ok := fileFlags["selector"]; ok {
 		o.Selector = val
 	}
 
 	o.Excludes = []string{}
-	if len(excludeFlag) > 0 {
-		for _, val := range excludeFlag {
+	if len(flags.Excludes) > 0 {
+		for _, val := range flags.Excludes {
 			o.Excludes = append(o.Excludes, strings.Split(val, ",")...)
 		}
 	} else if val, ok := fileFlags["exclude"]; ok {
-		o.Excludes = strings.Split(val, ",")
+		o.Excludes = splitFileFlag(val)
 	}
 
 	o.TemplateDir = "."
-	if templateDirFlag != "." {
-		o.TemplateDir = templateDirFlag
+	if flags.TemplateDir != "." && len(flags.TemplateDir) > 0 {
+		o.TemplateDir = flags.TemplateDir
 	} else if val, ok := fileFlags["template-dir"]; ok {
 		o.TemplateDir = val
 	}
 
 	o.ParamDir = "."
-	if paramDirFlag != "." {
-		o.ParamDir = paramDirFlag
+	if flags.ParamDir != "." && len(flags.ParamDir) > 0 {
+		o.ParamDir = flags.ParamDir
 	} else if val, ok := fileFlags["param-dir"]; ok {
 		o.ParamDir = val
 	}
 
 	o.PrivateKey = "private.key"
-	if privateKeyFlag != "private.key" {
-		o.PrivateKey = privateKeyFlag
+	if flags.PrivateKey != "private.key" && len(flags.PrivateKey) > 0 {
+		o.PrivateKey = flags.PrivateKey
 	} else if val, ok := fileFlags["private-key"]; ok {
 		o.PrivateKey = val
 	}
 
-	if len(passphraseFlag) > 0 {
-		o.Passphrase = passphraseFlag
+	if len(flags.Passphrase) > 0 {
+		o.Passphrase = flags.Passphrase
 	} else if val, ok := fileFlags["passphrase"]; ok {
 		o.Passphrase = val
 	}
 
-	if len(labelsFlag) > 0 {
-		o.Labels = labelsFlag
+	if len(flags.Labels) > 0 {
+		o.Labels = flags.Labels
 	} else if val, ok := fileFlags["labels"]; ok {
 		o.Labels = val
 	}
 
 	if val, ok := fileFlags["param"]; ok {
-		o.Params = strings.Split(val, ",")
+		o.Params = splitFileFlag(val)
 	}
-	if len(paramFlag) > 0 {
+	if len(flags.Params) > 0 {
 		params := map[string]string{}
 		for _, setParam := range o.Params {
 			setPair := strings.SplitN(setParam, "=", 2)
 			key := setPair[0]
 			params[key] = setPair[1]
-			for _, newParam := range paramFlag {
+			for _, newParam := range flags.Params {
 				newPair := strings.SplitN(newParam, "=", 2)
 				if key == newPair[0] {
 					params[key] = newPair[1]
@@ -249,7 +430,7 @@ func NewCompareOptions(
 		for k, v := range params {
 			o.Params = append(o.Params, k+"="+v)
 		}
-		for _, v := range paramFlag {
+		for _, v := range flags.Params {
 			pair := strings.SplitN(v, "=", 2)
 			if _, ok := params[pair[0]]; !ok {
 				o.Params = append(o.Params, v)
@@ -257,56 +438,289 @@ func NewCompareOptions(
 		}
 	}
 
-	if len(paramFileFlag) > 0 {
-		o.ParamFiles = paramFileFlag
+	if len(flags.ParamFiles) > 0 {
+		o.ParamFiles = flags.ParamFiles
 	} else if val, ok := fileFlags["param-file"]; ok {
-		o.ParamFiles = strings.Split(val, ",")
+		o.ParamFiles = splitFileFlag(val)
 	}
 
-	if len(preserveFlag) > 0 {
-		o.PreservePaths = preserveFlag
+	if len(flags.PreservePaths) > 0 {
+		o.PreservePaths = flags.PreservePaths
 	} else if val, ok := fileFlags["ignore-path"]; ok {
-		o.PreservePaths = strings.Split(val, ",")
+		o.PreservePaths = splitFileFlag(val)
 	} else if val, ok := fileFlags["preserve"]; ok {
-		o.PreservePaths = strings.Split(val, ",")
+		o.PreservePaths = splitFileFlag(val)
 	}
 
-	if preserveImmutableFieldsFlag {
+	if flags.PreserveImmutableFields {
 		o.PreserveImmutableFields = true
 	} else if fileFlags["preserve-immutable-fields"] == "true" {
 		o.PreserveImmutableFields = true
 	}
 
-	if ignoreUnknownParametersFlag {
+	if flags.IgnoreUnknownParameters {
 		o.IgnoreUnknownParameters = true
 	} else if fileFlags["ignore-unknown-parameters"] == "true" {
 		o.IgnoreUnknownParameters = true
 	}
 
-	if upsertOnlyFlag {
+	if flags.UpsertOnly {
 		o.UpsertOnly = true
 	} else if fileFlags["upsert-only"] == "true" {
 		o.UpsertOnly = true
 	}
 
-	if allowRecreateFlag {
+	if flags.AllowRecreate {
 		o.AllowRecreate = true
 	} else if fileFlags["allow-recreate"] == "true" {
 		o.AllowRecreate = true
 	}
 
-	if revealSecretsFlag {
+	if flags.AllowProtectedDeletion {
+		o.AllowProtectedDeletion = true
+	} else if fileFlags["allow-protected-deletion"] == "true" {
+		o.AllowProtectedDeletion = true
+	}
+
+	if flags.RevealSecrets {
 		o.RevealSecrets = true
 	} else if fileFlags["reveal-secrets"] == "true" {
 		o.RevealSecrets = true
 	}
 
-	if verifyFlag {
+	if flags.RedactSecrets {
+		o.RedactSecrets = true
+	} else if fileFlags["redact-secrets"] == "true" {
+		o.RedactSecrets = true
+	}
+
+	if flags.ServerDryRun {
+		o.ServerDryRun = true
+	} else if fileFlags["server-dry-run"] == "true" {
+		o.ServerDryRun = true
+	}
+
+	if flags.Verify {
 		o.Verify = true
 	} else if fileFlags["verify"] == "true" {
 		o.Verify = true
 	}
 
+	if flags.Wait {
+		o.Wait = true
+	} else if fileFlags["wait"] == "true" {
+		o.Wait = true
+	}
+
+	if flags.ContinueOnError {
+		o.ContinueOnError = true
+	} else if fileFlags["continue-on-error"] == "true" {
+		o.ContinueOnError = true
+	}
+
+	waitTimeout := "5m"
+	if flags.WaitTimeout != "5m" && len(flags.WaitTimeout) > 0 {
+		waitTimeout = flags.WaitTimeout
+	} else if val, ok := fileFlags["wait-timeout"]; ok {
+		waitTimeout = val
+	}
+	o.WaitTimeout, err = time.ParseDuration(waitTimeout)
+	if err != nil {
+		return o, fmt.Errorf("Wait timeout '%s' is not a valid duration (e.g. 90s or 5m)", waitTimeout)
+	}
+
+	o.BackupDir = DefaultBackupDir()
+	if flags.BackupDir != o.BackupDir && len(flags.BackupDir) > 0 {
+		o.BackupDir = flags.BackupDir
+	} else if val, ok := fileFlags["backup-dir"]; ok {
+		o.BackupDir = val
+	}
+
+	if len(flags.MaxDeletions) > 0 {
+		o.MaxDeletions = flags.MaxDeletions
+	} else if val, ok := fileFlags["max-deletions"]; ok {
+		o.MaxDeletions = val
+	}
+
+	parallelism := "1"
+	if flags.Parallelism != "1" && len(flags.Parallelism) > 0 {
+		parallelism = flags.Parallelism
+	} else if val, ok := fileFlags["parallelism"]; ok {
+		parallelism = val
+	}
+	o.Parallelism, err = strconv.Atoi(parallelism)
+	if err != nil {
+		return o, fmt.Errorf("Parallelism '%s' is not a number", parallelism)
+	}
+
+	namespaceParallelism := "1"
+	if flags.NamespaceParallelism != "1" && len(flags.NamespaceParallelism) > 0 {
+		namespaceParallelism = flags.NamespaceParallelism
+	} else if val, ok := fileFlags["namespace-parallelism"]; ok {
+		namespaceParallelism = val
+	}
+	o.NamespaceParallelism, err = strconv.Atoi(namespaceParallelism)
+	if err != nil {
+		return o, fmt.Errorf("Namespace parallelism '%s' is not a number", namespaceParallelism)
+	}
+
+	if len(flags.Report) > 0 {
+		o.Report = flags.Report
+	} else if val, ok := fileFlags["report"]; ok {
+		o.Report = val
+	}
+
+	if len(flags.Schema) > 0 {
+		o.Schema = flags.Schema
+	} else if val, ok := fileFlags["schema"]; ok {
+		o.Schema = val
+	}
+
+	o.Hooks, err = parseHooks(fileFlags, filepath.Dir(tailorfile))
+	if err != nil {
+		return o, err
+	}
+
+	o.Output = "text"
+	if flags.Output != "text" && len(flags.Output) > 0 {
+		o.Output = flags.Output
+	} else if val, ok := fileFlags["output"]; ok {
+		o.Output = val
+	}
+
+	if len(flags.OutPlan) > 0 {
+		o.OutPlan = flags.OutPlan
+	} else if val, ok := fileFlags["out-plan"]; ok {
+		o.OutPlan = val
+	}
+
+	if len(flags.Plan) > 0 {
+		o.Plan = flags.Plan
+	} else if val, ok := fileFlags["plan"]; ok {
+		o.Plan = val
+	}
+
+	if len(flags.Resource) > 0 {
+		o.Resource = flags.Resource
+	} else if val, ok := fileFlags["resource"]; ok {
+		o.Resource = val
+	}
+
+	DebugMsg(fmt.Sprintf("%#v", o))
+
+	return o, o.check(o.ClusterRequired)
+}
+
+// NewRenderOptions returns new options for the render command based on file/flags.
+// The compare options are expected to be created without requiring a cluster.
+func NewRenderOptions(
+	compareOptions *CompareOptions,
+	formatFlag string,
+	outputDirFlag string) (*RenderOptions, error) {
+	o := &RenderOptions{
+		CompareOptions: compareOptions,
+	}
+	// Rendering shows the desired state as is, checking it against a schema
+	// is left to diff, apply and validate.
+	o.Schema = ""
+	filename := o.resolvedFile(o.Namespace)
+
+	fileFlags, err := getFileFlags(filename, o.Env, o.Command, verbose)
+	if err != nil {
+		return o, fmt.Errorf("Could not read '%s': %s", filename, err)
+	}
+
+	o.Format = "yaml"
+	if formatFlag != "yaml" && len(formatFlag) > 0 {
+		o.Format = formatFlag
+	} else if val, ok := fileFlags["format"]; ok {
+		o.Format = val
+	}
+
+	if len(outputDirFlag) > 0 {
+		o.OutputDir = outputDirFlag
+	} else if val, ok := fileFlags["output-dir"]; ok {
+		o.OutputDir = val
+	}
+
+	DebugMsg(fmt.Sprintf("%#v", o))
+
+	return o, o.check()
+}
+
+// NewValidateOptions returns new options for the validate command. Without a
+// configured schema, the bundled OpenShift 4 schema is used.
+func NewValidateOptions(compareOptions *CompareOptions) (*ValidateOptions, error) {
+	o := &ValidateOptions{
+		CompareOptions: compareOptions,
+	}
+	if len(o.Schema) == 0 {
+		o.Schema = defaultSchema
+	}
+
+	DebugMsg(fmt.Sprintf("%#v", o))
+
+	return o, o.requireClusterForProcessing()
+}
+
+// NewRollbackOptions returns new options for the rollback command based on file/flags.
+func NewRollbackOptions(
+	compareOptions *CompareOptions,
+	snapshotArg string) (*RollbackOptions, error) {
+	o := &RollbackOptions{
+		CompareOptions: compareOptions,
+		Snapshot:       snapshotArg,
+	}
+	// Hooks belong to applying templates, not to restoring snapshots.
+	o.Hooks = nil
+	if len(o.Namespaces) > 0 {
+		return o, errors.New("Rollback supports only a single namespace")
+	}
+
+	DebugMsg(fmt.Sprintf("%#v", o))
+
+	return o, nil
+}
+
+// NewAdoptOptions returns new options for the adopt command based on file/flags.
+func NewAdoptOptions(
+	globalOptions *GlobalOptions,
+	namespaceFlag string,
+	selectorFlag string,
+	excludeFlag []string,
+	resourceArg string) (*AdoptOptions, error) {
+	o := &AdoptOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &NamespaceOptions{},
+	}
+	filename := o.resolvedFile(namespaceFlag)
+
+	fileFlags, err := getFileFlags(filename, o.Env, o.Command, verbose)
+	if err != nil {
+		return o, fmt.Errorf("Could not read %s: %s", filename, err)
+	}
+
+	if len(namespaceFlag) > 0 {
+		o.Namespace = namespaceFlag
+	} else if val, ok := fileFlags["namespace"]; ok {
+		o.Namespace = val
+	}
+
+	if len(selectorFlag) > 0 {
+		o.Selector = selectorFlag
+	} else if val, ok := fileFlags["selector"]; ok {
+		o.Selector = val
+	}
+
+	o.Excludes = []string{}
+	if len(excludeFlag) > 0 {
+		for _, val := range excludeFlag {
+			o.Excludes = append(o.Excludes, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["exclude"]; ok {
+		o.Excludes = splitFileFlag(val)
+	}
+
 	if len(resourceArg) > 0 {
 		o.Resource = resourceArg
 	} else if val, ok := fileFlags["resource"]; ok {
@@ -315,7 +729,7 @@ func NewCompareOptions(
 
 	DebugMsg(fmt.Sprintf("%#v", o))
 
-	return o, o.check(o.ClusterRequired)
+	return o, o.check()
 }
 
 // NewExportOptions returns new options for the export command based on file/flags.
@@ -329,6 +743,8 @@ func NewExportOptions(
 	withAnnotationsFlag bool,
 	withHardcodedNamespaceFlag bool,
 	trimAnnotationsFlag []string,
+	outputDirFlag string,
+	groupByFlag string,
 	resourceArg string) (*ExportOptions, error) {
 	o := &ExportOptions{
 		GlobalOptions:    globalOptions,
@@ -336,7 +752,7 @@ func NewExportOptions(
 	}
 	filename := o.resolvedFile(namespaceFlag)
 
-	fileFlags, err := getFileFlags(filename, verbose)
+	fileFlags, err := getFileFlags(filename, o.Env, o.Command, verbose)
 	if err != nil {
 		return o, fmt.Errorf("Could not read %s: %s", filename, err)
 	}
@@ -359,7 +775,7 @@ func NewExportOptions(
 			o.Excludes = append(o.Excludes, strings.Split(val, ",")...)
 		}
 	} else if val, ok := fileFlags["exclude"]; ok {
-		o.Excludes = strings.Split(val, ",")
+		o.Excludes = splitFileFlag(val)
 	}
 
 	o.TemplateDir = "."
@@ -391,7 +807,20 @@ func NewExportOptions(
 	if len(trimAnnotationsFlag) > 0 {
 		o.TrimAnnotations = trimAnnotationsFlag
 	} else if val, ok := fileFlags["trim-annotation"]; ok {
-		o.TrimAnnotations = strings.Split(val, ",")
+		o.TrimAnnotations = splitFileFlag(val)
+	}
+
+	if len(outputDirFlag) > 0 {
+		o.OutputDir = outputDirFlag
+	} else if val, ok := fileFlags["output-dir"]; ok {
+		o.OutputDir = val
+	}
+
+	o.GroupBy = "app"
+	if groupByFlag != "app" && len(groupByFlag) > 0 {
+		o.GroupBy = groupByFlag
+	} else if val, ok := fileFlags["group-by"]; ok {
+		o.GroupBy = val
 	}
 
 	if len(resourceArg) > 0 {
@@ -418,7 +847,7 @@ func NewSecretsOptions(
 	namespaceFlag := "" // namespace does not make sense for secrets
 	filename := o.resolvedFile(namespaceFlag)
 
-	fileFlags, err := getFileFlags(filename, verbose)
+	fileFlags, err := getFileFlags(filename, o.Env, o.Command, verbose)
 	if err != nil {
 		return o, fmt.Errorf("Could not read %s: %s", filename, err)
 	}
@@ -459,10 +888,15 @@ func (o *GlobalOptions) resolvedFile(namespaceFlag string) string {
 		return o.File
 	}
 	namespacedFile := fmt.Sprintf("%s.%s", o.File, namespaceFlag)
-	if _, err := o.fs.Stat(namespacedFile); os.IsNotExist(err) {
-		return o.File
+	if _, err := o.fs.Stat(namespacedFile); err == nil {
+		return namespacedFile
+	}
+	for _, ext := range yamlTailorfileExtensions {
+		if _, err := o.fs.Stat(namespacedFile + ext); err == nil {
+			return namespacedFile
+		}
 	}
-	return namespacedFile
+	return o.File
 }
 
 // FileExists checks whether given file exists.
@@ -472,6 +906,22 @@ func (o *GlobalOptions) FileExists(file string) bool {
 }
 
 func (o *GlobalOptions) check(clusterRequired bool) error {
+	if o.Backend != "oc" && o.Backend != "api" {
+		return fmt.Errorf("Backend '%s' is not supported, use 'oc' or 'api'", o.Backend)
+	}
+	if len(o.Owner) > 0 && !o.OwnerAnnotation && !labelValueRegex.MatchString(o.Owner) {
+		return fmt.Errorf("Owner '%s' is not a valid label value, use --owner-annotation to allow arbitrary values", o.Owner)
+	}
+	if o.Backend == "api" {
+		// The API backend talks to the server directly, so neither the oc
+		// binary nor a matching client version is required.
+		if clusterRequired {
+			if !o.checkLoggedIn() {
+				return errors.New("You need valid credentials in your kubeconfig first")
+			}
+		}
+		return nil
+	}
 	if !o.checkOcBinary() {
 		return fmt.Errorf("No such oc binary: %s", o.OcBinary)
 	}
@@ -498,9 +948,40 @@ func (o *GlobalOptions) check(clusterRequired bool) error {
 	return nil
 }
 
+// DiscoverAPIResources returns the resources served by the cluster. Discovery
+// happens at most once, and only if the command requires a cluster. If
+// discovery fails, Tailor falls back to the built-in kinds.
+func (o *GlobalOptions) DiscoverAPIResources() []APIResource {
+	if o.apiResources != nil {
+		return o.apiResources
+	}
+	o.apiResources = []APIResource{}
+	if !o.ClusterRequired {
+		return o.apiResources
+	}
+	c, err := NewClient("")
+	if err == nil {
+		var apiResources []APIResource
+		apiResources, err = c.APIResources()
+		if err == nil {
+			o.apiResources = apiResources
+		}
+	}
+	if err != nil {
+		VerboseMsg("Could not discover API resources, using built-in kinds only:", err.Error())
+		return o.apiResources
+	}
+	DebugMsg(fmt.Sprintf("Discovered %d API resources", len(o.apiResources)))
+	return o.apiResources
+}
+
 func (o *GlobalOptions) checkLoggedIn() bool {
 	if !o.IsLoggedIn {
-		c := NewOcClient("")
+		c, err := NewClient("")
+		if err != nil {
+			VerboseMsg(err.Error())
+			return false
+		}
 		loggedIn, err := c.CheckLoggedIn()
 		if err != nil {
 			VerboseMsg(err.Error())
@@ -520,6 +1001,26 @@ func (o *GlobalOptions) checkOcBinary() bool {
 }
 
 func (o *CompareOptions) check(clusterRequired bool) error {
+	if o.Output != "text" && o.Output != "json" {
+		return fmt.Errorf("Output format '%s' is not supported, use 'text' or 'json'", o.Output)
+	}
+	if o.WaitTimeout <= 0 {
+		return fmt.Errorf("Wait timeout must be positive, got %s", o.WaitTimeout)
+	}
+	if len(o.MaxDeletions) > 0 && !maxDeletionsRegex.MatchString(o.MaxDeletions) {
+		return fmt.Errorf("Max deletions '%s' is invalid, use a number (e.g. 5) or a percentage (e.g. 10%%)", o.MaxDeletions)
+	}
+	if o.Parallelism < 1 {
+		return fmt.Errorf("Parallelism must be at least 1, got %d", o.Parallelism)
+	}
+	if o.NamespaceParallelism < 1 {
+		return fmt.Errorf("Namespace parallelism must be at least 1, got %d", o.NamespaceParallelism)
+	}
+	if len(o.Plan) > 0 {
+		if _, err := os.Stat(o.Plan); os.IsNotExist(err) {
+			return fmt.Errorf("Plan '%s' does not exist", o.Plan)
+		}
+	}
 	// Check if template dir exists
 	if o.TemplateDir != "." {
 		td := o.TemplateDir
@@ -540,9 +1041,39 @@ func (o *CompareOptions) check(clusterRequired bool) error {
 		o.Selector = ""
 	}
 
+	if o.targetsMultipleNamespaces() {
+		if o.Command != "diff" && o.Command != "apply" {
+			return errors.New("Multiple namespaces are only supported by diff and apply")
+		}
+		if len(o.Plan) > 0 || len(o.OutPlan) > 0 {
+			return errors.New("Plans cannot be used with multiple namespaces")
+		}
+		if !clusterRequired {
+			// Resolving the namespaces requires the cluster, so "tailor config"
+			// shows them as given.
+			return nil
+		}
+		return o.resolveNamespaces()
+	}
+
 	return o.setNamespace(clusterRequired)
 }
 
+// MaxDeletionsLimit returns the maximum number of resources which may be
+// deleted, given the total number of targeted resources. If no maximum is
+// configured, -1 is returned.
+func (o *CompareOptions) MaxDeletionsLimit(total int) int {
+	if len(o.MaxDeletions) == 0 {
+		return -1
+	}
+	if strings.HasSuffix(o.MaxDeletions, "%") {
+		percentage, _ := strconv.Atoi(strings.TrimSuffix(o.MaxDeletions, "%"))
+		return total * percentage / 100
+	}
+	limit, _ := strconv.Atoi(o.MaxDeletions)
+	return limit
+}
+
 func (o *CompareOptions) PathsToPreserve() []string {
 	pathsToPreserve := []string{}
 	if o.PreserveImmutableFields {
@@ -558,7 +1089,44 @@ func (o *CompareOptions) PathsToPreserve() []string {
 	return append(pathsToPreserve, o.PreservePaths...)
 }
 
+func (o *RenderOptions) check() error {
+	if o.Format != "yaml" && o.Format != "json" {
+		return fmt.Errorf("Format '%s' is not supported, use 'yaml' or 'json'", o.Format)
+	}
+	return o.requireClusterForProcessing()
+}
+
+// requireClusterForProcessing checks the cluster connection for commands which
+// do not need a cluster except to process templates.
+func (o *CompareOptions) requireClusterForProcessing() error {
+	// Without local processing, templates are processed by the cluster.
+	if !o.LocalProcessing && !o.ClusterRequired {
+		o.ClusterRequired = true
+		err := o.GlobalOptions.check(true)
+		if err != nil {
+			return err
+		}
+		return o.setNamespace(true)
+	}
+	return nil
+}
+
+func (o *AdoptOptions) check() error {
+	if len(o.Owner) == 0 {
+		return errors.New("No owner configured, pass --owner or set 'owner' in the Tailorfile")
+	}
+	if strings.Contains(o.Resource, "/") && len(o.Selector) > 0 {
+		DebugMsg("Ignoring selector", o.Selector, "as resource is given")
+		o.Selector = ""
+	}
+
+	return o.setNamespace(o.ClusterRequired)
+}
+
 func (o *ExportOptions) check() error {
+	if o.GroupBy != "app" && o.GroupBy != "kind" {
+		return fmt.Errorf("Grouping by '%s' is not supported, use 'app' or 'kind'", o.GroupBy)
+	}
 	if strings.Contains(o.Resource, "/") && len(o.Selector) > 0 {
 		DebugMsg("Ignoring selector", o.Selector, "as resource is given")
 		o.Selector = ""
@@ -589,11 +1157,72 @@ func (o *NamespaceOptions) setNamespace(clusterRequired bool) error {
 	return nil
 }
 
+// targetsMultipleNamespaces returns true if the namespace is a list or glob,
+// or namespaces are selected by label.
+func (o *NamespaceOptions) targetsMultipleNamespaces() bool {
+	return strings.ContainsAny(o.Namespace, ",*?[") || len(o.NamespaceSelector) > 0
+}
+
+// resolveNamespaces sets Namespaces to the existing namespaces matching the
+// comma-separated names or globs of Namespace and the NamespaceSelector.
+func (o *NamespaceOptions) resolveNamespaces() error {
+	c, err := NewClient("")
+	if err != nil {
+		return err
+	}
+	projects, err := c.ListProjects(o.NamespaceSelector)
+	if err != nil {
+		return err
+	}
+	namespaces, err := matchNamespaces(o.Namespace, projects)
+	if err != nil {
+		return err
+	}
+	if len(namespaces) == 0 {
+		return fmt.Errorf("No namespace matches '%s' (selector '%s')", o.Namespace, o.NamespaceSelector)
+	}
+	DebugMsg("Targeting namespaces", strings.Join(namespaces, ", "))
+	o.Namespaces = namespaces
+	return nil
+}
+
+// matchNamespaces returns the projects matching any of the comma-separated
+// names or globs, sorted by name. An empty pattern matches all projects.
+// Names without glob characters must match an existing project.
+func matchNamespaces(patterns string, projects []string) ([]string, error) {
+	namespaces := []string{}
+	for _, p := range projects {
+		matches := len(strings.TrimSpace(patterns)) == 0
+		for _, pattern := range strings.Split(patterns, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if ok, err := path.Match(pattern, p); err != nil {
+				return nil, fmt.Errorf("Invalid namespace pattern '%s': %s", pattern, err)
+			} else if ok && len(pattern) > 0 {
+				matches = true
+			}
+		}
+		if matches {
+			namespaces = append(namespaces, p)
+		}
+	}
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if len(pattern) > 0 && !strings.ContainsAny(pattern, "*?[") && !utils.Includes(namespaces, pattern) {
+			return nil, fmt.Errorf("No such project: %s", pattern)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
 func (o *NamespaceOptions) checkOcNamespace(n string) error {
 	if utils.Includes(o.CheckedNamespaces, n) {
 		return nil
 	}
-	c := NewOcClient("")
+	c, err := NewClient("")
+	if err != nil {
+		return err
+	}
 	exists, err := c.CheckProjectExists(n)
 	if exists {
 		o.CheckedNamespaces = append(o.CheckedNamespaces, n)
@@ -602,46 +1231,227 @@ func (o *NamespaceOptions) checkOcNamespace(n string) error {
 }
 
 func getOcNamespace() (string, error) {
-	c := NewOcClient("")
+	c, err := NewClient("")
+	if err != nil {
+		return "", err
+	}
 	return c.CurrentProject()
 }
 
-func getFileFlags(filename string, verbose bool) (map[string]string, error) {
+// getFileFlags reads the flags from given Tailorfile. Files ending in .yml or
+// .yaml are read as YAML (see readYAMLTailorfile), other files line by line.
+// If the file does not exist, the file of the same name ending in .yml is
+// read instead (if present).
+//
+// In the line-based format, lines after a "[name]" line belong to the profile
+// "name" and are only used if env selects that profile. Flags of the profile
+// take precedence over the common flags at the top of the file: single values
+// are replaced, while lists (such as param or preserve) are extended, with
+// params of the profile replacing common params of the same name.
+func getFileFlags(filename string, env string, command string, verbose bool) (map[string]string, error) {
+	fileFlags, _, _, err := readFileFlags(filename, env, command, verbose)
+	return fileFlags, err
+}
+
+// readFileFlags reads the flags like getFileFlags. It also returns the lines
+// each flag is set in, and the name of the file which was read (empty if no
+// file was found).
+func readFileFlags(filename string, env string, command string, verbose bool) (map[string]string, fileFlagLines, string, error) {
 	fileFlags := make(map[string]string)
+	fileLines := fileFlagLines{}
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		if filename == "Tailorfile" {
+		if yamlFilename, ok := yamlTailorfile(filename); ok {
+			filename = yamlFilename
+		} else if filename == "Tailorfile" && len(env) == 0 {
 			if verbose {
 				PrintBluef("--> No file '%s' found.\n", filename)
 			}
-			return fileFlags, nil
+			return fileFlags, fileLines, "", nil
+		} else {
+			return fileFlags, fileLines, "", err
 		}
-		return fileFlags, err
 	}
 
 	b, err := os.ReadFile(filename)
 	if err != nil {
-		return fileFlags, err
+		return fileFlags, fileLines, "", err
+	}
+	if isYAMLTailorfile(filename) {
+		fileFlags, err := readYAMLTailorfile(b, env, command)
+		if err != nil {
+			return fileFlags, fileLines, "", err
+		}
+		return fileFlags, yamlFileFlagLines(b, env, command), filename, nil
 	}
 	content := string(b)
 	text := strings.TrimSuffix(content, "\n")
 	lines := strings.Split(text, "\n")
 
-	for _, untrimmedLine := range lines {
+	profiles := map[string]map[string]string{}
+	profileLines := map[string]fileFlagLines{}
+	flags := fileFlags
+	flagLines := fileLines
+	for i, untrimmedLine := range lines {
 		line := strings.TrimSpace(untrimmedLine)
 		if len(line) == 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
 			continue
 		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			profile := strings.TrimSpace(strings.Trim(line, "[]"))
+			if _, ok := profiles[profile]; !ok {
+				profiles[profile] = make(map[string]string)
+				profileLines[profile] = fileFlagLines{}
+			}
+			flags = profiles[profile]
+			flagLines = profileLines[profile]
+			continue
+		}
 		pair := strings.SplitN(line, " ", 2)
 		if len(pair) == 2 {
-			key := pair[0]
-			value := strings.TrimSpace(pair[1])
-			if val, ok := fileFlags[key]; ok {
-				value = val + "," + value
+			key, value := pair[0], strings.TrimSpace(pair[1])
+			if !isKnownFileFlag(key) {
+				warnUnknownFileFlag(filename, key, i+1)
 			}
-			fileFlags[key] = value
+			// Lists may also be given comma-separated on one line.
+			if isListFileFlag(key) && !isHookFileFlag(key) {
+				value = strings.Join(strings.Split(value, ","), "\n")
+			}
+			addFileFlag(flags, key, value)
+			flagLines.add(key, i+1)
 		} else {
-			fileFlags["resource"] = pair[0]
+			flags["resource"] = pair[0]
+			flagLines.add("resource", i+1)
+		}
+	}
+
+	if len(env) == 0 {
+		return fileFlags, fileLines, filename, nil
+	}
+	profile, ok := profiles[env]
+	if !ok {
+		names := []string{}
+		for name := range profiles {
+			names = append(names, name)
+		}
+		return fileFlags, fileLines, "", profileNotDefinedError(env, names)
+	}
+	VerboseMsg("Using profile", env, "of", filename)
+	mergeFileFlags(fileFlags, profile)
+	fileLines.merge(profileLines[env])
+	return fileFlags, fileLines, filename, nil
+}
+
+// yamlTailorfile returns the YAML variant of given Tailorfile (e.g.
+// "Tailorfile.yml" for "Tailorfile"), if it exists.
+func yamlTailorfile(filename string) (string, bool) {
+	for _, ext := range yamlTailorfileExtensions {
+		if _, err := os.Stat(filename + ext); err == nil {
+			return filename + ext, true
+		}
+	}
+	return "", false
+}
+
+// warnedUnknownFileFlags avoids repeating warnings, as the Tailorfile is read
+// once per set of options.
+var warnedUnknownFileFlags = map[string]bool{}
+
+// warnUnknownFileFlag warns about an unknown key of a line-based Tailorfile.
+// Unlike YAML Tailorfiles, which reject unknown keys, line-based ones are
+// still accepted to stay compatible with existing files.
+func warnUnknownFileFlag(filename string, key string, line int) {
+	id := fmt.Sprintf("%s:%d", filename, line)
+	if warnedUnknownFileFlags[id] {
+		return
+	}
+	warnedUnknownFileFlags[id] = true
+	PrintYellowf("Warning: Ignoring unknown key '%s' in %s, line %d.\n", key, filename, line)
+}
+
+// fileFlagLines maps Tailorfile keys to the lines they are set in.
+type fileFlagLines map[string][]int
+
+// add records that key is set in given line. Lines setting a single value
+// replace earlier ones, while lines of lists are collected.
+func (l fileFlagLines) add(key string, line int) {
+	if isListFileFlag(key) {
+		l[key] = append(l[key], line)
+	} else {
+		l[key] = []int{line}
+	}
+}
+
+// merge applies the lines of overrides like mergeFileFlags applies values.
+func (l fileFlagLines) merge(overrides fileFlagLines) {
+	for key, lines := range overrides {
+		for _, line := range lines {
+			l.add(key, line)
+		}
+	}
+}
+
+// profileNotDefinedError returns the error for a profile which is not among
+// the given names.
+func profileNotDefinedError(env string, names []string) error {
+	sort.Strings(names)
+	return fmt.Errorf("Profile '%s' is not defined, available profiles: %s", env, strings.Join(names, ", "))
+}
+
+// mergeFileFlags applies overrides (of a profile or a command section) to
+// fileFlags. Single values are replaced, lists are extended.
+func mergeFileFlags(fileFlags map[string]string, overrides map[string]string) {
+	for key, value := range overrides {
+		if !isListFileFlag(key) {
+			fileFlags[key] = value
+			continue
+		}
+		if key == "param" {
+			fileFlags[key] = withoutOverriddenParams(fileFlags[key], value)
+		}
+		addFileFlag(fileFlags, key, value)
+	}
+}
+
+// addFileFlag sets key to value. Repeated keys of lists are joined by newline
+// (see splitFileFlag), other repeated keys by comma.
+func addFileFlag(flags map[string]string, key string, value string) {
+	if val, ok := flags[key]; ok && len(val) > 0 {
+		separator := ","
+		if isListFileFlag(key) {
+			separator = "\n"
+		}
+		value = val + separator + value
+	}
+	flags[key] = value
+}
+
+// splitFileFlag returns the elements of a list read from the Tailorfile.
+// Elements are separated by newlines so that they may contain commas.
+func splitFileFlag(val string) []string {
+	return strings.Split(val, "\n")
+}
+
+// isListFileFlag returns true if given Tailorfile key may be repeated to
+// specify multiple values.
+func isListFileFlag(key string) bool {
+	return utils.Includes(
+		[]string{"param", "param-file", "preserve", "ignore-path", "exclude", "kind", "trim-annotation"},
+		key,
+	) || isHookFileFlag(key)
+}
+
+// withoutOverriddenParams removes the params from the list common which are
+// set again in overrides.
+func withoutOverriddenParams(common string, overrides string) string {
+	overridden := []string{}
+	for _, param := range splitFileFlag(overrides) {
+		overridden = append(overridden, strings.SplitN(param, "=", 2)[0])
+	}
+	kept := []string{}
+	for _, param := range splitFileFlag(common) {
+		if len(param) > 0 && !utils.Includes(overridden, strings.SplitN(param, "=", 2)[0]) {
+			kept = append(kept, param)
 		}
 	}
-	return fileFlags, nil
+	return strings.Join(kept, "\n")
 }
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"os"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -27,6 +28,12 @@ func TestResolvedFile(t *testing.T) {
 			fs:            &helper.SomeFilesExistFS{Existing: []string{"Tailorfile.foo"}},
 			expected:      "Tailorfile.foo",
 		},
+		"no file flag given but namespace flag given and namespaced YAML file exists": {
+			fileFlag:      "Tailorfile", // default
+			namespaceFlag: "foo",
+			fs:            &helper.SomeFilesExistFS{Existing: []string{"Tailorfile.foo.yaml"}},
+			expected:      "Tailorfile.foo",
+		},
 		"no file flag given but namespace flag given and namespaced file does not exist": {
 			fileFlag:      "Tailorfile", // default
 			namespaceFlag: "foo",
@@ -86,31 +93,11 @@ func TestNewCompareOptionsExcludes(t *testing.T) {
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			o, err := NewGlobalOptions(false, "Tailorfile", false, false, false, "oc", false)
+			o, err := NewGlobalOptions(false, "diff", "Tailorfile", "", false, false, false, "oc", "oc", "", false, []string{}, "", "", false, false)
 			if err != nil {
 				t.Fatal(err)
 			}
-			got, err := NewCompareOptions(
-				o,
-				"",
-				"",
-				tc.excludeFlag,
-				".",
-				".",
-				"",
-				"",
-				"",
-				"",
-				[]string{},
-				[]string{},
-				[]string{},
-				false,
-				false,
-				false,
-				false,
-				false,
-				false,
-				"")
+			got, err := NewCompareOptions(o, &CompareFlags{Excludes: tc.excludeFlag})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -121,6 +108,42 @@ func TestNewCompareOptionsExcludes(t *testing.T) {
 	}
 }
 
+func TestNewCompareOptionsMultipleNamespacesWithoutCluster(t *testing.T) {
+	tests := map[string]struct {
+		command string
+		wantErr string
+	}{
+		"diff": {
+			command: "diff",
+		},
+		"render": {
+			command: "render",
+			wantErr: "Multiple namespaces are only supported by diff and apply",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			o, err := NewGlobalOptions(false, tc.command, "Tailorfile", "", false, false, false, "oc", "oc", "", false, []string{}, "", "", false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewCompareOptions(o, &CompareFlags{Namespace: "foo-*"})
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Namespace != "foo-*" || len(got.Namespaces) > 0 {
+				t.Fatalf("Want unresolved namespace 'foo-*', got: %s %v", got.Namespace, got.Namespaces)
+			}
+		})
+	}
+}
+
 func TestNewExportOptionsExcludes(t *testing.T) {
 	tests := map[string]struct {
 		excludeFlag  []string
@@ -149,7 +172,7 @@ func TestNewExportOptionsExcludes(t *testing.T) {
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			o, err := NewGlobalOptions(false, "Tailorfile", false, false, false, "oc", false)
+			o, err := NewGlobalOptions(false, "diff", "Tailorfile", "", false, false, false, "oc", "oc", "", false, []string{}, "", "", false, false)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -163,6 +186,8 @@ func TestNewExportOptionsExcludes(t *testing.T) {
 				false,
 				false,
 				[]string{},
+				"",
+				"app",
 				"")
 			if err != nil {
 				t.Fatal(err)
@@ -173,3 +198,141 @@ func TestNewExportOptionsExcludes(t *testing.T) {
 		})
 	}
 }
+
+func TestMaxDeletionsLimit(t *testing.T) {
+	tests := map[string]struct {
+		maxDeletions string
+		total        int
+		want         int
+	}{
+		"not configured": {maxDeletions: "", total: 10, want: -1},
+		"absolute":       {maxDeletions: "3", total: 10, want: 3},
+		"zero":           {maxDeletions: "0", total: 10, want: 0},
+		"percentage":     {maxDeletions: "20%", total: 10, want: 2},
+		"rounded down":   {maxDeletions: "25%", total: 10, want: 2},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := &CompareOptions{MaxDeletions: tc.maxDeletions}
+			got := o.MaxDeletionsLimit(tc.total)
+			if got != tc.want {
+				t.Fatalf("Want %d, got: %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGetFileFlagsProfiles(t *testing.T) {
+	content := `namespace foo-dev
+param-dir params
+param FOO=common
+param BAR=common
+preserve bc:/spec/output
+upsert-only true
+
+[test]
+namespace foo-test
+
+[prod]
+namespace foo-prod
+param-dir params/prod
+param FOO=prod
+preserve dc:/spec/replicas
+`
+	tests := map[string]struct {
+		env     string
+		want    map[string]string
+		wantErr string
+	}{
+		"no profile": {
+			env: "",
+			want: map[string]string{
+				"namespace":   "foo-dev",
+				"param-dir":   "params",
+				"param":       "FOO=common\nBAR=common",
+				"preserve":    "bc:/spec/output",
+				"upsert-only": "true",
+			},
+		},
+		"profile overriding single values": {
+			env: "test",
+			want: map[string]string{
+				"namespace":   "foo-test",
+				"param-dir":   "params",
+				"param":       "FOO=common\nBAR=common",
+				"preserve":    "bc:/spec/output",
+				"upsert-only": "true",
+			},
+		},
+		"profile overriding params and extending lists": {
+			env: "prod",
+			want: map[string]string{
+				"namespace":   "foo-prod",
+				"param-dir":   "params/prod",
+				"param":       "BAR=common\nFOO=prod",
+				"preserve":    "bc:/spec/output\ndc:/spec/replicas",
+				"upsert-only": "true",
+			},
+		},
+		"unknown profile": {
+			env:     "qa",
+			wantErr: "Profile 'qa' is not defined, available profiles: prod, test",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			filename := t.TempDir() + "/Tailorfile"
+			err := os.WriteFile(filename, []byte(content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := getFileFlags(filename, tc.env, "", false)
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("File flags mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMatchNamespaces(t *testing.T) {
+	projects := []string{"tenant-b", "tenant-a", "shared", "other"}
+	tests := map[string]struct {
+		patterns string
+		want     []string
+		wantErr  string
+	}{
+		"all":     {patterns: "", want: []string{"other", "shared", "tenant-a", "tenant-b"}},
+		"list":    {patterns: "shared, tenant-a", want: []string{"shared", "tenant-a"}},
+		"glob":    {patterns: "tenant-*", want: []string{"tenant-a", "tenant-b"}},
+		"mixed":   {patterns: "tenant-*,shared", want: []string{"shared", "tenant-a", "tenant-b"}},
+		"no hits": {patterns: "dev-*", want: []string{}},
+		"missing": {patterns: "shared,unknown", wantErr: "No such project: unknown"},
+		"invalid": {patterns: "tenant-[", wantErr: "Invalid namespace pattern 'tenant-[': syntax error in pattern"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := matchNamespaces(tc.patterns, projects)
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Namespaces mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
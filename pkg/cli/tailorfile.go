@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// tailorfile is the structure of a YAML Tailorfile. Settings at the top level
+// apply to all commands, settings below a command (e.g. "apply") only to that
+// command. Profiles have the same structure and are selected via --env.
+type tailorfile struct {
+	tailorfileSection `yaml:",inline"`
+	Profiles          map[string]*tailorfileSection `yaml:"profiles"`
+}
+
+type tailorfileSection struct {
+	tailorfileSettings `yaml:",inline"`
+	Diff               *tailorfileSettings `yaml:"diff"`
+	Apply              *tailorfileSettings `yaml:"apply"`
+	Render             *tailorfileSettings `yaml:"render"`
+	Validate           *tailorfileSettings `yaml:"validate"`
+	Export             *tailorfileSettings `yaml:"export"`
+	Adopt              *tailorfileSettings `yaml:"adopt"`
+	Rollback           *tailorfileSettings `yaml:"rollback"`
+	Secrets            *tailorfileSettings `yaml:"secrets"`
+}
+
+// tailorfileSettings are named like the flags. All fields are pointers (or
+// slices) so that settings which are not given can be told apart.
+type tailorfileSettings struct {
+	Verbose                 *bool    `yaml:"verbose"`
+	Debug                   *bool    `yaml:"debug"`
+	NonInteractive          *bool    `yaml:"non-interactive"`
+	OcBinary                *string  `yaml:"oc-binary"`
+	Backend                 *string  `yaml:"backend"`
+	Kubeconfig              *string  `yaml:"kubeconfig"`
+	LocalProcessing         *bool    `yaml:"local-processing"`
+	Kind                    []string `yaml:"kind"`
+	Owner                   *string  `yaml:"owner"`
+	OwnerKey                *string  `yaml:"owner-key"`
+	OwnerAnnotation         *bool    `yaml:"owner-annotation"`
+	Force                   *bool    `yaml:"force"`
+	Namespace               *string  `yaml:"namespace"`
+	NamespaceSelector       *string  `yaml:"namespace-selector"`
+	Selector                *string  `yaml:"selector"`
+	Exclude                 []string `yaml:"exclude"`
+	TemplateDir             *string  `yaml:"template-dir"`
+	ParamDir                *string  `yaml:"param-dir"`
+	PublicKeyDir            *string  `yaml:"public-key-dir"`
+	PrivateKey              *string  `yaml:"private-key"`
+	Passphrase              *string  `yaml:"passphrase"`
+	Labels                  *string  `yaml:"labels"`
+	Param                   []string `yaml:"param"`
+	ParamFile               []string `yaml:"param-file"`
+	Preserve                []string `yaml:"preserve"`
+	IgnorePath              []string `yaml:"ignore-path"`
+	PreserveImmutableFields *bool    `yaml:"preserve-immutable-fields"`
+	IgnoreUnknownParameters *bool    `yaml:"ignore-unknown-parameters"`
+	UpsertOnly              *bool    `yaml:"upsert-only"`
+	AllowRecreate           *bool    `yaml:"allow-recreate"`
+	AllowProtectedDeletion  *bool    `yaml:"allow-protected-deletion"`
+	RevealSecrets           *bool    `yaml:"reveal-secrets"`
+	RedactSecrets           *bool    `yaml:"redact-secrets"`
+	ServerDryRun            *bool    `yaml:"server-dry-run"`
+	Verify                  *bool    `yaml:"verify"`
+	Wait                    *bool    `yaml:"wait"`
+	ContinueOnError         *bool    `yaml:"continue-on-error"`
+	WaitTimeout             *string  `yaml:"wait-timeout"`
+	BackupDir               *string  `yaml:"backup-dir"`
+	MaxDeletions            *string  `yaml:"max-deletions"`
+	Parallelism             *int     `yaml:"parallelism"`
+	NamespaceParallelism    *int     `yaml:"namespace-parallelism"`
+	Report                  *string  `yaml:"report"`
+	Schema                  *string  `yaml:"schema"`
+	PreApplyHook            []string `yaml:"pre-apply-hook"`
+	PostApplyHook           []string `yaml:"post-apply-hook"`
+	PreResourceHook         []string `yaml:"pre-resource-hook"`
+	PostResourceHook        []string `yaml:"post-resource-hook"`
+	Output                  *string  `yaml:"output"`
+	OutPlan                 *string  `yaml:"out-plan"`
+	Plan                    *string  `yaml:"plan"`
+	Format                  *string  `yaml:"format"`
+	OutputDir               *string  `yaml:"output-dir"`
+	WithAnnotations         *bool    `yaml:"with-annotations"`
+	WithHardcodedNamespace  *bool    `yaml:"with-hardcoded-namespace"`
+	TrimAnnotation          []string `yaml:"trim-annotation"`
+	GroupBy                 *string  `yaml:"group-by"`
+	Resource                *string  `yaml:"resource"`
+}
+
+var (
+	yamlKeyRegex      = regexp.MustCompile(`^(\s*)([\w.-]+)\s*:(\s|$)`)
+	unknownKeyRegex   = regexp.MustCompile(`field (\S+) not found in type \S+`)
+	invalidValueRegex = regexp.MustCompile("cannot unmarshal !!(\\w+)(?: `(.*)`)? into (\\S+)")
+)
+
+// yamlTailorfileExtensions are the extensions of YAML Tailorfiles, in the
+// order they are picked up automatically.
+var yamlTailorfileExtensions = []string{".yml", ".yaml"}
+
+// isYAMLTailorfile returns true if given file is read as YAML Tailorfile.
+func isYAMLTailorfile(filename string) bool {
+	for _, ext := range yamlTailorfileExtensions {
+		if strings.HasSuffix(filename, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKnownFileFlag returns true if given key is a setting of the Tailorfile.
+func isKnownFileFlag(key string) bool {
+	t := reflect.TypeOf(tailorfileSettings{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("yaml") == key {
+			return true
+		}
+	}
+	return false
+}
+
+// readYAMLTailorfile reads the flags from the content of a YAML Tailorfile.
+// The sections of the profile env and of the command are merged into the
+// common settings like profiles of line-based Tailorfiles, in this order:
+// common, common command section, profile, profile command section.
+func readYAMLTailorfile(content []byte, env string, command string) (map[string]string, error) {
+	tf := &tailorfile{}
+	err := yaml.UnmarshalStrict(content, tf)
+	if err != nil {
+		return nil, yamlTailorfileError(err)
+	}
+	fileFlags := tf.fileFlags(command)
+	if len(env) == 0 {
+		return fileFlags, nil
+	}
+	profile, ok := tf.Profiles[env]
+	if !ok || profile == nil {
+		names := []string{}
+		for name := range tf.Profiles {
+			names = append(names, name)
+		}
+		return nil, profileNotDefinedError(env, names)
+	}
+	VerboseMsg("Using profile", env)
+	mergeFileFlags(fileFlags, profile.fileFlags(command))
+	return fileFlags, nil
+}
+
+// yamlFileFlagLines returns the lines the settings returned by
+// readYAMLTailorfile are set in. The lines are found by indentation, which is
+// sufficient for Tailorfiles as they do not use flow or multi-line keys.
+func yamlFileFlagLines(content []byte, env string, command string) fileFlagLines {
+	type key struct {
+		indent int
+		name   string
+	}
+	pathLines := map[string]int{}
+	stack := []key{}
+	for i, line := range strings.Split(string(content), "\n") {
+		m := yamlKeyRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent := len(m[1])
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, key{indent: indent, name: m[2]})
+		names := []string{}
+		for _, k := range stack {
+			names = append(names, k.name)
+		}
+		pathLines[strings.Join(names, "\x00")] = i + 1
+	}
+
+	command = strings.SplitN(command, " ", 2)[0]
+	sections := [][]string{{}, {command}}
+	if len(env) > 0 {
+		sections = append(sections, []string{"profiles", env}, []string{"profiles", env, command})
+	}
+	lines := fileFlagLines{}
+	for _, section := range sections {
+		sectionLines := fileFlagLines{}
+		prefix := strings.Join(append(section, ""), "\x00")
+		for path, line := range pathLines {
+			name := strings.TrimPrefix(path, prefix)
+			if strings.HasPrefix(path, prefix) && !strings.Contains(name, "\x00") {
+				sectionLines.add(name, line)
+			}
+		}
+		lines.merge(sectionLines)
+	}
+	return lines
+}
+
+// fileFlags returns the settings of the section, merged with the settings of
+// the section of given command (e.g. "secrets" for "secrets edit").
+func (s *tailorfileSection) fileFlags(command string) map[string]string {
+	flags := s.tailorfileSettings.fileFlags()
+	var commandSettings *tailorfileSettings
+	switch strings.SplitN(command, " ", 2)[0] {
+	case "diff":
+		commandSettings = s.Diff
+	case "apply":
+		commandSettings = s.Apply
+	case "render":
+		commandSettings = s.Render
+	case "validate":
+		commandSettings = s.Validate
+	case "export":
+		commandSettings = s.Export
+	case "adopt":
+		commandSettings = s.Adopt
+	case "rollback":
+		commandSettings = s.Rollback
+	case "secrets":
+		commandSettings = s.Secrets
+	}
+	if commandSettings != nil {
+		mergeFileFlags(flags, commandSettings.fileFlags())
+	}
+	return flags
+}
+
+// fileFlags returns the given settings in the same form as read from a
+// line-based Tailorfile.
+func (s *tailorfileSettings) fileFlags() map[string]string {
+	flags := map[string]string{}
+	v := reflect.ValueOf(s).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("yaml")
+		switch val := v.Field(i).Interface().(type) {
+		case *string:
+			if val != nil {
+				flags[key] = *val
+			}
+		case *bool:
+			if val != nil {
+				flags[key] = strconv.FormatBool(*val)
+			}
+		case *int:
+			if val != nil {
+				flags[key] = strconv.Itoa(*val)
+			}
+		case []string:
+			if len(val) > 0 {
+				flags[key] = strings.Join(val, "\n")
+			}
+		}
+	}
+	return flags
+}
+
+// yamlTailorfileError lists all unknown keys and invalid values, each with
+// its line number.
+func yamlTailorfileError(err error) error {
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		return err
+	}
+	problems := []string{}
+	for _, e := range typeErr.Errors {
+		e = unknownKeyRegex.ReplaceAllString(e, "unknown key '$1'")
+		if m := invalidValueRegex.FindStringSubmatch(e); m != nil {
+			value := "'" + m[2] + "'"
+			if len(m[2]) == 0 {
+				value = "a " + yamlTypeName(m[1])
+			}
+			e = strings.Replace(e, m[0], fmt.Sprintf("%s is not a valid %s", value, yamlTypeName(m[3])), 1)
+		}
+		problems = append(problems, "* "+e)
+	}
+	return fmt.Errorf("Found %d problem(s):\n%s", len(problems), strings.Join(problems, "\n"))
+}
+
+// yamlTypeName describes a YAML tag (e.g. "seq") or the Go type a YAML value
+// was decoded into.
+func yamlTypeName(typ string) string {
+	switch strings.TrimPrefix(typ, "*") {
+	case "bool":
+		return "boolean"
+	case "int":
+		return "integer"
+	case "str", "string":
+		return "string"
+	case "seq":
+		return "list"
+	case "[]string":
+		return "list of strings"
+	}
+	return "section"
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadYAMLTailorfile(t *testing.T) {
+	content := `namespace: foo-dev
+template-dir: templates
+param:
+  - FOO=common
+  - HOSTS=a.example.com,b.example.com
+upsert-only: true
+max-deletions: 3
+apply:
+  wait: true
+  parallelism: 4
+  preserve:
+    - bc:/spec/output
+profiles:
+  prod:
+    namespace: foo-prod
+    param:
+      - FOO=prod
+    apply:
+      preserve:
+        - dc:/spec/replicas
+`
+	tests := map[string]struct {
+		content string
+		env     string
+		command string
+		want    map[string]string
+		wantErr string
+	}{
+		"common settings": {
+			command: "diff",
+			want: map[string]string{
+				"namespace":     "foo-dev",
+				"template-dir":  "templates",
+				"param":         "FOO=common\nHOSTS=a.example.com,b.example.com",
+				"upsert-only":   "true",
+				"max-deletions": "3",
+			},
+		},
+		"command section": {
+			command: "apply",
+			want: map[string]string{
+				"namespace":     "foo-dev",
+				"template-dir":  "templates",
+				"param":         "FOO=common\nHOSTS=a.example.com,b.example.com",
+				"upsert-only":   "true",
+				"max-deletions": "3",
+				"wait":          "true",
+				"parallelism":   "4",
+				"preserve":      "bc:/spec/output",
+			},
+		},
+		"profile with command section": {
+			env:     "prod",
+			command: "apply",
+			want: map[string]string{
+				"namespace":     "foo-prod",
+				"template-dir":  "templates",
+				"param":         "HOSTS=a.example.com,b.example.com\nFOO=prod",
+				"upsert-only":   "true",
+				"max-deletions": "3",
+				"wait":          "true",
+				"parallelism":   "4",
+				"preserve":      "bc:/spec/output\ndc:/spec/replicas",
+			},
+		},
+		"unknown profile": {
+			env:     "qa",
+			command: "diff",
+			wantErr: "Profile 'qa' is not defined, available profiles: prod",
+		},
+		"unknown keys and wrong types": {
+			content: `namespace: foo
+upsert-only: yes please
+nmespace: bar
+wait-timeout: [5m]
+apply:
+  parallelism: many
+  param: FOO=bar
+profiles:
+  prod:
+    profiles: {}
+`,
+			command: "apply",
+			wantErr: `Found 6 problem(s):
+* line 2: 'yes please' is not a valid boolean
+* line 3: unknown key 'nmespace'
+* line 4: a list is not a valid string
+* line 6: 'many' is not a valid integer
+* line 7: 'FOO=bar' is not a valid list of strings
+* line 10: unknown key 'profiles'`,
+		},
+		"syntax error": {
+			content: "namespace: foo\n  template-dir: bar\n",
+			command: "diff",
+			wantErr: "yaml: line 2: mapping values are not allowed in this context",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := content
+			if len(tc.content) > 0 {
+				c = tc.content
+			}
+			got, err := readYAMLTailorfile([]byte(c), tc.env, tc.command)
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("File flags mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetFileFlagsFallsBackToYAML(t *testing.T) {
+	for _, ext := range []string{".yml", ".yaml"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := t.TempDir()
+			err := os.WriteFile(filepath.Join(dir, "Tailorfile"+ext), []byte("namespace: foo\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := getFileFlags(filepath.Join(dir, "Tailorfile"), "", "diff", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(map[string]string{"namespace": "foo"}, got); diff != "" {
+				t.Fatalf("File flags mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsKnownFileFlag(t *testing.T) {
+	tests := map[string]bool{
+		"namespace":         true,
+		"pre-resource-hook": true,
+		"nmespace":          false,
+	}
+	for key, want := range tests {
+		if got := isKnownFileFlag(key); got != want {
+			t.Fatalf("Want known=%t for '%s', got: %t", want, key, got)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+var (
+	stringParameterRegex    = regexp.MustCompile(`\$\{([a-zA-Z0-9_]+?)\}`)
+	nonStringParameterRegex = regexp.MustCompile(`^\$\{\{([a-zA-Z0-9_]+?)\}\}$`)
+)
+
+// processArgs are the arguments understood by "oc process", as far as they
+// are used by Tailor.
+type processArgs struct {
+	Filename                string
+	Labels                  map[string]string
+	Params                  [][2]string
+	ParamFileParams         [][2]string
+	IgnoreUnknownParameters bool
+}
+
+// parseProcessArgs parses arguments given in the format of "oc process".
+func parseProcessArgs(args []string) (*processArgs, error) {
+	pa := &processArgs{Labels: map[string]string{}}
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--filename="):
+			pa.Filename = strings.TrimPrefix(arg, "--filename=")
+		case strings.HasPrefix(arg, "--labels="):
+			for _, label := range strings.Split(strings.TrimPrefix(arg, "--labels="), ",") {
+				pair := strings.SplitN(label, "=", 2)
+				if len(pair) != 2 {
+					return nil, fmt.Errorf("invalid label %q", label)
+				}
+				pa.Labels[pair[0]] = pair[1]
+			}
+		case strings.HasPrefix(arg, "--param="):
+			pair := strings.SplitN(strings.TrimPrefix(arg, "--param="), "=", 2)
+			if len(pair) != 2 {
+				return nil, fmt.Errorf("invalid parameter assignment in %q", arg)
+			}
+			pa.Params = append(pa.Params, [2]string{pair[0], pair[1]})
+		case strings.HasPrefix(arg, "--param-file="):
+			b, err := os.ReadFile(strings.TrimPrefix(arg, "--param-file="))
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range strings.Split(string(b), "\n") {
+				line = strings.TrimSpace(line)
+				if len(line) == 0 || strings.HasPrefix(line, "#") {
+					continue
+				}
+				pair := strings.SplitN(line, "=", 2)
+				if len(pair) != 2 {
+					return nil, fmt.Errorf("invalid parameter assignment in %q", line)
+				}
+				pa.ParamFileParams = append(pa.ParamFileParams, [2]string{pair[0], unquote(pair[1])})
+			}
+		case arg == "--ignore-unknown-parameters=true":
+			pa.IgnoreUnknownParameters = true
+		}
+	}
+	if len(pa.Filename) == 0 {
+		return nil, errors.New("no template file given")
+	}
+	return pa, nil
+}
+
+// unquote strips matching single or double quotes surrounding value, like
+// "oc process" does for values in param files.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// readTemplate reads the template file given in the arguments.
+func (pa *processArgs) readTemplate() (map[string]interface{}, error) {
+	b, err := os.ReadFile(pa.Filename)
+	if err != nil {
+		return nil, err
+	}
+	var template map[string]interface{}
+	err = yaml.Unmarshal(b, &template)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, fmt.Errorf("template %s is empty", pa.Filename)
+	}
+	return template, nil
+}
+
+// setParameterValues sets the value of the template parameters. Values
+// given via --param take precedence over values from --param-file.
+func (pa *processArgs) setParameterValues(template map[string]interface{}) error {
+	parameters, _ := template["parameters"].([]interface{})
+	for _, pair := range append(pa.ParamFileParams, pa.Params...) {
+		found := false
+		for _, p := range parameters {
+			pm, ok := p.(map[string]interface{})
+			if ok && pm["name"] == pair[0] {
+				pm["value"] = pair[1]
+				// An explicit value disables generation.
+				delete(pm, "generate")
+				found = true
+			}
+		}
+		if !found && !pa.IgnoreUnknownParameters {
+			return fmt.Errorf("unknown parameter name %q", pair[0])
+		}
+	}
+	return nil
+}
+
+// LocalProcessor processes OpenShift templates in-process, without the need
+// for a cluster session. It accepts the same arguments as "oc process".
+type LocalProcessor struct{}
+
+// NewLocalProcessor creates a new LocalProcessor.
+func NewLocalProcessor() *LocalProcessor {
+	return &LocalProcessor{}
+}
+
+// Process processes the template given in args and returns a YAML list of
+// the processed objects.
+func (p *LocalProcessor) Process(args []string) ([]byte, []byte, error) {
+	pa, err := parseProcessArgs(args)
+	if err != nil {
+		return nil, []byte("error: " + err.Error()), err
+	}
+	template, err := pa.readTemplate()
+	if err != nil {
+		return nil, []byte("error: " + err.Error()), err
+	}
+	err = pa.setParameterValues(template)
+	if err != nil {
+		return nil, []byte("error: " + err.Error()), err
+	}
+	objects, err := processTemplate(template, pa.Labels)
+	if err != nil {
+		return nil, []byte("error: " + err.Error()), err
+	}
+	out, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      objects,
+	})
+	if err != nil {
+		return nil, []byte(err.Error()), err
+	}
+	return out, []byte{}, nil
+}
+
+// processTemplate substitutes all parameter references in the objects of
+// the template and adds labels to each object.
+func processTemplate(template map[string]interface{}, labels map[string]string) ([]interface{}, error) {
+	values := map[string]string{}
+	parameters, _ := template["parameters"].([]interface{})
+	for i, p := range parameters {
+		pm, ok := p.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("template.parameters[%d]: invalid parameter", i)
+		}
+		name, _ := pm["name"].(string)
+		if len(name) == 0 {
+			return nil, fmt.Errorf("template.parameters[%d]: parameter without name", i)
+		}
+		value := ""
+		if v, ok := pm["value"]; ok && v != nil {
+			value = fmt.Sprintf("%v", v)
+		}
+		if generate, _ := pm["generate"].(string); len(value) == 0 && len(generate) > 0 {
+			if generate != "expression" {
+				return nil, fmt.Errorf("template.parameters[%d]: unknown generator %q", i, generate)
+			}
+			from, _ := pm["from"].(string)
+			generated, err := generateFromExpression(from)
+			if err != nil {
+				return nil, fmt.Errorf("template.parameters[%d]: %s", i, err)
+			}
+			value = generated
+		}
+		if required, _ := pm["required"].(bool); required && len(value) == 0 {
+			return nil, fmt.Errorf("template.parameters[%d]: parameter %s is required and must be specified", i, name)
+		}
+		values[name] = value
+	}
+
+	templateLabels := map[string]interface{}{}
+	if tl, ok := template["labels"].(map[string]interface{}); ok {
+		for k, v := range tl {
+			templateLabels[k] = substituteString(fmt.Sprintf("%v", v), values)
+		}
+	}
+	for k, v := range labels {
+		templateLabels[k] = v
+	}
+
+	objects, _ := template["objects"].([]interface{})
+	processed := []interface{}{}
+	for _, o := range objects {
+		obj := substituteParameters(o, values)
+		if m, ok := obj.(map[string]interface{}); ok && len(templateLabels) > 0 {
+			metadata, _ := m["metadata"].(map[string]interface{})
+			if metadata == nil {
+				metadata = map[string]interface{}{}
+				m["metadata"] = metadata
+			}
+			objectLabels, _ := metadata["labels"].(map[string]interface{})
+			if objectLabels == nil {
+				objectLabels = map[string]interface{}{}
+				metadata["labels"] = objectLabels
+			}
+			for k, v := range templateLabels {
+				objectLabels[k] = v
+			}
+		}
+		processed = append(processed, obj)
+	}
+	return processed, nil
+}
+
+// substituteParameters replaces parameter references in all keys and string
+// values. A value consisting only of "${{PARAM}}" is replaced by the JSON
+// value of the parameter (e.g. a number or boolean) if it is valid JSON. Like
+// "oc process", "${{PARAM}}" within a longer string is left untouched.
+func substituteParameters(v interface{}, values map[string]string) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range vv {
+			m[substituteString(k, values)] = substituteParameters(val, values)
+		}
+		return m
+	case []interface{}:
+		a := []interface{}{}
+		for _, val := range vv {
+			a = append(a, substituteParameters(val, values))
+		}
+		return a
+	case string:
+		if match := nonStringParameterRegex.FindStringSubmatch(vv); match != nil {
+			if value, ok := values[match[1]]; ok {
+				var f interface{}
+				if err := json.Unmarshal([]byte(value), &f); err == nil {
+					return f
+				}
+				return value
+			}
+		}
+		return substituteString(vv, values)
+	}
+	return v
+}
+
+func substituteString(s string, values map[string]string) string {
+	return stringParameterRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		name := stringParameterRegex.FindStringSubmatch(ref)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// generateFromExpression generates a random value matching the given
+// expression, e.g. "[a-zA-Z0-9]{16}". Supported are literals, character
+// classes with ranges, the shortcuts \w, \d, \a and \A as well as {n}
+// quantifiers.
+func generateFromExpression(expression string) (string, error) {
+	if len(expression) == 0 {
+		return "", errors.New("generate: expression requires 'from'")
+	}
+	var result strings.Builder
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		var charset []rune
+		switch {
+		case runes[i] == '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				return "", fmt.Errorf("malformed character class in %q", expression)
+			}
+			cs, err := expandCharacterClass(runes[i+1 : end])
+			if err != nil {
+				return "", err
+			}
+			charset = cs
+			i = end + 1
+		case runes[i] == '\\' && i+1 < len(runes):
+			cs, ok := shortcutCharset(runes[i+1])
+			if !ok {
+				cs = []rune{runes[i+1]}
+			}
+			charset = cs
+			i += 2
+		default:
+			charset = []rune{runes[i]}
+			i++
+		}
+
+		count := 1
+		if i < len(runes) && runes[i] == '{' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end == len(runes) {
+				return "", fmt.Errorf("malformed quantifier in %q", expression)
+			}
+			n, err := strconv.Atoi(string(runes[i+1 : end]))
+			if err != nil || n < 0 {
+				return "", fmt.Errorf("malformed quantifier in %q", expression)
+			}
+			count = n
+			i = end + 1
+		}
+
+		for j := 0; j < count; j++ {
+			idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+			if err != nil {
+				return "", err
+			}
+			result.WriteRune(charset[idx.Int64()])
+		}
+	}
+	return result.String(), nil
+}
+
+func expandCharacterClass(class []rune) ([]rune, error) {
+	charset := []rune{}
+	for i := 0; i < len(class); i++ {
+		if class[i] == '\\' && i+1 < len(class) {
+			if cs, ok := shortcutCharset(class[i+1]); ok {
+				charset = append(charset, cs...)
+			} else {
+				charset = append(charset, class[i+1])
+			}
+			i++
+			continue
+		}
+		if i+2 < len(class) && class[i+1] == '-' {
+			if class[i] > class[i+2] {
+				return nil, fmt.Errorf("invalid range %c-%c", class[i], class[i+2])
+			}
+			for r := class[i]; r <= class[i+2]; r++ {
+				charset = append(charset, r)
+			}
+			i += 2
+			continue
+		}
+		charset = append(charset, class[i])
+	}
+	if len(charset) == 0 {
+		return nil, errors.New("empty character class")
+	}
+	return charset, nil
+}
+
+// Character sets of the generator shortcuts, as defined by "oc process".
+const (
+	generatorAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	generatorNumerals = "0123456789"
+	generatorSymbols  = "~!@#$%^&*()-_+={}[]\\|<,>.?/\"';:`"
+)
+
+// shortcutCharset returns the characters of a shortcut such as \w, given the
+// rune following the backslash.
+func shortcutCharset(r rune) ([]rune, bool) {
+	switch r {
+	case 'w':
+		return []rune(generatorAlphabet + generatorNumerals + "_"), true
+	case 'd':
+		return []rune(generatorNumerals), true
+	case 'a':
+		return []rune(generatorAlphabet + generatorNumerals), true
+	case 'A':
+		return []rune(generatorSymbols), true
+	}
+	return nil, false
+}
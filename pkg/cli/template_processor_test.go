@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLocalProcessorProcess(t *testing.T) {
+	template := `apiVersion: template.openshift.io/v1
+kind: Template
+labels:
+  template: foo-${NAME}
+parameters:
+- name: NAME
+  required: true
+- name: REPLICAS
+  value: "1"
+- name: ENABLED
+  value: "true"
+- name: UNUSED
+objects:
+- apiVersion: v1
+  kind: DeploymentConfig
+  metadata:
+    name: ${NAME}
+  spec:
+    replicas: ${{REPLICAS}}
+    paused: ${{ENABLED}}
+    test: ${NAME}-${UNKNOWN}
+    embedded: ${NAME}-${{REPLICAS}}
+`
+	tests := map[string]struct {
+		params    []string
+		paramFile string
+		wantOut   string
+		wantErr   string
+	}{
+		"substitutes parameters": {
+			params: []string{"--param=NAME=bar", "--param=REPLICAS=3", "--labels=app=bar"},
+			wantOut: `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: DeploymentConfig
+  metadata:
+    labels:
+      app: bar
+      template: foo-bar
+    name: bar
+  spec:
+    embedded: bar-${{REPLICAS}}
+    paused: true
+    replicas: 3
+    test: bar-${UNKNOWN}
+kind: List
+`,
+		},
+		"param takes precedence over param file": {
+			params:    []string{"--param=NAME=bar"},
+			paramFile: "NAME=baz\nREPLICAS=2\n",
+			wantOut: `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: DeploymentConfig
+  metadata:
+    labels:
+      template: foo-bar
+    name: bar
+  spec:
+    embedded: bar-${{REPLICAS}}
+    paused: true
+    replicas: 2
+    test: bar-${UNKNOWN}
+kind: List
+`,
+		},
+		"param file values are unquoted": {
+			paramFile: "NAME=\"baz\"\nREPLICAS='2'\n",
+			wantOut: `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: DeploymentConfig
+  metadata:
+    labels:
+      template: foo-baz
+    name: baz
+  spec:
+    embedded: baz-${{REPLICAS}}
+    paused: true
+    replicas: 2
+    test: baz-${UNKNOWN}
+kind: List
+`,
+		},
+		"required parameter missing": {
+			params:  []string{},
+			wantErr: "parameter NAME is required and must be specified",
+		},
+		"unknown parameter": {
+			params:  []string{"--param=NAME=bar", "--param=FOO=bar"},
+			wantErr: `unknown parameter name "FOO"`,
+		},
+		"unknown parameter ignored": {
+			params: []string{"--param=NAME=bar", "--param=FOO=bar", "--ignore-unknown-parameters=true"},
+			wantOut: `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: DeploymentConfig
+  metadata:
+    labels:
+      template: foo-bar
+    name: bar
+  spec:
+    embedded: bar-${{REPLICAS}}
+    paused: true
+    replicas: 1
+    test: bar-${UNKNOWN}
+kind: List
+`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			templateFile := dir + "/template.yml"
+			err := os.WriteFile(templateFile, []byte(template), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := []string{"--filename=" + templateFile, "--output=yaml"}
+			args = append(args, tc.params...)
+			if len(tc.paramFile) > 0 {
+				paramFile := dir + "/params.env"
+				err := os.WriteFile(paramFile, []byte(tc.paramFile), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "--param-file="+paramFile)
+			}
+			out, errBytes, err := NewLocalProcessor().Process(args)
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(string(errBytes), tc.wantErr) {
+					t.Fatalf("Want error %q, got: %v (%s)", tc.wantErr, err, errBytes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantOut, string(out)); diff != "" {
+				t.Fatalf("Processed template mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateFromExpression(t *testing.T) {
+	tests := map[string]struct {
+		expression string
+		wantRegex  string
+	}{
+		"character class with quantifier": {
+			expression: "[a-zA-Z0-9]{16}",
+			wantRegex:  "^[a-zA-Z0-9]{16}$",
+		},
+		"shortcuts and literals": {
+			expression: `admin\d{3}\w{2}`,
+			wantRegex:  `^admin[0-9]{3}[a-zA-Z0-9_]{2}$`,
+		},
+		"shortcut in class": {
+			expression: `[\a_]{8}`,
+			wantRegex:  `^[a-zA-Z0-9_]{8}$`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := generateFromExpression(tc.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !regexp.MustCompile(tc.wantRegex).MatchString(got) {
+				t.Fatalf("Want value matching %s, got: %s", tc.wantRegex, got)
+			}
+		})
+	}
+
+	if _, err := generateFromExpression("[a-z"); err == nil {
+		t.Fatal("Want error for malformed expression")
+	}
+}
+
+func TestShortcutCharset(t *testing.T) {
+	tests := map[string]struct {
+		shortcut rune
+		want     string
+	}{
+		"word": {
+			shortcut: 'w',
+			want:     "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz",
+		},
+		"digit": {
+			shortcut: 'd',
+			want:     "0123456789",
+		},
+		"alphanumeric": {
+			shortcut: 'a',
+			want:     "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+		},
+		"symbol": {
+			shortcut: 'A',
+			want:     "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			charset, ok := shortcutCharset(tc.shortcut)
+			if !ok {
+				t.Fatalf("Want \\%c to be a shortcut", tc.shortcut)
+			}
+			sort.Slice(charset, func(i, j int) bool { return charset[i] < charset[j] })
+			if diff := cmp.Diff(tc.want, string(charset)); diff != "" {
+				t.Fatalf("Charset mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, ok := shortcutCharset('x'); ok {
+		t.Fatal("Want \\x not to be a shortcut")
+	}
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// Adopt marks existing resources with the configured owner, so that they are
+// managed (and may be deleted) by Tailor. Resources of other owners are left
+// alone.
+func Adopt(nonInteractive bool, adoptOptions *cli.AdoptOptions, ocClient cli.ClientPatcherExporter, stdin io.Reader) error {
+	err := openshift.RegisterKinds(adoptOptions.Kinds, adoptOptions.DiscoverAPIResources)
+	if err != nil {
+		return err
+	}
+	filter, err := openshift.NewResourceFilter(adoptOptions.Resource, adoptOptions.Selector, adoptOptions.Excludes)
+	if err != nil {
+		return err
+	}
+	exportedOut, err := ocClient.Export(filter.ConvertToKinds(), filter.Label)
+	if err != nil {
+		return fmt.Errorf("Could not export %s resources: %s", filter.String(), err)
+	}
+	platformBasedList, err := openshift.NewPlatformBasedResourceList(filter, exportedOut)
+	if err != nil {
+		return err
+	}
+
+	ownership := openshift.NewOwnership(adoptOptions.GlobalOptions)
+	candidates := []*openshift.ResourceItem{}
+	for _, item := range platformBasedList.Items {
+		owner := ownership.Owner(item)
+		if owner == ownership.Value {
+			cli.VerboseMsg(item.ShortName(), "is already owned by", owner)
+		} else if len(owner) > 0 {
+			fmt.Printf("Skipping %s as it is owned by %s.\n", item.ShortName(), owner)
+		} else {
+			candidates = append(candidates, item)
+		}
+	}
+	if len(candidates) == 0 {
+		fmt.Printf("No resources to adopt in OCP namespace %s.\n", adoptOptions.Namespace)
+		return nil
+	}
+
+	fmt.Printf("Resources to adopt in OCP namespace %s as %s:\n", adoptOptions.Namespace, ownership.Value)
+	for _, item := range candidates {
+		fmt.Printf("* %s\n", item.ShortName())
+	}
+	fmt.Println("")
+
+	if !nonInteractive {
+		a := cli.AskForAction("Adopt resources?", []string{"y=yes", "n=no"}, bufio.NewReader(stdin))
+		if a != "y" {
+			return nil
+		}
+	}
+
+	patch := ownership.MergePatch()
+	for _, item := range candidates {
+		fmt.Printf("Adopting %s ... ", item.ShortName())
+		errBytes, err := ocClient.Patch(item.Kind, item.Name, patch)
+		if err != nil {
+			fmt.Println("failed")
+			return errors.New(string(errBytes))
+		}
+		fmt.Println("done")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+type mockOcAdoptClient struct {
+	patched []string
+}
+
+func (c *mockOcAdoptClient) Export(target string, label string) ([]byte, error) {
+	return []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: owned
+    labels:
+      tailor.opendevstack.org/owner: foo
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foreign
+    labels:
+      tailor.opendevstack.org/owner: bar
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: unowned
+`), nil
+}
+
+func (c *mockOcAdoptClient) Patch(kind string, name string, patch string) ([]byte, error) {
+	c.patched = append(c.patched, kind+"/"+name+" "+patch)
+	return []byte(""), nil
+}
+
+func TestAdopt(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	globalOptions.Owner = "foo"
+	globalOptions.OwnerKey = "tailor.opendevstack.org/owner"
+	adoptOptions := &cli.AdoptOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		Resource:         "cm",
+		Excludes:         []string{},
+	}
+	ocClient := &mockOcAdoptClient{}
+	var stdin bytes.Buffer
+	err := Adopt(true, adoptOptions, ocClient, &stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`ConfigMap/unowned {"metadata":{"labels":{"tailor.opendevstack.org/owner":"foo"}}}`}
+	if diff := cmp.Diff(want, ocClient.patched); diff != "" {
+		t.Fatalf("Patches mismatch (-want +got):\n%s", diff)
+	}
+}
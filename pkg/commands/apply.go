@@ -6,37 +6,48 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/openshift"
 )
 
-type printChange func(w io.Writer, change *openshift.Change, revealSecrets bool)
-type handleChange func(label string, change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error
+type printChange func(w io.Writer, change *openshift.Change, secrets openshift.SecretDisplay)
+type modifyChange func(change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error
 
 // Apply prints the drift between desired and current state to STDOUT.
 // If there is any, it asks for confirmation and applies the changeset.
+// If a plan is given, the changes of the plan are applied instead.
 func Apply(nonInteractive bool, compareOptions *cli.CompareOptions, ocClient cli.ClientApplier, stdin io.Reader) (bool, error) {
 	stdinReader := bufio.NewReader(stdin)
 
+	if len(compareOptions.Plan) > 0 {
+		return applyPlan(nonInteractive, compareOptions, ocClient, stdinReader)
+	}
+
 	var buf bytes.Buffer
 	driftDetected, changeset, err := calculateChangeset(&buf, compareOptions, ocClient)
 	fmt.Print(buf.String())
+	if err == nil {
+		err = validationError(changeset)
+	}
 	if err != nil {
 		return driftDetected, err
 	}
 
 	if driftDetected {
+		err = checkMaxDeletions(compareOptions, changeset, changeset.Targeted())
+		if err != nil {
+			return true, err
+		}
+
 		if nonInteractive {
-			err = apply(compareOptions, changeset, ocClient)
+			err = applyChangeset(compareOptions, changeset, ocClient)
 			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
-			}
-			if compareOptions.Verify {
-				err := performVerification(compareOptions, ocClient)
-				if err != nil {
-					return true, err
-				}
+				return true, err
 			}
 			// As apply has run successfully, there should not be any drift
 			// anymore. Therefore we report no drift here.
@@ -54,41 +65,35 @@ func Apply(nonInteractive bool, compareOptions *cli.CompareOptions, ocClient cli
 		a := cli.AskForAction("Apply all changes?", options, stdinReader)
 		if a == "y" {
 			fmt.Println("")
-			err = apply(compareOptions, changeset, ocClient)
+			err = applyChangeset(compareOptions, changeset, ocClient)
 			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
-			}
-			if compareOptions.Verify {
-				err := performVerification(compareOptions, ocClient)
-				if err != nil {
-					return true, err
-				}
+				return true, err
 			}
 			// As apply has run successfully, there should not be any drift
 			// anymore. Therefore we report no drift here.
 			return false, nil
 		} else if allowSelecting && a == "s" {
-			anyChangeSkipped := false
-
-			anyDeleteChangeSkipped, err := askAndApply(compareOptions, ocClient, stdinReader, changeset.Delete, printDeleteChange, "Deleting", ocDelete)
-			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
-			} else if anyDeleteChangeSkipped {
-				anyChangeSkipped = true
+			// All changes are selected upfront, so that the selected changes
+			// are applied like all changes, e.g. with --continue-on-error.
+			selected := &openshift.Changeset{
+				Create: []*openshift.Change{},
+				Update: []*openshift.Change{},
+				Delete: []*openshift.Change{},
+				Noop:   []*openshift.Change{},
 			}
-			anyCreateChangeSkipped, err := askAndApply(compareOptions, ocClient, stdinReader, changeset.Create, printCreateChange, "Creating", ocApply)
-			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
-			} else if anyCreateChangeSkipped {
-				anyChangeSkipped = true
+			var anyDeleteChangeSkipped, anyCreateChangeSkipped, anyUpdateChangeSkipped bool
+			selected.Delete, anyDeleteChangeSkipped = askForChanges(compareOptions, stdinReader, changeset.Delete, printDeleteChange)
+			selected.Create, anyCreateChangeSkipped = askForChanges(compareOptions, stdinReader, changeset.Create, printCreateChange)
+			selected.Update, anyUpdateChangeSkipped = askForChanges(compareOptions, stdinReader, changeset.Update, printUpdateChange)
+			anyChangeSkipped := anyDeleteChangeSkipped || anyCreateChangeSkipped || anyUpdateChangeSkipped
+			if selected.Blank() {
+				return anyChangeSkipped, nil
 			}
-			anyUpdateChangeSkipped, err := askAndApply(compareOptions, ocClient, stdinReader, changeset.Update, printUpdateChange, "Updating", ocApply)
+			fmt.Println("")
+			err = applyChangeset(compareOptions, selected, ocClient)
 			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
-			} else if anyUpdateChangeSkipped {
-				anyChangeSkipped = true
+				return true, err
 			}
-
 			return anyChangeSkipped, nil
 		}
 
@@ -100,13 +105,95 @@ func Apply(nonInteractive bool, compareOptions *cli.CompareOptions, ocClient cli
 	return false, nil
 }
 
-func askAndApply(compareOptions *cli.CompareOptions, ocClient cli.ClientApplier, stdinReader *bufio.Reader, changes []*openshift.Change, changePrinter printChange, label string, changeHandler handleChange) (bool, error) {
+// applyPlan applies the changes of a previously saved plan. Selecting
+// individual changes is not offered as the plan should be applied as a whole.
+func applyPlan(nonInteractive bool, compareOptions *cli.CompareOptions, ocClient cli.ClientApplier, stdinReader *bufio.Reader) (bool, error) {
+	err := openshift.RegisterKinds(compareOptions.Kinds, compareOptions.DiscoverAPIResources)
+	if err != nil {
+		return false, err
+	}
+	plan, err := openshift.ReadPlan(compareOptions.Plan)
+	if err != nil {
+		return false, err
+	}
+	if plan.Namespace != compareOptions.Namespace {
+		return false, fmt.Errorf(
+			"Plan '%s' was created for OCP namespace %s, not %s",
+			compareOptions.Plan,
+			plan.Namespace,
+			compareOptions.Namespace,
+		)
+	}
+	// The selector is part of the plan as it influences how changes are applied.
+	compareOptions.Selector = plan.Selector
+
+	fmt.Printf(
+		"Applying plan %s to OCP namespace %s.\n",
+		compareOptions.Plan,
+		plan.Namespace,
+	)
+
+	changeset := plan.Changeset
+	if changeset.Blank() {
+		fmt.Println("Plan does not contain any changes.")
+		return false, nil
+	}
+
+	platformBasedList, err := assemblePlatformBasedResourceList(plan.Filter(), compareOptions, ocClient)
+	if err != nil {
+		return true, err
+	}
+	err = plan.Verify(platformBasedList)
+	if err != nil {
+		return true, fmt.Errorf("Plan cannot be applied: %s", err)
+	}
+	fmt.Print("Current state matches the state the plan was created against.\n\n")
+
+	err = checkMaxDeletions(compareOptions, changeset, plan.Targeted)
+	if err != nil {
+		return true, err
+	}
+
+	var buf bytes.Buffer
+	printChangeset(&buf, changeset, secretDisplay(compareOptions))
+	fmt.Print(buf.String())
+
+	if !nonInteractive {
+		a := cli.AskForAction("Apply plan?", []string{"y=yes", "n=no"}, stdinReader)
+		if a != "y" {
+			return true, nil
+		}
+		fmt.Println("")
+	}
+
+	err = apply(compareOptions, changeset, ocClient)
+	if err != nil {
+		return true, applyFailed(compareOptions, err)
+	}
+	err = finishApply(compareOptions, changeset.Changes(), ocClient)
+	if err != nil {
+		return true, err
+	}
+	if compareOptions.Verify {
+		err := performVerification(compareOptions, ocClient)
+		if err != nil {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// askForChanges asks for each of given changes whether it should be applied,
+// and returns the selected ones. The returned bool is true if any change was
+// not selected.
+func askForChanges(compareOptions *cli.CompareOptions, stdinReader *bufio.Reader, changes []*openshift.Change, changePrinter printChange) ([]*openshift.Change, bool) {
 	anyChangeSkipped := false
+	selected := []*openshift.Change{}
 
 	for _, change := range changes {
 		fmt.Println("")
 		var buf bytes.Buffer
-		changePrinter(&buf, change, compareOptions.RevealSecrets)
+		changePrinter(&buf, change, secretDisplay(compareOptions))
 		fmt.Print(buf.String())
 		a := cli.AskForAction(
 			fmt.Sprintf("Apply change to %s?", change.ItemName()),
@@ -114,70 +201,264 @@ func askAndApply(compareOptions *cli.CompareOptions, ocClient cli.ClientApplier,
 			stdinReader,
 		)
 		if a == "y" {
-			fmt.Println("")
-			err := changeHandler(label, change, compareOptions, ocClient)
-			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
-			}
+			selected = append(selected, change)
 		} else {
 			anyChangeSkipped = true
 		}
 	}
-	return anyChangeSkipped, nil
+	return selected, anyChangeSkipped
 }
 
-func apply(compareOptions *cli.CompareOptions, c *openshift.Changeset, ocClient cli.ClientModifier) error {
+func apply(compareOptions *cli.CompareOptions, c *openshift.Changeset, ocClient cli.ClientModifierGetter) error {
+	err := backupChangeset(compareOptions, c)
+	if err != nil {
+		return err
+	}
 
-	for _, change := range c.Delete {
-		err := ocDelete("Deleting", change, compareOptions, ocClient)
+	report := newApplyReport()
+	err = runHooks(os.Stdout, cli.HookPreApply, nil, compareOptions, ocClient)
+	phases := []struct {
+		label    string
+		changes  []*openshift.Change
+		modifier modifyChange
+	}{
+		{"Deleting", c.Delete, deleteChange},
+		{"Creating", c.Create, applyChange},
+		{"Updating", c.Update, applyChange},
+	}
+	for _, phase := range phases {
 		if err != nil {
-			return err
+			report.skip(phase.changes)
+			continue
+		}
+		err = applyChanges(phase.label, phase.changes, phase.modifier, compareOptions, ocClient, report)
+	}
+
+	if compareOptions.ContinueOnError {
+		report.print(os.Stdout)
+	}
+	if len(compareOptions.Report) > 0 {
+		reportErr := report.write(compareOptions.Report)
+		if reportErr != nil {
+			cli.PrintRedf("%s\n", reportErr)
 		}
 	}
+	if err != nil {
+		return err
+	}
+	if report.Summary.Failed > 0 {
+		return fmt.Errorf("%d of %d change(s) failed", report.Summary.Failed, len(report.Results))
+	}
+	return nil
+}
+
+// applyChangeset applies all changes of the changeset, finishes the apply and
+// verifies the result if --verify is given.
+func applyChangeset(compareOptions *cli.CompareOptions, changeset *openshift.Changeset, ocClient cli.ClientApplier) error {
+	err := apply(compareOptions, changeset, ocClient)
+	if err != nil {
+		return applyFailed(compareOptions, err)
+	}
+	err = finishApply(compareOptions, changeset.Changes(), ocClient)
+	if err != nil {
+		return err
+	}
+	if compareOptions.Verify {
+		return performVerification(compareOptions, ocClient)
+	}
+	return nil
+}
 
-	for _, change := range c.Create {
-		err := ocApply("Creating", change, compareOptions, ocClient)
+// finishApply waits for the rollouts of given applied changes if --wait is
+// given, and runs the post-apply hooks afterwards.
+func finishApply(compareOptions *cli.CompareOptions, changes []*openshift.Change, ocClient cli.ClientModifierGetter) error {
+	if compareOptions.Wait {
+		err := waitForRollouts(changes, compareOptions.WaitTimeout, ocClient)
 		if err != nil {
 			return err
 		}
 	}
+	return runHooks(os.Stdout, cli.HookPostApply, nil, compareOptions, ocClient)
+}
 
-	for _, change := range c.Update {
-		err := ocApply("Updating", change, compareOptions, ocClient)
+// applyFailed wraps an error returned by apply. Unless --continue-on-error is
+// given, the apply was aborted at the failing change.
+func applyFailed(compareOptions *cli.CompareOptions, err error) error {
+	if compareOptions.ContinueOnError {
+		return fmt.Errorf("Apply failed: %s", err)
+	}
+	return fmt.Errorf("Apply aborted: %s", err)
+}
+
+// applyChanges executes given changes one after another, aborting on the
+// first failure unless --continue-on-error is given. With --parallelism > 1,
+// changes not depending on each other are executed concurrently instead, see
+// applyConcurrently. The result of each change is recorded in report.
+func applyChanges(label string, changes []*openshift.Change, modifier modifyChange, compareOptions *cli.CompareOptions, ocClient cli.ClientModifierGetter, report *applyReport) error {
+	if compareOptions.Parallelism > 1 {
+		return applyConcurrently(label, changes, modifier, compareOptions, ocClient, report)
+	}
+	for i, change := range changes {
+		if report.blocked(change) {
+			report.add(change, resultSkipped, nil)
+			continue
+		}
+		err := modifyOne(label, change, modifier, compareOptions, ocClient)
 		if err != nil {
+			report.add(change, resultOf(err), err)
+			if compareOptions.ContinueOnError && !isHookError(err) {
+				continue
+			}
+			report.skip(changes[i+1:])
 			return err
 		}
+		report.add(change, resultSucceeded, nil)
+	}
+	return nil
+}
+
+// applyConcurrently executes given changes in batches of changes which do not
+// depend on each other, running up to --parallelism changes at the same time.
+// Results are printed in the order of the changes once a batch is finished.
+// If changes of a batch fail, later batches are not started (unless
+// --continue-on-error is given) and all failures of the batch are reported
+// together. Output of resource hooks is printed along with the result.
+func applyConcurrently(label string, changes []*openshift.Change, modifier modifyChange, compareOptions *cli.CompareOptions, ocClient cli.ClientModifierGetter, report *applyReport) error {
+	batches := openshift.Batches(changes)
+	for b, batch := range batches {
+		errs := make([]error, len(batch))
+		skipped := make([]bool, len(batch))
+		modified := make([]bool, len(batch))
+		preHookOutput := make([]bytes.Buffer, len(batch))
+		postHookOutput := make([]bytes.Buffer, len(batch))
+		sem := make(chan struct{}, compareOptions.Parallelism)
+		var wg sync.WaitGroup
+		for i, change := range batch {
+			if report.blocked(change) {
+				skipped[i] = true
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, change *openshift.Change) {
+				defer wg.Done()
+				errs[i] = runHooks(&preHookOutput[i], cli.HookPreResource, change, compareOptions, ocClient)
+				if errs[i] == nil {
+					modified[i] = true
+					errs[i] = modifier(change, compareOptions, ocClient)
+				}
+				if errs[i] == nil {
+					errs[i] = runHooks(&postHookOutput[i], cli.HookPostResource, change, compareOptions, ocClient)
+				}
+				<-sem
+			}(i, change)
+		}
+		wg.Wait()
+
+		failures := []string{}
+		hookFailed := false
+		for i, change := range batch {
+			if skipped[i] {
+				report.add(change, resultSkipped, nil)
+				continue
+			}
+			fmt.Print(preHookOutput[i].String())
+			if modified[i] {
+				fmt.Printf("%s %s ... ", label, change.ItemName())
+				if errs[i] != nil && !isHookError(errs[i]) {
+					fmt.Println("failed")
+				} else {
+					fmt.Println("done")
+				}
+			}
+			fmt.Print(postHookOutput[i].String())
+			if errs[i] != nil {
+				report.add(change, resultOf(errs[i]), errs[i])
+				failures = append(failures, fmt.Sprintf("* %s: %s", change.ItemName(), strings.TrimSpace(errs[i].Error())))
+				hookFailed = hookFailed || isHookError(errs[i])
+			} else {
+				report.add(change, resultSucceeded, nil)
+			}
+		}
+		if len(failures) > 0 && (!compareOptions.ContinueOnError || hookFailed) {
+			for _, remaining := range batches[b+1:] {
+				report.skip(remaining)
+			}
+			return fmt.Errorf("%d change(s) failed:\n%s", len(failures), strings.Join(failures, "\n"))
+		}
+	}
+	return nil
+}
+
+// checkMaxDeletions returns an error if more resources would be deleted than
+// allowed by --max-deletions, given the total number of targeted resources.
+func checkMaxDeletions(compareOptions *cli.CompareOptions, c *openshift.Changeset, total int) error {
+	limit := compareOptions.MaxDeletionsLimit(total)
+	if limit < 0 {
+		return nil
+	}
+	deletions := c.Deletions()
+	if deletions > limit {
+		return fmt.Errorf(
+			"Apply aborted: %d resource(s) would be deleted, but --max-deletions %s allows at most %d (of %d targeted resources)",
+			deletions,
+			compareOptions.MaxDeletions,
+			limit,
+			total,
+		)
 	}
+	return nil
+}
 
+// backupChangeset saves a snapshot of the current state of all resources
+// touched by given changeset, which can be restored with "tailor rollback".
+func backupChangeset(compareOptions *cli.CompareOptions, c *openshift.Changeset) error {
+	if len(compareOptions.BackupDir) == 0 {
+		return nil
+	}
+	b := openshift.NewBackup(compareOptions.Namespace, compareOptions.Selector, c, time.Now())
+	filename, err := b.Write(compareOptions.BackupDir)
+	if err != nil {
+		return fmt.Errorf("Could not save snapshot: %s", err)
+	}
+	fmt.Printf("Saved snapshot of current state to %s.\n", filename)
 	return nil
 }
 
-func ocDelete(label string, change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error {
+// modifyOne executes a single change, surrounded by the resource hooks
+// targeting it.
+func modifyOne(label string, change *openshift.Change, modifier modifyChange, compareOptions *cli.CompareOptions, ocClient cli.ClientModifierGetter) error {
+	err := runHooks(os.Stdout, cli.HookPreResource, change, compareOptions, ocClient)
+	if err != nil {
+		return err
+	}
 	fmt.Printf("%s %s ... ", label, change.ItemName())
-	errBytes, err := ocClient.Delete(change.Kind, change.Name)
-	if err == nil {
-		fmt.Println("done")
-	} else {
+	err = modifier(change, compareOptions, ocClient)
+	if err != nil {
 		fmt.Println("failed")
+		return err
+	}
+	fmt.Println("done")
+	return runHooks(os.Stdout, cli.HookPostResource, change, compareOptions, ocClient)
+}
+
+func deleteChange(change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error {
+	errBytes, err := ocClient.Delete(change.Kind, change.Name)
+	if err != nil {
 		return errors.New(string(errBytes))
 	}
 	return nil
 }
 
-func ocApply(label string, change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error {
-	fmt.Printf("%s %s ... ", label, change.ItemName())
+func applyChange(change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error {
 	errBytes, err := ocClient.Apply(change.DesiredState, compareOptions.Selector)
-	if err == nil {
-		fmt.Println("done")
-	} else {
-		fmt.Println("failed")
+	if err != nil {
 		return errors.New(string(errBytes))
 	}
-
 	return nil
 }
 
-func performVerification(compareOptions *cli.CompareOptions, ocClient cli.ClientProcessorExporter) error {
+func performVerification(compareOptions *cli.CompareOptions, ocClient cli.ClientComparer) error {
 	var buf bytes.Buffer
 	fmt.Print("\nVerifying current state matches desired state ... ")
 	driftDetected, _, err := calculateChangeset(&buf, compareOptions, ocClient)
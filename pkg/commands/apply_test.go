@@ -2,10 +2,20 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/opendevstack/tailor/internal/test/helper"
 	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
 	"github.com/opendevstack/tailor/pkg/utils"
 )
 
@@ -13,6 +23,8 @@ type mockOcApplyClient struct {
 	t              *testing.T
 	currentFixture string
 	desiredFixture string
+	failApply      bool
+	failDryRun     bool
 }
 
 func (c *mockOcApplyClient) Export(target string, label string) ([]byte, error) {
@@ -24,6 +36,16 @@ func (c *mockOcApplyClient) Process(args []string) ([]byte, []byte, error) {
 }
 
 func (c *mockOcApplyClient) Apply(config string, selector string) ([]byte, error) {
+	if c.failApply {
+		return []byte("cannot apply\n"), errors.New("exit status 1")
+	}
+	return []byte(""), nil
+}
+
+func (c *mockOcApplyClient) DryRunApply(config string, selector string) ([]byte, error) {
+	if c.failDryRun {
+		return []byte("admission webhook denied\n"), errors.New("exit status 1")
+	}
 	return []byte(""), nil
 }
 
@@ -31,6 +53,10 @@ func (c *mockOcApplyClient) Delete(kind string, name string) ([]byte, error) {
 	return []byte(""), nil
 }
 
+func (c *mockOcApplyClient) Get(kind string, name string) ([]byte, error) {
+	return []byte("{}"), nil
+}
+
 func TestApply(t *testing.T) {
 	tests := map[string]struct {
 		namespace      string
@@ -90,3 +116,313 @@ func TestApply(t *testing.T) {
 		})
 	}
 }
+
+func TestApplySelectedContinueOnError(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:       []string{},
+		Parallelism:      1,
+		ContinueOnError:  true,
+		Report:           reportFile,
+	}
+	ocClient := &mockOcApplyClient{
+		currentFixture: "current-list.yml",
+		desiredFixture: "template-dir/desired-list.yml",
+		failApply:      true,
+	}
+	var stdin bytes.Buffer
+	stdin.Write([]byte("s\ny\ny\ny\ny\ny\n"))
+	_, err := Apply(false, compareOptions, ocClient, &stdin)
+	if err == nil || !strings.HasPrefix(err.Error(), "Apply failed") {
+		t.Fatalf("Want apply to fail after attempting all changes, got: %v", err)
+	}
+	b, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatalf("Want report to be written: %s", err)
+	}
+	got := &applyReport{}
+	err = json.Unmarshal(b, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The BuildConfig outputs to the failed ImageStream, so it is skipped.
+	if diff := cmp.Diff(applySummary{Failed: 1, Skipped: 1}, got.Summary); diff != "" {
+		t.Fatalf("Summary mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	tests := map[string]struct {
+		changeResourceVersion bool
+		wantErr               bool
+	}{
+		"unchanged cluster state": {
+			changeResourceVersion: false,
+			wantErr:               false,
+		},
+		"changed cluster state": {
+			changeResourceVersion: true,
+			wantErr:               true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			planFile := t.TempDir() + "/tailor.plan"
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:       []string{},
+				OutPlan:          planFile,
+			}
+			ocClient := &mockOcApplyClient{
+				currentFixture: "current-list.yml",
+				desiredFixture: "template-dir/desired-list.yml",
+			}
+			var out bytes.Buffer
+			drift, err := diff(&out, compareOptions, ocClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !drift {
+				t.Fatal("Want drift to be detected")
+			}
+
+			if tc.changeResourceVersion {
+				plan, err := openshift.ReadPlan(planFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				plan.ResourceVersions["BuildConfig/foo"] = "1"
+				err = plan.Write(planFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			compareOptions.OutPlan = ""
+			compareOptions.Plan = planFile
+			var stdin bytes.Buffer
+			drift, err = Apply(true, compareOptions, ocClient, &stdin)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Want error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if drift {
+				t.Fatal("Want no drift after applying plan")
+			}
+		})
+	}
+}
+
+func TestCheckMaxDeletions(t *testing.T) {
+	changeset := &openshift.Changeset{}
+	changeset.Add(
+		&openshift.Change{Action: "Delete", Kind: "ConfigMap", Name: "foo"},
+		&openshift.Change{Action: "Delete", Kind: "ConfigMap", Name: "bar"},
+		// Recreations do not count as deletions.
+		&openshift.Change{Action: "Delete", Kind: "Route", Name: "foo"},
+		&openshift.Change{Action: "Create", Kind: "Route", Name: "foo"},
+	)
+	tests := map[string]struct {
+		maxDeletions string
+		wantErr      bool
+	}{
+		"no limit":            {maxDeletions: "", wantErr: false},
+		"limit reached":       {maxDeletions: "2", wantErr: false},
+		"limit exceeded":      {maxDeletions: "1", wantErr: true},
+		"percentage reached":  {maxDeletions: "20%", wantErr: false},
+		"percentage exceeded": {maxDeletions: "10%", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compareOptions := &cli.CompareOptions{MaxDeletions: tc.maxDeletions}
+			err := checkMaxDeletions(compareOptions, changeset, 10)
+			if tc.wantErr && err == nil {
+				t.Fatal("Want error, got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("Want no error, got: %s", err)
+			}
+		})
+	}
+}
+
+// mockOcModifyClient records the applied resources and fails for the
+// configured ones.
+type mockOcModifyClient struct {
+	mu          sync.Mutex
+	applied     []string
+	failing     []string
+	failDeletes bool
+	invalid     []string
+}
+
+func (c *mockOcModifyClient) Apply(config string, selector string) ([]byte, error) {
+	name := strings.TrimPrefix(strings.SplitN(config, "\n", 2)[0], "name: ")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applied = append(c.applied, name)
+	if utils.Includes(c.failing, name) {
+		return []byte("cannot apply " + name + "\n"), errors.New("exit status 1")
+	}
+	return []byte(""), nil
+}
+
+func (c *mockOcModifyClient) DryRunApply(config string, selector string) ([]byte, error) {
+	name := strings.TrimPrefix(strings.SplitN(config, "\n", 2)[0], "name: ")
+	if utils.Includes(c.invalid, name) {
+		return []byte("admission webhook denied " + name + "\n"), errors.New("exit status 1")
+	}
+	return []byte(""), nil
+}
+
+func (c *mockOcModifyClient) Get(kind string, name string) ([]byte, error) {
+	return []byte("not found"), errors.New("exit status 1")
+}
+
+func (c *mockOcModifyClient) Delete(kind string, name string) ([]byte, error) {
+	if c.failDeletes {
+		return []byte("cannot delete " + name + "\n"), errors.New("exit status 1")
+	}
+	return []byte(""), nil
+}
+
+func TestApplyChanges(t *testing.T) {
+	tests := map[string]struct {
+		parallelism     int
+		continueOnError bool
+		failing         []string
+		wantApplied     []string
+		wantErr         string
+		wantSummary     applySummary
+	}{
+		"concurrently, all successful": {
+			parallelism: 3,
+			failing:     []string{},
+			wantApplied: []string{"a", "b", "c", "route"},
+			wantSummary: applySummary{Succeeded: 4},
+		},
+		"concurrently, failures are aggregated": {
+			parallelism: 3,
+			failing:     []string{"a", "c"},
+			wantApplied: []string{"a", "b", "c"},
+			wantErr:     "2 change(s) failed:\n* cm/a: cannot apply a\n* svc/c: cannot apply c",
+			wantSummary: applySummary{Succeeded: 1, Failed: 2, Skipped: 1},
+		},
+		"concurrently, continue on error": {
+			parallelism:     3,
+			continueOnError: true,
+			failing:         []string{"a", "c"},
+			wantApplied:     []string{"a", "b", "c"},
+			wantSummary:     applySummary{Succeeded: 1, Failed: 2, Skipped: 1},
+		},
+		"sequentially, abort on first error": {
+			parallelism: 1,
+			failing:     []string{"b"},
+			wantApplied: []string{"a", "b"},
+			wantErr:     "cannot apply b\n",
+			wantSummary: applySummary{Succeeded: 1, Failed: 1, Skipped: 2},
+		},
+		"sequentially, continue on error": {
+			parallelism:     1,
+			continueOnError: true,
+			failing:         []string{"b"},
+			wantApplied:     []string{"a", "b", "c", "route"},
+			wantSummary:     applySummary{Succeeded: 3, Failed: 1},
+		},
+		"sequentially, continue on error skips dependents": {
+			parallelism:     1,
+			continueOnError: true,
+			failing:         []string{"c"},
+			wantApplied:     []string{"a", "b", "c"},
+			wantSummary:     applySummary{Succeeded: 2, Failed: 1, Skipped: 1},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			changes := []*openshift.Change{
+				{Action: "Create", Kind: "ConfigMap", Name: "a", DesiredState: "name: a\n"},
+				{Action: "Create", Kind: "ConfigMap", Name: "b", DesiredState: "name: b\n"},
+				{Action: "Create", Kind: "Service", Name: "c", DesiredState: "name: c\n"},
+				// The route depends on the service, so it is not applied
+				// concurrently with it.
+				{Action: "Create", Kind: "Route", Name: "route", DesiredState: "name: route\nspec: {to: {kind: Service, name: c}}\n"},
+			}
+			ocClient := &mockOcModifyClient{failing: tc.failing}
+			compareOptions := &cli.CompareOptions{
+				Parallelism:     tc.parallelism,
+				ContinueOnError: tc.continueOnError,
+			}
+			report := newApplyReport()
+			err := applyChanges("Creating", changes, applyChange, compareOptions, ocClient, report)
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(ocClient.applied)
+			if diff := cmp.Diff(tc.wantApplied, ocClient.applied); diff != "" {
+				t.Fatalf("Applied resources mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantSummary, report.Summary); diff != "" {
+				t.Fatalf("Summary mismatch (-want +got):\n%s", diff)
+			}
+			if len(report.Results) != len(changes) {
+				t.Fatalf("Want %d results, got %d", len(changes), len(report.Results))
+			}
+		})
+	}
+}
+
+func TestApplySkipsRecreationIfDeleteFailed(t *testing.T) {
+	ocClient := &mockOcModifyClient{failDeletes: true}
+	changeset := &openshift.Changeset{}
+	changeset.Add(
+		&openshift.Change{Action: "Delete", Kind: "Route", Name: "foo"},
+		&openshift.Change{Action: "Create", Kind: "Route", Name: "foo", DesiredState: "name: foo\n"},
+		&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "bar", DesiredState: "name: bar\n"},
+	)
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	compareOptions := &cli.CompareOptions{
+		Parallelism:     1,
+		ContinueOnError: true,
+		Report:          reportFile,
+	}
+	err := apply(compareOptions, changeset, ocClient)
+	if err == nil || err.Error() != "1 of 3 change(s) failed" {
+		t.Fatalf("Want error about failed change, got: %v", err)
+	}
+	b, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &applyReport{}
+	err = json.Unmarshal(b, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &applyReport{
+		Summary: applySummary{Succeeded: 1, Failed: 1, Skipped: 1},
+		Results: []*applyResult{
+			{Action: "Delete", Kind: "Route", Name: "foo", Result: resultFailed, Error: "cannot delete foo"},
+			{Action: "Create", Kind: "ConfigMap", Name: "bar", Result: resultSucceeded},
+			{Action: "Create", Kind: "Route", Name: "foo", Result: resultSkipped},
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(applyReport{})); diff != "" {
+		t.Fatalf("Report mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,253 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// configTable collects the rows of resolved options.
+type configTable struct {
+	configOptions *cli.ConfigOptions
+	rows          [][]string
+}
+
+// add adds a row for the option configured by given flag. If neither a flag
+// nor the Tailorfile sets it, the origin is fallback.
+func (t *configTable) add(flag string, value interface{}, fallback string) {
+	t.rows = append(t.rows, []string{flag, formatConfigValue(value), t.configOptions.Origin(flag, fallback)})
+}
+
+// Config prints the resolved options of a command, together with the origin
+// of each value and the files which would be used.
+func Config(configOptions *cli.ConfigOptions) error {
+	return config(os.Stdout, configOptions)
+}
+
+func config(w io.Writer, configOptions *cli.ConfigOptions) error {
+	t := &configTable{configOptions: configOptions}
+	addGlobalConfig(t, configOptions)
+
+	switch o := configOptions.Options.(type) {
+	case *cli.CompareOptions:
+		addCompareConfig(t, o)
+		printConfigTable(w, configOptions, t)
+		return printTemplateConfig(w, o)
+	case *cli.ExportOptions:
+		addNamespaceConfig(t, o.NamespaceOptions)
+		t.add("selector", o.Selector, "default")
+		t.add("exclude", o.Excludes, "default")
+		t.add("template-dir", o.TemplateDir, "default")
+		t.add("param-dir", o.ParamDir, "default")
+		t.add("with-annotations", o.WithAnnotations, "default")
+		t.add("with-hardcoded-namespace", o.WithHardcodedNamespace, "default")
+		t.add("trim-annotation", o.TrimAnnotations, "default")
+		t.add("output-dir", o.OutputDir, "default")
+		t.add("group-by", o.GroupBy, "default")
+		t.add("resource", o.Resource, "default")
+		printConfigTable(w, configOptions, t)
+		return nil
+	case *cli.SecretsOptions:
+		t.add("param-dir", o.ParamDir, "default")
+		publicKeyDir, origin := o.PublicKeyDir, "default"
+		// Like the secrets commands, prefer the "public-keys" folder.
+		if publicKeyDir == "." {
+			if _, err := os.Stat("public-keys"); err == nil {
+				publicKeyDir, origin = "public-keys", "convention (public-keys folder)"
+			}
+		}
+		t.add("public-key-dir", publicKeyDir, origin)
+		t.add("private-key", o.PrivateKey, "default")
+		t.add("passphrase", maskedPassphrase(o.Passphrase), "default")
+		printConfigTable(w, configOptions, t)
+		return printPublicKeyConfig(w, publicKeyDir)
+	}
+	return fmt.Errorf("Options of type %T cannot be shown", configOptions.Options)
+}
+
+func addGlobalConfig(t *configTable, configOptions *cli.ConfigOptions) {
+	file, origin := configOptions.File, "default"
+	if len(file) == 0 {
+		file, origin = configOptions.GlobalOptions.File+" (not found)", "default"
+	} else if file != configOptions.GlobalOptions.File {
+		origin = "convention (" + file + ")"
+	}
+	t.add("file", file, origin)
+	t.add("env", configOptions.Env, "default")
+	t.add("backend", configOptions.Backend, "default")
+	t.add("oc-binary", configOptions.OcBinary, "default")
+	t.add("kubeconfig", configOptions.Kubeconfig, "default")
+	t.add("local-processing", configOptions.LocalProcessing, "default")
+	t.add("kind", configOptions.Kinds, "default")
+	t.add("owner", configOptions.Owner, "default")
+	t.add("owner-key", configOptions.OwnerKey, "default")
+	t.add("owner-annotation", configOptions.OwnerAnnotation, "default")
+	t.add("non-interactive", configOptions.NonInteractive, "default")
+	t.add("force", configOptions.Force, "default")
+}
+
+func addNamespaceConfig(t *configTable, o *cli.NamespaceOptions) {
+	if len(o.Namespace) == 0 {
+		t.add("namespace", "", "default (current project)")
+		return
+	}
+	t.add("namespace", o.Namespace, "default")
+}
+
+func addCompareConfig(t *configTable, o *cli.CompareOptions) {
+	addNamespaceConfig(t, o.NamespaceOptions)
+	t.add("namespace-selector", o.NamespaceSelector, "default")
+	t.add("selector", o.Selector, "default")
+	t.add("exclude", o.Excludes, "default")
+	t.add("template-dir", o.TemplateDir, "default")
+	paramDir, origin := o.ParamDir, "default"
+	// Like template processing, prefer the <namespace> folder.
+	if paramDir == "." && len(o.Namespace) > 0 {
+		if info, err := os.Stat(o.Namespace); err == nil && info.IsDir() {
+			paramDir, origin = o.Namespace, "convention (<namespace> folder)"
+		}
+	}
+	t.add("param-dir", paramDir, origin)
+	t.add("private-key", o.PrivateKey, "default")
+	t.add("passphrase", maskedPassphrase(o.Passphrase), "default")
+	t.add("labels", o.Labels, "default")
+	t.add("param", o.Params, "default")
+	t.add("param-file", o.ParamFiles, "default")
+	// ignore-path is the deprecated name of preserve.
+	t.add("preserve", o.PreservePaths, t.configOptions.Origin("ignore-path", "default"))
+	t.add("preserve-immutable-fields", o.PreserveImmutableFields, "default")
+	t.add("ignore-unknown-parameters", o.IgnoreUnknownParameters, "default")
+	t.add("upsert-only", o.UpsertOnly, "default")
+	t.add("allow-recreate", o.AllowRecreate, "default")
+	t.add("allow-protected-deletion", o.AllowProtectedDeletion, "default")
+	t.add("reveal-secrets", o.RevealSecrets, "default")
+	t.add("redact-secrets", o.RedactSecrets, "default")
+	t.add("server-dry-run", o.ServerDryRun, "default")
+	t.add("verify", o.Verify, "default")
+	t.add("wait", o.Wait, "default")
+	t.add("continue-on-error", o.ContinueOnError, "default")
+	t.add("wait-timeout", o.WaitTimeout, "default")
+	t.add("backup-dir", o.BackupDir, "default")
+	t.add("max-deletions", o.MaxDeletions, "default")
+	t.add("parallelism", o.Parallelism, "default")
+	t.add("namespace-parallelism", o.NamespaceParallelism, "default")
+	t.add("report", o.Report, "default")
+	t.add("schema", o.Schema, "default")
+	for _, hook := range o.Hooks {
+		run := hook.Run
+		if len(hook.Target) > 0 {
+			run = hook.Target + " " + run
+		}
+		t.add(hook.Event+"-hook", run, "default")
+	}
+	t.add("output", o.Output, "default")
+	t.add("out-plan", o.OutPlan, "default")
+	t.add("plan", o.Plan, "default")
+	t.add("resource", o.Resource, "default")
+}
+
+func printConfigTable(w io.Writer, configOptions *cli.ConfigOptions, t *configTable) {
+	command := configOptions.Command
+	if len(configOptions.Env) > 0 {
+		command = fmt.Sprintf("%s (profile %s)", command, configOptions.Env)
+	}
+	fmt.Fprintf(w, "Resolved options of %s:\n\n", command)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPTION\tVALUE\tORIGIN")
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// printTemplateConfig writes the templates which would be processed, and the
+// param files used for each of them.
+func printTemplateConfig(w io.Writer, o *cli.CompareOptions) error {
+	files, err := templateFiles(o.TemplateDir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nTemplates in '%s':\n\n", o.TemplateDir)
+	if len(files) == 0 {
+		fmt.Fprintln(w, "No templates found.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEMPLATE\tPARAM FILE\tORIGIN")
+	for _, file := range files {
+		paramFiles := openshift.ParamFilesFor(file, o.ParamDir, o)
+		if len(paramFiles) == 0 {
+			fmt.Fprintf(tw, "%s\t-\t-\n", file)
+			continue
+		}
+		template := file
+		for _, p := range paramFiles {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", template, p.File, p.Origin)
+			if _, err := os.Stat(p.File + ".enc"); err == nil {
+				fmt.Fprintf(tw, "\t%s\t%s\n", p.File+".enc", "encrypted params of "+p.File)
+			}
+			template = ""
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+// printPublicKeyConfig writes the public keys secrets would be encrypted
+// for.
+func printPublicKeyConfig(w io.Writer, publicKeyDir string) error {
+	files, err := os.ReadDir(publicKeyDir)
+	if err != nil {
+		return fmt.Errorf("Cannot get files in public key directory '%s': %s", publicKeyDir, err)
+	}
+	fmt.Fprintf(w, "\nPublic keys in '%s':\n\n", publicKeyDir)
+	found := false
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".key") || strings.HasSuffix(file.Name(), "private.key") {
+			continue
+		}
+		fmt.Fprintln(w, filepath.Join(publicKeyDir, file.Name()))
+		found = true
+	}
+	if !found {
+		fmt.Fprintln(w, "No public keys found.")
+	}
+	return nil
+}
+
+func maskedPassphrase(passphrase string) string {
+	if len(passphrase) == 0 {
+		return ""
+	}
+	return "********"
+}
+
+func formatConfigValue(value interface{}) string {
+	s := ""
+	switch v := value.(type) {
+	case string:
+		s = v
+	case []string:
+		s = strings.Join(v, ", ")
+	case bool:
+		s = strconv.FormatBool(v)
+	case int:
+		s = strconv.Itoa(v)
+	case time.Duration:
+		s = v.String()
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+	if len(s) == 0 {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestConfig(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	globalOptions.Command = "apply"
+	globalOptions.File = "Tailorfile"
+	globalOptions.Backend = "oc"
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+		ParamDir:         ".",
+		Params:           []string{"FOO=bar"},
+		Passphrase:       "s3cr3t-phrase",
+		Parallelism:      4,
+		Hooks:            []*cli.Hook{{Event: cli.HookPreResource, Target: "dc:foo", Run: "./migrate.sh"}},
+	}
+	configOptions := &cli.ConfigOptions{
+		GlobalOptions: globalOptions,
+		Options:       compareOptions,
+		File:          "Tailorfile.foo",
+		FlagOrigins:   map[string]string{"namespace": "flag --namespace"},
+		FileOrigins: map[string]string{
+			"param":             "Tailorfile.foo:3",
+			"parallelism":       "Tailorfile.foo:4",
+			"pre-resource-hook": "Tailorfile.foo:5",
+		},
+	}
+	var buf bytes.Buffer
+	err := config(&buf, configOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Columns are aligned depending on the longest value.
+	lines := []string{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	got := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"Resolved options of apply:",
+		"file Tailorfile.foo convention (Tailorfile.foo)",
+		"namespace foo flag --namespace",
+		"param FOO=bar Tailorfile.foo:3",
+		"passphrase ******** default",
+		"parallelism 4 Tailorfile.foo:4",
+		"pre-resource-hook dc:foo ./migrate.sh Tailorfile.foo:5",
+		"upsert-only false default",
+		"TEMPLATE PARAM FILE ORIGIN",
+		"desired-list.yml - -",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("Want output to contain '%s', got:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "s3cr3t-phrase") {
+		t.Fatalf("Want passphrase to be masked, got:\n%s", buf.String())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"strings"
 
 	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/openshift"
@@ -14,15 +15,51 @@ import (
 )
 
 // Diff prints the drift between desired and current state to STDOUT.
+// Depending on the output option, the drift is either human-oriented text
+// or JSON.
 func Diff(compareOptions *cli.CompareOptions) (bool, error) {
-	ocClient := cli.NewOcClient(compareOptions.Namespace)
+	ocClient, err := cli.NewClient(compareOptions.Namespace)
+	if err != nil {
+		return false, err
+	}
+	return diff(os.Stdout, compareOptions, ocClient)
+}
+
+func diff(w io.Writer, compareOptions *cli.CompareOptions, ocClient cli.ClientComparer) (bool, error) {
 	var buf bytes.Buffer
-	driftDetected, _, err := calculateChangeset(&buf, compareOptions, ocClient)
-	fmt.Print(buf.String())
-	return driftDetected, err
+	driftDetected, changeset, err := calculateChangeset(&buf, compareOptions, ocClient)
+	// A plan of invalid changes would fail on apply, so it is not written.
+	if err == nil && len(compareOptions.OutPlan) > 0 && validationError(changeset) == nil {
+		plan := openshift.NewPlan(compareOptions.Namespace, compareOptions.Selector, changeset)
+		err = plan.Write(compareOptions.OutPlan)
+		if err != nil {
+			return driftDetected, fmt.Errorf("Could not write plan: %s", err)
+		}
+		if compareOptions.Output != "json" {
+			fmt.Fprintf(&buf, "Plan written to %s.\n", compareOptions.OutPlan)
+		}
+	}
+	if compareOptions.Output != "json" {
+		fmt.Fprint(w, buf.String())
+		if err == nil {
+			err = validationError(changeset)
+		}
+		return driftDetected, err
+	}
+	if err != nil {
+		// Surface the explanation on STDERR to keep STDOUT machine-readable.
+		fmt.Fprint(os.Stderr, buf.String())
+		return driftDetected, err
+	}
+	b, err := changeset.JSON(secretDisplay(compareOptions))
+	if err != nil {
+		return driftDetected, fmt.Errorf("Could not serialize changeset: %s", err)
+	}
+	fmt.Fprintln(w, string(b))
+	return driftDetected, validationError(changeset)
 }
 
-func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClient cli.ClientProcessorExporter) (bool, *openshift.Changeset, error) {
+func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClient cli.ClientComparer) (bool, *openshift.Changeset, error) {
 	updateRequired := false
 
 	where := compareOptions.TemplateDir
@@ -53,6 +90,11 @@ func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClien
 
 	resource := compareOptions.Resource
 
+	err := openshift.RegisterKinds(compareOptions.Kinds, compareOptions.DiscoverAPIResources)
+	if err != nil {
+		return updateRequired, &openshift.Changeset{}, err
+	}
+
 	filter, err := openshift.NewResourceFilter(resource, compareOptions.Selector, compareOptions.Excludes)
 	if err != nil {
 		return updateRequired, &openshift.Changeset{}, err
@@ -132,42 +174,94 @@ func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClien
 		return updateRequired, &openshift.Changeset{}, errors.New("Diff not performed due to misconfiguration")
 	}
 
-	changeset, err := compare(
-		w,
+	changeset, err := openshift.NewChangeset(
 		platformBasedList,
 		templateBasedList,
 		compareOptions.UpsertOnly,
 		compareOptions.AllowRecreate,
-		compareOptions.RevealSecrets,
 		compareOptions.PathsToPreserve(),
+		openshift.NewOwnership(compareOptions.GlobalOptions),
+		compareOptions.AllowProtectedDeletion,
 	)
 	if err != nil {
 		return false, changeset, err
 	}
+	if compareOptions.ServerDryRun {
+		validateChanges(changeset, compareOptions.Selector, ocClient)
+	}
+	printChangeset(w, changeset, secretDisplay(compareOptions))
 	updateRequired = !changeset.Blank()
 	return updateRequired, changeset, nil
 }
 
-func compare(w io.Writer, remoteResourceList *openshift.ResourceList, localResourceList *openshift.ResourceList, upsertOnly bool, allowRecreate bool, revealSecrets bool, preservePaths []string) (*openshift.Changeset, error) {
-	changeset, err := openshift.NewChangeset(remoteResourceList, localResourceList, upsertOnly, allowRecreate, preservePaths)
-	if err != nil {
-		return changeset, err
+// validateChanges sends all changes creating or updating resources through a
+// server-side dry-run apply, and attaches the errors to the changes. Creations
+// of recreated resources are skipped as the resource still exists.
+func validateChanges(changeset *openshift.Changeset, selector string, ocClient cli.OcClientDryRunner) {
+	deleted := map[string]bool{}
+	for _, change := range changeset.Delete {
+		deleted[change.ItemName()] = true
+	}
+	changes := []*openshift.Change{}
+	changes = append(changes, changeset.Create...)
+	changes = append(changes, changeset.Update...)
+	for _, change := range changes {
+		if deleted[change.ItemName()] {
+			cli.DebugMsg("Skipping server-side dry-run of recreated", change.ItemName())
+			continue
+		}
+		cli.DebugMsg("Server-side dry-run of", change.ItemName())
+		errBytes, err := ocClient.DryRunApply(change.DesiredState, selector)
+		if err != nil {
+			change.ValidationError = strings.TrimSpace(string(errBytes))
+			if len(change.ValidationError) == 0 {
+				change.ValidationError = err.Error()
+			}
+		}
+	}
+}
+
+// validationError returns an error if any change failed server-side
+// validation.
+func validationError(changeset *openshift.Changeset) error {
+	invalid := changeset.Invalid()
+	if len(invalid) == 0 {
+		return nil
+	}
+	names := []string{}
+	for _, change := range invalid {
+		names = append(names, change.ItemName())
 	}
+	return fmt.Errorf("Server-side dry-run failed for %s", strings.Join(names, ", "))
+}
+
+// secretDisplay returns how the drift of Secret resources is shown. Revealing
+// takes precedence over redacting.
+func secretDisplay(compareOptions *cli.CompareOptions) openshift.SecretDisplay {
+	if compareOptions.RevealSecrets {
+		return openshift.SecretsRevealed
+	}
+	if compareOptions.RedactSecrets {
+		return openshift.SecretsRedacted
+	}
+	return openshift.SecretsHidden
+}
 
+func printChangeset(w io.Writer, changeset *openshift.Changeset, secrets openshift.SecretDisplay) {
 	for _, change := range changeset.Noop {
 		fmt.Fprintf(w, "* %s is in sync\n", change.ItemName())
 	}
 
 	for _, change := range changeset.Delete {
-		printDeleteChange(w, change, revealSecrets)
+		printDeleteChange(w, change, secrets)
 	}
 
 	for _, change := range changeset.Create {
-		printCreateChange(w, change, revealSecrets)
+		printCreateChange(w, change, secrets)
 	}
 
 	for _, change := range changeset.Update {
-		printUpdateChange(w, change, revealSecrets)
+		printUpdateChange(w, change, secrets)
 	}
 
 	fmt.Fprintf(w, "\nSummary: %d in sync, ", len(changeset.Noop))
@@ -176,54 +270,110 @@ func compare(w io.Writer, remoteResourceList *openshift.ResourceList, localResou
 	cli.FprintYellowf(w, "%d to update", len(changeset.Update))
 	fmt.Fprint(w, ", ")
 	cli.FprintRedf(w, "%d to delete\n\n", len(changeset.Delete))
-
-	return changeset, nil
+	if invalid := len(changeset.Invalid()); invalid > 0 {
+		cli.FprintRedf(w, "%d change(s) failed server-side validation.\n\n", invalid)
+	}
 }
 
-func printDeleteChange(w io.Writer, change *openshift.Change, revealSecrets bool) {
+func printDeleteChange(w io.Writer, change *openshift.Change, secrets openshift.SecretDisplay) {
 	cli.FprintRedf(w, "- %s to delete\n", change.ItemName())
-	fmt.Fprint(w, change.Diff(revealSecrets))
+	fmt.Fprint(w, change.Diff(secrets))
 }
 
-func printCreateChange(w io.Writer, change *openshift.Change, revealSecrets bool) {
+func printCreateChange(w io.Writer, change *openshift.Change, secrets openshift.SecretDisplay) {
 	cli.FprintGreenf(w, "+ %s to create\n", change.ItemName())
-	fmt.Fprint(w, change.Diff(revealSecrets))
+	fmt.Fprint(w, change.Diff(secrets))
+	printValidationError(w, change)
 }
 
-func printUpdateChange(w io.Writer, change *openshift.Change, revealSecrets bool) {
+func printUpdateChange(w io.Writer, change *openshift.Change, secrets openshift.SecretDisplay) {
 	cli.FprintYellowf(w, "~ %s to update\n", change.ItemName())
-	fmt.Fprint(w, change.Diff(revealSecrets))
+	fmt.Fprint(w, change.Diff(secrets))
+	printValidationError(w, change)
 }
 
-func assembleTemplateBasedResourceList(filter *openshift.ResourceFilter, compareOptions *cli.CompareOptions, ocClient cli.OcClientProcessor) (*openshift.ResourceList, error) {
-	var inputs [][]byte
+func printValidationError(w io.Writer, change *openshift.Change) {
+	if len(change.ValidationError) > 0 {
+		cli.FprintRedf(w, "! %s failed server-side validation: %s\n", change.ItemName(), change.ValidationError)
+	}
+}
 
-	files, err := os.ReadDir(compareOptions.TemplateDir)
+// templateFiles returns the names of the templates in given directory.
+func templateFiles(templateDir string) ([]string, error) {
+	files, err := os.ReadDir(templateDir)
 	if err != nil {
-		return nil, fmt.Errorf("Cannot get files in template directory '%s': %s", compareOptions.TemplateDir, err)
+		return nil, fmt.Errorf("Cannot get files in template directory '%s': %s", templateDir, err)
 	}
 	filePattern := ".*\\.ya?ml$"
 	re := regexp.MustCompile(filePattern)
+	names := []string{}
 	for _, file := range files {
-		matched := re.MatchString(file.Name())
-		if !matched {
-			continue
+		if re.MatchString(file.Name()) {
+			names = append(names, file.Name())
+		}
+	}
+	return names, nil
+}
+
+func assembleTemplateBasedResourceList(filter *openshift.ResourceFilter, compareOptions *cli.CompareOptions, ocClient cli.OcClientProcessor) (*openshift.ResourceList, error) {
+	list := &openshift.ResourceList{Filter: filter}
+
+	var schema *openshift.Schema
+	violations := []*openshift.SchemaViolation{}
+	if len(compareOptions.Schema) > 0 {
+		s, err := openshift.LoadSchema(compareOptions.Schema)
+		if err != nil {
+			return nil, err
 		}
-		cli.DebugMsg("Reading template", file.Name())
+		schema = s
+	}
+
+	files, err := templateFiles(compareOptions.TemplateDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		cli.DebugMsg("Reading template", file)
 		processedOut, err := openshift.ProcessTemplate(
 			compareOptions.TemplateDir,
-			file.Name(),
+			file,
 			compareOptions.ParamDir,
 			compareOptions,
 			ocClient,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("Could not process %s template: %s", file.Name(), err)
+			return nil, fmt.Errorf("Could not process %s template: %s", file, err)
+		}
+		templateList, err := openshift.NewTemplateBasedResourceList(filter, processedOut)
+		if err != nil {
+			return nil, err
+		}
+		if schema != nil {
+			violations = append(violations, schema.ValidateTemplate(file, templateList)...)
 		}
-		inputs = append(inputs, processedOut)
+		list.Items = append(list.Items, templateList.Items...)
+	}
+	if len(violations) > 0 {
+		return nil, schemaViolationsError(violations)
+	}
+
+	if ownership := openshift.NewOwnership(compareOptions.GlobalOptions); ownership != nil {
+		err = ownership.Stamp(list)
 	}
+	return list, err
+}
 
-	return openshift.NewTemplateBasedResourceList(filter, inputs...)
+// schemaViolationsError lists all schema violations in one error.
+func schemaViolationsError(violations []*openshift.SchemaViolation) error {
+	lines := []string{}
+	for _, v := range violations {
+		lines = append(lines, "* "+v.String())
+	}
+	return fmt.Errorf(
+		"Found %d schema violation(s):\n%s",
+		len(violations),
+		strings.Join(lines, "\n"),
+	)
 }
 
 func assemblePlatformBasedResourceList(filter *openshift.ResourceFilter, compareOptions *cli.CompareOptions, ocClient cli.OcClientExporter) (*openshift.ResourceList, error) {
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestValidateChanges(t *testing.T) {
+	tests := map[string]struct {
+		invalid       []string
+		wantValidated []string
+		wantErrors    map[string]string
+		wantErr       string
+	}{
+		"all valid": {
+			invalid:    []string{},
+			wantErrors: map[string]string{},
+		},
+		"invalid create and update": {
+			invalid: []string{"a", "c"},
+			wantErrors: map[string]string{
+				"a": "admission webhook denied a",
+				"c": "admission webhook denied c",
+			},
+			wantErr: "Server-side dry-run failed for cm/a, cm/c",
+		},
+		"recreated resource is skipped": {
+			invalid:    []string{"d"},
+			wantErrors: map[string]string{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			changeset := &openshift.Changeset{}
+			changeset.Add(
+				&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "a", DesiredState: "name: a\n"},
+				&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "d", DesiredState: "name: d\n"},
+				&openshift.Change{Action: "Update", Kind: "ConfigMap", Name: "b", CurrentState: "name: b\n", DesiredState: "name: b\ndata: {}\n"},
+				&openshift.Change{Action: "Update", Kind: "ConfigMap", Name: "c", CurrentState: "name: c\n", DesiredState: "name: c\ndata: {}\n"},
+				&openshift.Change{Action: "Delete", Kind: "ConfigMap", Name: "d", CurrentState: "name: d\n"},
+			)
+			validateChanges(changeset, "", &mockOcModifyClient{invalid: tc.invalid})
+			gotErrors := map[string]string{}
+			for _, change := range changeset.Changes() {
+				if len(change.ValidationError) > 0 {
+					gotErrors[change.Name] = change.ValidationError
+				}
+			}
+			if diff := cmp.Diff(tc.wantErrors, gotErrors); diff != "" {
+				t.Fatalf("Validation errors mismatch (-want +got):\n%s", diff)
+			}
+
+			err := validationError(changeset)
+			if len(tc.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+			}
+
+			var buf bytes.Buffer
+			printChangeset(&buf, changeset, openshift.SecretsHidden)
+			for changeName, msg := range tc.wantErrors {
+				want := "cm/" + changeName + " failed server-side validation: " + msg
+				if !strings.Contains(buf.String(), want) {
+					t.Fatalf("Want output to contain '%s', got:\n%s", want, buf.String())
+				}
+			}
+
+			b, err := changeset.JSON(openshift.SecretsHidden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), `"validationError": "admission webhook denied a"`) {
+				t.Fatalf("Want JSON to contain validation error, got:\n%s", string(b))
+			}
+		})
+	}
+}
+
+func TestDiffOutPlan(t *testing.T) {
+	tests := map[string]struct {
+		failDryRun bool
+		wantPlan   bool
+	}{
+		"valid changes": {
+			failDryRun: false,
+			wantPlan:   true,
+		},
+		"invalid changes": {
+			failDryRun: true,
+			wantPlan:   false,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			planFile := filepath.Join(t.TempDir(), "tailor.plan")
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    cli.InitGlobalOptions(&utils.OsFS{}),
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:       []string{},
+				ServerDryRun:     true,
+				OutPlan:          planFile,
+			}
+			ocClient := &mockOcApplyClient{
+				currentFixture: "current-list.yml",
+				desiredFixture: "template-dir/desired-list.yml",
+				failDryRun:     tc.failDryRun,
+			}
+			var buf bytes.Buffer
+			_, err := diff(&buf, compareOptions, ocClient)
+			if tc.failDryRun != (err != nil) {
+				t.Fatalf("Want validation error: %t, got: %v", tc.failDryRun, err)
+			}
+			_, err = os.Stat(planFile)
+			if tc.wantPlan != (err == nil) {
+				t.Fatalf("Want plan written: %t, got: %v", tc.wantPlan, err)
+			}
+		})
+	}
+}
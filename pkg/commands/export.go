@@ -2,26 +2,63 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/openshift"
 )
 
-// Export prints an export of targeted resources to STDOUT.
+// Export prints an export of targeted resources to STDOUT, or writes one
+// template per group into the output directory.
 func Export(exportOptions *cli.ExportOptions) error {
+	c, err := cli.NewClient(exportOptions.Namespace)
+	if err != nil {
+		return err
+	}
+	return export(os.Stdout, exportOptions, c)
+}
+
+func export(w io.Writer, exportOptions *cli.ExportOptions, ocClient cli.OcClientExporter) error {
+	err := openshift.RegisterKinds(exportOptions.Kinds, exportOptions.DiscoverAPIResources)
+	if err != nil {
+		return err
+	}
+
 	filter, err := openshift.NewResourceFilter(exportOptions.Resource, exportOptions.Selector, exportOptions.Excludes)
 	if err != nil {
 		return err
 	}
 
-	c := cli.NewOcClient(exportOptions.Namespace)
+	if len(exportOptions.OutputDir) > 0 {
+		templates, err := openshift.ExportAsTemplateFiles(
+			filter,
+			exportOptions.WithAnnotations,
+			exportOptions.Namespace,
+			exportOptions.WithHardcodedNamespace,
+			exportOptions.TrimAnnotations,
+			exportOptions.GroupBy,
+			ocClient,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"Could not export %s resources as templates: %s",
+				filter.String(),
+				err,
+			)
+		}
+		return exportToDir(w, exportOptions, templates)
+	}
+
 	out, err := openshift.ExportAsTemplateFile(
 		filter,
 		exportOptions.WithAnnotations,
 		exportOptions.Namespace,
 		exportOptions.WithHardcodedNamespace,
 		exportOptions.TrimAnnotations,
-		c,
+		ocClient,
 	)
 	if err != nil {
 		return fmt.Errorf(
@@ -31,6 +68,69 @@ func Export(exportOptions *cli.ExportOptions) error {
 		)
 	}
 
-	fmt.Println(out)
+	fmt.Fprintln(w, out)
 	return nil
 }
+
+// exportToDir writes each template into <group>.yml in the output directory,
+// together with a param file skeleton <group>.env. The skeletons are placed
+// into the param directory if one is given, and existing param files are
+// never overwritten.
+func exportToDir(w io.Writer, exportOptions *cli.ExportOptions, templates map[string]string) error {
+	paramDir := exportOptions.ParamDir
+	if paramDir == "." {
+		paramDir = exportOptions.OutputDir
+	}
+	for _, dir := range []string{exportOptions.OutputDir, paramDir} {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return fmt.Errorf("Could not create directory '%s': %s", dir, err)
+		}
+	}
+
+	groups := []string{}
+	for group := range templates {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		filename := filepath.Join(exportOptions.OutputDir, group+".yml")
+		err := os.WriteFile(filename, []byte(templates[group]), 0644)
+		if err != nil {
+			return fmt.Errorf("Could not write '%s': %s", filename, err)
+		}
+		cli.VerboseMsg("Wrote", filename)
+
+		paramFilename := filepath.Join(paramDir, group+".env")
+		if _, err := os.Stat(paramFilename); err == nil {
+			cli.VerboseMsg("Kept existing", paramFilename)
+			continue
+		}
+		err = os.WriteFile(paramFilename, []byte(paramSkeleton(group, exportOptions.WithHardcodedNamespace)), 0644)
+		if err != nil {
+			return fmt.Errorf("Could not write '%s': %s", paramFilename, err)
+		}
+		cli.VerboseMsg("Wrote", paramFilename)
+	}
+
+	fmt.Fprintf(
+		w,
+		"Exported %d template(s) into '%s', with param files in '%s'.\n",
+		len(groups),
+		exportOptions.OutputDir,
+		paramDir,
+	)
+	return nil
+}
+
+// paramSkeleton returns the content of a param file for the template of
+// given group. Exported templates do not define any parameters other than
+// TAILOR_NAMESPACE, so the skeleton only explains how to add some.
+func paramSkeleton(group string, withHardcodedNamespace bool) string {
+	s := fmt.Sprintf("# Parameters of template %s.yml, one KEY=value per line.\n", group)
+	if !withHardcodedNamespace {
+		s += "# TAILOR_NAMESPACE is set by Tailor and does not need to be given.\n"
+	}
+	return s
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/internal/test/helper"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+type mockOcExportClient struct {
+	t *testing.T
+}
+
+func (c *mockOcExportClient) Export(target string, label string) ([]byte, error) {
+	return helper.ReadFixtureFile(c.t, "export/mixed.yml"), nil
+}
+
+func TestExportToDir(t *testing.T) {
+	tests := map[string]struct {
+		groupBy       string
+		paramDir      string
+		wantTemplates []string
+		wantParams    []string
+	}{
+		"group by app": {
+			groupBy:       "app",
+			paramDir:      ".",
+			wantTemplates: []string{"_unlabelled.env", "_unlabelled.yml", "db.env", "db.yml", "foo.env", "foo.yml"},
+		},
+		"group by kind": {
+			groupBy:       "kind",
+			paramDir:      ".",
+			wantTemplates: []string{"buildconfig.env", "buildconfig.yml", "configmap.env", "configmap.yml", "imagestream.env", "imagestream.yml", "service.env", "service.yml"},
+		},
+		"separate param dir": {
+			groupBy:       "app",
+			paramDir:      "params",
+			wantTemplates: []string{"_unlabelled.yml", "db.yml", "foo.yml"},
+			wantParams:    []string{"_unlabelled.env", "db.env", "foo.env"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			outputDir := filepath.Join(dir, "templates")
+			paramDir := tc.paramDir
+			if paramDir != "." {
+				paramDir = filepath.Join(dir, paramDir)
+			}
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			exportOptions := &cli.ExportOptions{
+				GlobalOptions:    globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				Excludes:         []string{},
+				ParamDir:         paramDir,
+				TrimAnnotations:  []string{},
+				OutputDir:        outputDir,
+				GroupBy:          tc.groupBy,
+			}
+			var out bytes.Buffer
+			err := export(&out, exportOptions, &mockOcExportClient{t: t})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantTemplates, fileNames(t, outputDir)); diff != "" {
+				t.Fatalf("Template files mismatch (-want +got):\n%s", diff)
+			}
+			if len(tc.wantParams) > 0 {
+				if diff := cmp.Diff(tc.wantParams, fileNames(t, paramDir)); diff != "" {
+					t.Fatalf("Param files mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestExportToDirKeepsParamFiles(t *testing.T) {
+	outputDir := t.TempDir()
+	existing := filepath.Join(outputDir, "foo.env")
+	err := os.WriteFile(existing, []byte("FOO=bar\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	exportOptions := &cli.ExportOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		Excludes:         []string{},
+		ParamDir:         ".",
+		TrimAnnotations:  []string{},
+		OutputDir:        outputDir,
+		GroupBy:          "app",
+	}
+	var out bytes.Buffer
+	err = export(&out, exportOptions, &mockOcExportClient{t: t})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("FOO=bar\n", string(b)); diff != "" {
+		t.Fatalf("Param file mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func fileNames(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// hookError is returned if a hook fails. It aborts the remaining changes,
+// even with --continue-on-error.
+type hookError struct {
+	hook *cli.Hook
+	err  error
+}
+
+func (e *hookError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.hook, e.err)
+}
+
+func isHookError(err error) bool {
+	var he *hookError
+	return errors.As(err, &he)
+}
+
+// runHooks runs the hooks configured for given event one after another. For
+// resource hooks, change is the change the hooks are run for and only hooks
+// targeting its resource are run.
+func runHooks(w io.Writer, event string, change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifierGetter) error {
+	for _, hook := range compareOptions.HooksFor(event) {
+		if change != nil && len(hook.Target) > 0 {
+			matches, err := change.Matches(hook.Target)
+			if err != nil {
+				return &hookError{hook: hook, err: err}
+			}
+			if !matches {
+				continue
+			}
+		}
+		fmt.Fprintf(w, "Running %s ...\n", hook)
+		var err error
+		if hook.IsJob() {
+			err = runJobHook(w, hook, compareOptions, ocClient)
+		} else {
+			err = runCommandHook(w, hook, change, compareOptions)
+		}
+		if err != nil {
+			return &hookError{hook: hook, err: err}
+		}
+	}
+	return nil
+}
+
+// runCommandHook runs the command of the hook in a shell. The namespace and
+// (for resource hooks) the change are passed as environment variables.
+func runCommandHook(w io.Writer, hook *cli.Hook, change *openshift.Change, compareOptions *cli.CompareOptions) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", hook.Run)
+	} else {
+		cmd = exec.Command("sh", "-c", hook.Run)
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+	cmd.Env = append(
+		os.Environ(),
+		"TAILOR_NAMESPACE="+compareOptions.Namespace,
+		"TAILOR_HOOK="+hook.Event,
+	)
+	if change != nil {
+		cmd.Env = append(
+			cmd.Env,
+			"TAILOR_ACTION="+change.Action,
+			"TAILOR_KIND="+change.Kind,
+			"TAILOR_NAME="+change.Name,
+		)
+	}
+	return cmd.Run()
+}
+
+// runJobHook creates the Job defined in the file of the hook and waits until
+// it completes, at most for --wait-timeout. As Jobs cannot be updated, a Job
+// with the same name left over from a previous run is deleted first.
+func runJobHook(w io.Writer, hook *cli.Hook, compareOptions *cli.CompareOptions, ocClient cli.ClientModifierGetter) error {
+	content, err := os.ReadFile(hook.JobFile())
+	if err != nil {
+		return fmt.Errorf("Could not read Job: %s", err)
+	}
+	var job map[string]interface{}
+	err = yaml.Unmarshal(content, &job)
+	if err != nil {
+		return fmt.Errorf("Could not parse Job: %s", err)
+	}
+	metadata, _ := job["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if job["kind"] != "Job" || len(name) == 0 {
+		return fmt.Errorf("%s does not define a Job with a name", hook.JobFile())
+	}
+
+	deadline := time.Now().Add(compareOptions.WaitTimeout)
+	if _, err := ocClient.Get("Job", name); err == nil {
+		cli.VerboseMsg("Deleting previous run of job", name)
+		errBytes, err := ocClient.Delete("Job", name)
+		if err != nil {
+			return fmt.Errorf("Could not delete previous run: %s", strings.TrimSpace(string(errBytes)))
+		}
+		for {
+			if _, err := ocClient.Get("Job", name); err != nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return errors.New("Timed out waiting for deletion of previous run")
+			}
+			time.Sleep(rolloutPollInterval)
+		}
+	}
+
+	errBytes, err := ocClient.Apply(string(content), "")
+	if err != nil {
+		return fmt.Errorf("Could not create Job: %s", strings.TrimSpace(string(errBytes)))
+	}
+	for {
+		config, err := ocClient.Get("Job", name)
+		state, message := openshift.RolloutPending, ""
+		if err == nil {
+			state, message, err = openshift.JobState(config)
+		}
+		if err != nil {
+			message = err.Error()
+		}
+		cli.VerboseMsg("job/"+name, state+":", message)
+		switch state {
+		case openshift.RolloutComplete:
+			fmt.Fprintf(w, "Job %s ... done\n", name)
+			return nil
+		case openshift.RolloutFailed:
+			fmt.Fprintf(w, "Job %s ... failed\n", name)
+			return fmt.Errorf("Job %s failed (%s)", name, message)
+		}
+		if time.Now().After(deadline) {
+			fmt.Fprintf(w, "Job %s ... timed out\n", name)
+			return fmt.Errorf("Job %s did not complete within %s (%s)", name, compareOptions.WaitTimeout, message)
+		}
+		time.Sleep(rolloutPollInterval)
+	}
+}
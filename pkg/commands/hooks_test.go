@@ -0,0 +1,227 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// mockOcJobClient returns the configured Job states one after the other. An
+// empty state means the Job does not exist.
+type mockOcJobClient struct {
+	states  []string
+	applied []string
+}
+
+func (c *mockOcJobClient) Get(kind string, name string) ([]byte, error) {
+	state := c.states[0]
+	if len(c.states) > 1 {
+		c.states = c.states[1:]
+	}
+	if len(state) == 0 {
+		return []byte("not found"), errors.New("exit status 1")
+	}
+	return []byte(state), nil
+}
+
+func (c *mockOcJobClient) Apply(config string, selector string) ([]byte, error) {
+	c.applied = append(c.applied, config)
+	return []byte(""), nil
+}
+
+func (c *mockOcJobClient) Delete(kind string, name string) ([]byte, error) {
+	return []byte(""), nil
+}
+
+func TestRunCommandHook(t *testing.T) {
+	tests := map[string]struct {
+		hook       *cli.Hook
+		wantOutput string
+		wantErr    bool
+	}{
+		"apply hook": {
+			hook:       &cli.Hook{Event: cli.HookPreApply, Run: "echo $TAILOR_HOOK $TAILOR_NAMESPACE"},
+			wantOutput: "Running pre-apply hook (echo $TAILOR_HOOK $TAILOR_NAMESPACE) ...\npre-apply foo\n",
+		},
+		"matching resource hook": {
+			hook:       &cli.Hook{Event: cli.HookPreResource, Target: "dc:foo", Run: "echo $TAILOR_ACTION $TAILOR_KIND $TAILOR_NAME"},
+			wantOutput: "Running pre-resource hook for dc:foo (echo $TAILOR_ACTION $TAILOR_KIND $TAILOR_NAME) ...\nUpdate DeploymentConfig foo\n",
+		},
+		"other resource hook": {
+			hook:       &cli.Hook{Event: cli.HookPreResource, Target: "dc:bar", Run: "echo bar"},
+			wantOutput: "",
+		},
+		"failing hook": {
+			hook:       &cli.Hook{Event: cli.HookPreResource, Target: "dc", Run: "exit 3"},
+			wantOutput: "Running pre-resource hook for dc (exit 3) ...\n",
+			wantErr:    true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compareOptions := &cli.CompareOptions{
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				Hooks:            []*cli.Hook{tc.hook},
+			}
+			var change *openshift.Change
+			if len(tc.hook.Target) > 0 {
+				change = &openshift.Change{Action: "Update", Kind: "DeploymentConfig", Name: "foo"}
+			}
+			var buf bytes.Buffer
+			err := runHooks(&buf, tc.hook.Event, change, compareOptions, &mockOcJobClient{})
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("Want error: %t, got: %v", tc.wantErr, err)
+			}
+			if err != nil && !isHookError(err) {
+				t.Fatalf("Want hook error, got: %s", err)
+			}
+			if diff := cmp.Diff(tc.wantOutput, buf.String()); diff != "" {
+				t.Fatalf("Output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunJobHook(t *testing.T) {
+	defer func(interval time.Duration) { rolloutPollInterval = interval }(rolloutPollInterval)
+	rolloutPollInterval = time.Millisecond
+
+	jobFile := filepath.Join(t.TempDir(), "migrate.yml")
+	err := os.WriteFile(jobFile, []byte("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running := `{"status": {"active": 1}}`
+	complete := `{"status": {"conditions": [{"type": "Complete", "status": "True"}]}}`
+	failed := `{"status": {"conditions": [{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded"}]}}`
+	tests := map[string]struct {
+		states  []string
+		wantErr string
+	}{
+		"completing job": {
+			states: []string{"", running, complete},
+		},
+		"previous run is replaced": {
+			states: []string{complete, complete, "", running, complete},
+		},
+		"failing job": {
+			states:  []string{"", running, failed},
+			wantErr: "Job migrate failed (BackoffLimitExceeded)",
+		},
+		"job not completing": {
+			states:  []string{"", running},
+			wantErr: "Job migrate did not complete within 50ms (1 pod(s) active)",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			hook := &cli.Hook{Event: cli.HookPostApply, Run: "job:" + jobFile}
+			compareOptions := &cli.CompareOptions{
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				WaitTimeout:      50 * time.Millisecond,
+			}
+			ocClient := &mockOcJobClient{states: tc.states}
+			var buf bytes.Buffer
+			err := runJobHook(&buf, hook, compareOptions, ocClient)
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if len(ocClient.applied) != 1 || !strings.Contains(ocClient.applied[0], "name: migrate") {
+				t.Fatalf("Want Job to be created once, got: %v", ocClient.applied)
+			}
+		})
+	}
+}
+
+func TestApplyReportsHookFailures(t *testing.T) {
+	tests := map[string]struct {
+		parallelism int
+		hook        *cli.Hook
+		wantResults []string
+	}{
+		"sequentially, pre-resource hook fails": {
+			parallelism: 1,
+			hook:        &cli.Hook{Event: cli.HookPreResource, Target: "cm:b", Run: "exit 1"},
+			wantResults: []string{resultSucceeded, resultPreHookFailed, resultSkipped},
+		},
+		"sequentially, post-resource hook fails": {
+			parallelism: 1,
+			hook:        &cli.Hook{Event: cli.HookPostResource, Target: "cm:b", Run: "exit 1"},
+			wantResults: []string{resultSucceeded, resultPostHookFailed, resultSkipped},
+		},
+		"concurrently, pre-resource hook fails": {
+			parallelism: 3,
+			hook:        &cli.Hook{Event: cli.HookPreResource, Target: "cm:b", Run: "exit 1"},
+			wantResults: []string{resultSucceeded, resultPreHookFailed, resultSucceeded},
+		},
+		"concurrently, post-resource hook fails": {
+			parallelism: 3,
+			hook:        &cli.Hook{Event: cli.HookPostResource, Target: "cm:b", Run: "exit 1"},
+			wantResults: []string{resultSucceeded, resultPostHookFailed, resultSucceeded},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			changes := []*openshift.Change{
+				{Action: "Create", Kind: "ConfigMap", Name: "a", DesiredState: "name: a\n"},
+				{Action: "Create", Kind: "ConfigMap", Name: "b", DesiredState: "name: b\n"},
+				{Action: "Create", Kind: "ConfigMap", Name: "c", DesiredState: "name: c\n"},
+			}
+			compareOptions := &cli.CompareOptions{
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				Parallelism:      tc.parallelism,
+				Hooks:            []*cli.Hook{tc.hook},
+			}
+			report := newApplyReport()
+			err := applyChanges("Creating", changes, applyChange, compareOptions, &mockOcModifyClient{}, report)
+			if err == nil {
+				t.Fatal("Want hook error, got none")
+			}
+			got := []string{}
+			for _, ar := range report.Results {
+				got = append(got, ar.Result)
+			}
+			if diff := cmp.Diff(tc.wantResults, got); diff != "" {
+				t.Fatalf("Results mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyAbortsOnHookFailure(t *testing.T) {
+	ocClient := &mockOcModifyClient{}
+	changeset := &openshift.Changeset{}
+	changeset.Add(
+		&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "a", DesiredState: "name: a\n"},
+		&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "b", DesiredState: "name: b\n"},
+		&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "c", DesiredState: "name: c\n"},
+	)
+	compareOptions := &cli.CompareOptions{
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		Parallelism:      1,
+		ContinueOnError:  true,
+		Hooks: []*cli.Hook{
+			{Event: cli.HookPreResource, Target: "cm:b", Run: "exit 1"},
+			{Event: cli.HookPostApply, Run: "exit 1"},
+		},
+	}
+	err := apply(compareOptions, changeset, ocClient)
+	if err == nil || !strings.Contains(err.Error(), "pre-resource hook for cm:b (exit 1) failed") {
+		t.Fatalf("Want hook error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a"}, ocClient.applied); diff != "" {
+		t.Fatalf("Applied resources mismatch (-want +got):\n%s", diff)
+	}
+}
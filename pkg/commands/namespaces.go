@@ -0,0 +1,279 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// namespaceRun holds the changeset and the outcome of one of several targeted
+// namespaces.
+type namespaceRun struct {
+	namespace      string
+	compareOptions *cli.CompareOptions
+	ocClient       cli.ClientApplier
+	output         bytes.Buffer
+	driftDetected  bool
+	changeset      *openshift.Changeset
+	err            error
+	result         string
+}
+
+// newNamespaceClient creates the client used for one of several namespaces.
+type newNamespaceClient func(namespace string) (cli.ClientApplier, error)
+
+func newClientApplier(namespace string) (cli.ClientApplier, error) {
+	return cli.NewClient(namespace)
+}
+
+// DiffNamespaces prints the drift of each targeted namespace, followed by a
+// summary of all namespaces.
+func DiffNamespaces(compareOptions *cli.CompareOptions) (bool, error) {
+	return diffNamespaces(os.Stdout, os.Stderr, compareOptions, newClientApplier)
+}
+
+// diffNamespaces writes the drift to w. With JSON output, the explanation of
+// namespaces which failed is written to errW to keep w machine-readable.
+func diffNamespaces(w io.Writer, errW io.Writer, compareOptions *cli.CompareOptions, newClient newNamespaceClient) (bool, error) {
+	runs, err := calculateNamespaceChangesets(compareOptions, newClient)
+	if err != nil {
+		return false, err
+	}
+	driftDetected := false
+	for _, r := range runs {
+		driftDetected = driftDetected || r.driftDetected
+	}
+
+	if compareOptions.Output == "json" {
+		reports := map[string]json.RawMessage{}
+		for _, r := range runs {
+			if r.err != nil {
+				cli.FprintBluef(errW, "=== OCP namespace %s ===\n", r.namespace)
+				fmt.Fprint(errW, r.output.String())
+				b, _ := json.Marshal(map[string]string{"error": r.err.Error()})
+				reports[r.namespace] = b
+				continue
+			}
+			b, err := r.changeset.JSON(secretDisplay(compareOptions))
+			if err != nil {
+				return driftDetected, fmt.Errorf("Could not serialize changeset of %s: %s", r.namespace, err)
+			}
+			reports[r.namespace] = b
+		}
+		b, err := json.MarshalIndent(map[string]interface{}{"namespaces": reports}, "", "  ")
+		if err != nil {
+			return driftDetected, fmt.Errorf("Could not serialize changesets: %s", err)
+		}
+		fmt.Fprintln(w, string(b))
+		return driftDetected, namespacesFailed("Diff", runs)
+	}
+
+	printNamespaceOutputs(w, runs)
+	printNamespaceSummary(w, runs)
+	return driftDetected, namespacesFailed("Diff", runs)
+}
+
+// ApplyNamespaces prints the drift of each targeted namespace and a summary,
+// asks once for confirmation, and then applies the changesets of all
+// namespaces with drift.
+func ApplyNamespaces(nonInteractive bool, compareOptions *cli.CompareOptions, stdin io.Reader) (bool, error) {
+	return applyNamespaces(os.Stdout, nonInteractive, compareOptions, newClientApplier, bufio.NewReader(stdin))
+}
+
+func applyNamespaces(w io.Writer, nonInteractive bool, compareOptions *cli.CompareOptions, newClient newNamespaceClient, stdinReader *bufio.Reader) (bool, error) {
+	runs, err := calculateNamespaceChangesets(compareOptions, newClient)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range runs {
+		if r.err == nil && r.driftDetected {
+			r.err = checkMaxDeletions(r.compareOptions, r.changeset, r.changeset.Targeted())
+		}
+	}
+	printNamespaceOutputs(w, runs)
+	printNamespaceSummary(w, runs)
+	if err := namespacesFailed("Apply aborted, diff", runs); err != nil {
+		return true, err
+	}
+
+	pending := []*namespaceRun{}
+	for _, r := range runs {
+		if r.driftDetected {
+			pending = append(pending, r)
+		}
+	}
+	if len(pending) == 0 {
+		return false, nil
+	}
+	if !nonInteractive {
+		question := fmt.Sprintf("Apply all changes in %d namespace(s)?", len(pending))
+		a := cli.AskForAction(question, []string{"y=yes", "n=no"}, stdinReader)
+		if a != "y" {
+			return true, nil
+		}
+		fmt.Fprintln(w, "")
+	}
+
+	// Changesets are applied one namespace after another, so that the output
+	// of each apply (including hooks) is not interleaved.
+	for _, r := range pending {
+		fmt.Fprintf(w, "Applying changes to OCP namespace %s ...\n", r.namespace)
+		r.err = applyChangeset(r.compareOptions, r.changeset, r.ocClient)
+		if r.err != nil {
+			r.result = "failed"
+		} else {
+			r.result = "applied"
+		}
+	}
+	printNamespaceResults(w, runs)
+	if err := namespacesFailed("Apply", runs); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// calculateNamespaceChangesets calculates the changeset of each targeted
+// namespace, using the namespace-specific param files (<namespace>.env and
+// the <namespace> param dir) by convention.
+func calculateNamespaceChangesets(compareOptions *cli.CompareOptions, newClient newNamespaceClient) ([]*namespaceRun, error) {
+	// Registering once upfront avoids concurrent writes to the kind mappings.
+	err := openshift.RegisterKinds(compareOptions.Kinds, compareOptions.DiscoverAPIResources)
+	if err != nil {
+		return nil, err
+	}
+	runs := []*namespaceRun{}
+	for _, namespace := range compareOptions.Namespaces {
+		runs = append(runs, &namespaceRun{
+			namespace:      namespace,
+			compareOptions: namespaceOptions(compareOptions, namespace),
+		})
+	}
+	forEachNamespace(runs, compareOptions.NamespaceParallelism, func(r *namespaceRun) {
+		r.ocClient, r.err = newClient(r.namespace)
+		if r.err != nil {
+			return
+		}
+		r.driftDetected, r.changeset, r.err = calculateChangeset(&r.output, r.compareOptions, r.ocClient)
+		if r.err == nil {
+			r.err = validationError(r.changeset)
+		}
+	})
+	return runs, nil
+}
+
+// namespaceOptions returns a copy of the options targeting given namespace.
+// The report file is suffixed by the namespace so that the reports of several
+// namespaces do not overwrite each other.
+func namespaceOptions(compareOptions *cli.CompareOptions, namespace string) *cli.CompareOptions {
+	o := *compareOptions
+	o.NamespaceOptions = &cli.NamespaceOptions{Namespace: namespace}
+	if len(o.Report) > 0 {
+		ext := filepath.Ext(o.Report)
+		o.Report = strings.TrimSuffix(o.Report, ext) + "-" + namespace + ext
+	}
+	return &o
+}
+
+// forEachNamespace calls fn for each run, for at most parallelism runs at the
+// same time.
+func forEachNamespace(runs []*namespaceRun, parallelism int, fn func(r *namespaceRun)) {
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, r := range runs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r *namespaceRun) {
+			defer wg.Done()
+			fn(r)
+			<-sem
+		}(r)
+	}
+	wg.Wait()
+}
+
+func printNamespaceOutputs(w io.Writer, runs []*namespaceRun) {
+	for _, r := range runs {
+		cli.FprintBluef(w, "=== OCP namespace %s ===\n", r.namespace)
+		fmt.Fprint(w, r.output.String())
+		if r.err != nil {
+			cli.FprintRedf(w, "Error: %s\n", r.err)
+		}
+		fmt.Fprintln(w, "")
+	}
+}
+
+// printNamespaceSummary writes a table with the number of changes of each
+// namespace.
+func printNamespaceSummary(w io.Writer, runs []*namespaceRun) {
+	fmt.Fprint(w, "Summary of all namespaces:\n\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tCREATE\tUPDATE\tDELETE\tSTATUS")
+	for _, r := range runs {
+		switch {
+		case r.err != nil:
+			fmt.Fprintf(tw, "%s\t-\t-\t-\terror\n", r.namespace)
+		default:
+			status := "in sync"
+			if r.driftDetected {
+				status = "drift"
+			}
+			fmt.Fprintf(
+				tw,
+				"%s\t%d\t%d\t%d\t%s\n",
+				r.namespace,
+				len(r.changeset.Create),
+				len(r.changeset.Update),
+				len(r.changeset.Delete),
+				status,
+			)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "")
+}
+
+// printNamespaceResults writes a table with the result of the apply in each
+// namespace, followed by the errors.
+func printNamespaceResults(w io.Writer, runs []*namespaceRun) {
+	fmt.Fprint(w, "\nResults:\n\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tRESULT")
+	for _, r := range runs {
+		result := r.result
+		if len(result) == 0 {
+			result = "in sync"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", r.namespace, result)
+	}
+	tw.Flush()
+}
+
+// namespacesFailed returns an error listing all namespaces with an error.
+func namespacesFailed(label string, runs []*namespaceRun) error {
+	failures := []string{}
+	for _, r := range runs {
+		if r.err != nil {
+			failures = append(failures, fmt.Sprintf("* %s: %s", r.namespace, strings.TrimSpace(r.err.Error())))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf(
+		"%s failed for %d of %d namespace(s):\n%s",
+		label,
+		len(failures),
+		len(runs),
+		strings.Join(failures, "\n"),
+	)
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestNamespaces(t *testing.T) {
+	tests := map[string]struct {
+		apply          bool
+		nonInteractive bool
+		stdinInput     string
+		failNamespace  string
+		wantDrift      bool
+		wantOutput     []string
+		wantErr        string
+	}{
+		"diff": {
+			wantDrift: true,
+			wantOutput: []string{
+				"=== OCP namespace bar ===",
+				"=== OCP namespace foo ===",
+				"NAMESPACE  CREATE  UPDATE  DELETE  STATUS",
+				"bar        0       2       0       drift",
+				"foo        0       2       0       drift",
+			},
+		},
+		"diff with failing namespace": {
+			failNamespace: "bar",
+			wantDrift:     true,
+			wantOutput: []string{
+				"bar        -       -       -       error",
+				"foo        0       2       0       drift",
+			},
+			wantErr: "Diff failed for 1 of 2 namespace(s):\n* bar: no access",
+		},
+		"apply interactively": {
+			apply:      true,
+			stdinInput: "y\n",
+			wantDrift:  false,
+			wantOutput: []string{
+				"bar        applied",
+				"foo        applied",
+			},
+		},
+		"apply declined": {
+			apply:      true,
+			stdinInput: "n\n",
+			wantDrift:  true,
+		},
+		"apply non-interactively": {
+			apply:          true,
+			nonInteractive: true,
+			wantDrift:      false,
+			wantOutput: []string{
+				"bar        applied",
+				"foo        applied",
+			},
+		},
+		"apply with failing namespace": {
+			apply:         true,
+			failNamespace: "foo",
+			stdinInput:    "y\n",
+			wantDrift:     true,
+			wantErr:       "Apply aborted, diff failed for 1 of 2 namespace(s):\n* foo: no access",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:        globalOptions,
+				NamespaceOptions:     &cli.NamespaceOptions{Namespaces: []string{"bar", "foo"}},
+				TemplateDir:          "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:           []string{},
+				NamespaceParallelism: 2,
+			}
+			newClient := func(namespace string) (cli.ClientApplier, error) {
+				if namespace == tc.failNamespace {
+					return nil, errors.New("no access")
+				}
+				return &mockOcApplyClient{
+					currentFixture: "current-list.yml",
+					desiredFixture: "template-dir/desired-list.yml",
+				}, nil
+			}
+			var out bytes.Buffer
+			var drift bool
+			var err error
+			if tc.apply {
+				stdin := bufio.NewReader(strings.NewReader(tc.stdinInput))
+				drift, err = applyNamespaces(&out, tc.nonInteractive, compareOptions, newClient, stdin)
+			} else {
+				drift, err = diffNamespaces(&out, io.Discard, compareOptions, newClient)
+			}
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if drift != tc.wantDrift {
+				t.Fatalf("Want drift=%t, got drift=%t", tc.wantDrift, drift)
+			}
+			for _, want := range tc.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Fatalf("Want output to contain '%s', got:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestNamespaceOptions(t *testing.T) {
+	compareOptions := &cli.CompareOptions{
+		NamespaceOptions: &cli.NamespaceOptions{Namespaces: []string{"foo"}},
+		Report:           "out/report.json",
+	}
+	got := namespaceOptions(compareOptions, "foo")
+	if got.Namespace != "foo" || len(got.Namespaces) > 0 {
+		t.Fatalf("Want namespace foo only, got: %+v", got.NamespaceOptions)
+	}
+	if got.Report != "out/report-foo.json" {
+		t.Fatalf("Want report out/report-foo.json, got: %s", got.Report)
+	}
+	if compareOptions.Report != "out/report.json" {
+		t.Fatal("Want original options to be unchanged")
+	}
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// Render prints the processed templates (desired state) to STDOUT, or
+// writes one file per resource into the output directory.
+func Render(renderOptions *cli.RenderOptions) error {
+	ocClient, err := cli.NewClient(renderOptions.Namespace)
+	if err != nil {
+		return err
+	}
+	return render(os.Stdout, renderOptions, ocClient)
+}
+
+func render(w io.Writer, renderOptions *cli.RenderOptions, ocClient cli.OcClientProcessor) error {
+	err := openshift.RegisterKinds(renderOptions.Kinds, renderOptions.DiscoverAPIResources)
+	if err != nil {
+		return err
+	}
+
+	filter, err := openshift.NewResourceFilter(renderOptions.Resource, renderOptions.Selector, renderOptions.Excludes)
+	if err != nil {
+		return err
+	}
+
+	templateBasedList, err := assembleTemplateBasedResourceList(filter, renderOptions.CompareOptions, ocClient)
+	if err != nil {
+		return err
+	}
+
+	if len(renderOptions.OutputDir) > 0 {
+		return renderToDir(renderOptions.OutputDir, renderOptions.Format, templateBasedList)
+	}
+
+	if renderOptions.Format == "json" {
+		items := []interface{}{}
+		for _, item := range templateBasedList.Items {
+			items = append(items, item.Config)
+		}
+		b, err := json.MarshalIndent(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      items,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(b))
+		return nil
+	}
+
+	for i, item := range templateBasedList.Items {
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		fmt.Fprint(w, item.YamlConfig())
+	}
+	return nil
+}
+
+// renderToDir writes each item into its own file named <kind>-<name>.
+func renderToDir(dir string, format string, list *openshift.ResourceList) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("Could not create output directory '%s': %s", dir, err)
+	}
+	for _, item := range list.Items {
+		var b []byte
+		ext := "yml"
+		if format == "json" {
+			ext = "json"
+			b, err = json.MarshalIndent(item.Config, "", "  ")
+			b = append(b, '\n')
+		} else {
+			b, err = yaml.Marshal(item.Config)
+		}
+		if err != nil {
+			return fmt.Errorf("Could not serialize %s: %s", item.FullName(), err)
+		}
+		filename := filepath.Join(dir, fmt.Sprintf("%s-%s.%s", strings.ToLower(item.Kind), item.Name, ext))
+		err = os.WriteFile(filename, b, 0644)
+		if err != nil {
+			return fmt.Errorf("Could not write '%s': %s", filename, err)
+		}
+		cli.VerboseMsg("Wrote", filename)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestRender(t *testing.T) {
+	tests := map[string]struct {
+		format    string
+		resource  string
+		wantOut   string
+		wantFiles []string
+	}{
+		"yaml": {
+			format:   "yaml",
+			resource: "is",
+			wantOut: `apiVersion: image.openshift.io/v1
+kind: ImageStream
+metadata:
+  name: foo
+spec:
+  dockerImageRepository: foo
+  lookupPolicy:
+    local: true
+`,
+		},
+		"json": {
+			format:   "json",
+			resource: "is",
+			wantOut: `{
+  "apiVersion": "v1",
+  "items": [
+    {
+      "apiVersion": "image.openshift.io/v1",
+      "kind": "ImageStream",
+      "metadata": {
+        "name": "foo"
+      },
+      "spec": {
+        "dockerImageRepository": "foo",
+        "lookupPolicy": {
+          "local": true
+        }
+      }
+    }
+  ],
+  "kind": "List"
+}
+`,
+		},
+		"yaml files": {
+			format:    "yaml",
+			wantFiles: []string{"buildconfig-foo.yml", "imagestream-foo.yml"},
+		},
+		"json files": {
+			format:    "json",
+			wantFiles: []string{"buildconfig-foo.json", "imagestream-foo.json"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			outputDir := ""
+			if len(tc.wantFiles) > 0 {
+				outputDir = t.TempDir() + "/rendered"
+			}
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			renderOptions := &cli.RenderOptions{
+				CompareOptions: &cli.CompareOptions{
+					GlobalOptions:    globalOptions,
+					NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+					TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+					ParamFiles:       []string{},
+					Excludes:         []string{},
+					Resource:         tc.resource,
+				},
+				Format:    tc.format,
+				OutputDir: outputDir,
+			}
+			ocClient := &mockOcApplyClient{
+				t:              t,
+				desiredFixture: "template-dir/desired-list.yml",
+			}
+			var out bytes.Buffer
+			err := render(&out, renderOptions, ocClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantOut, out.String()); diff != "" {
+				t.Fatalf("Output mismatch (-want +got):\n%s", diff)
+			}
+			if len(tc.wantFiles) > 0 {
+				entries, err := os.ReadDir(outputDir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gotFiles := []string{}
+				for _, e := range entries {
+					gotFiles = append(gotFiles, e.Name())
+				}
+				if diff := cmp.Diff(tc.wantFiles, gotFiles); diff != "" {
+					t.Fatalf("Files mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+const (
+	resultSucceeded      = "succeeded"
+	resultFailed         = "failed"
+	resultSkipped        = "skipped"
+	resultPreHookFailed  = "skipped (pre-hook failed)"
+	resultPostHookFailed = "applied, post-hook failed"
+)
+
+// applyReport holds the result of each change of an apply.
+type applyReport struct {
+	Summary applySummary   `json:"summary"`
+	Results []*applyResult `json:"results"`
+	// unapplied are the changes which failed or were skipped.
+	unapplied []*openshift.Change
+}
+
+type applySummary struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+type applyResult struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+func newApplyReport() *applyReport {
+	return &applyReport{Results: []*applyResult{}}
+}
+
+// resultOf returns the result of a change which was executed with given
+// error. Changes whose pre-resource hook failed were not attempted, and
+// changes whose post-resource hook failed were applied nonetheless.
+func resultOf(err error) string {
+	var he *hookError
+	switch {
+	case err == nil:
+		return resultSucceeded
+	case errors.As(err, &he) && he.hook.Event == cli.HookPreResource:
+		return resultPreHookFailed
+	case errors.As(err, &he) && he.hook.Event == cli.HookPostResource:
+		return resultPostHookFailed
+	default:
+		return resultFailed
+	}
+}
+
+// add records the result of given change. err is only set for failures.
+func (r *applyReport) add(change *openshift.Change, result string, err error) {
+	ar := &applyResult{
+		Action: change.Action,
+		Kind:   change.Kind,
+		Name:   change.Name,
+		Result: result,
+	}
+	if err != nil {
+		ar.Error = strings.TrimSpace(err.Error())
+	}
+	switch result {
+	case resultSucceeded:
+		r.Summary.Succeeded++
+	case resultFailed:
+		r.Summary.Failed++
+		r.unapplied = append(r.unapplied, change)
+	case resultSkipped, resultPreHookFailed:
+		r.Summary.Skipped++
+		r.unapplied = append(r.unapplied, change)
+	case resultPostHookFailed:
+		r.Summary.Failed++
+	}
+	r.Results = append(r.Results, ar)
+}
+
+// skip records all given changes as skipped.
+func (r *applyReport) skip(changes []*openshift.Change) {
+	for _, change := range changes {
+		r.add(change, resultSkipped, nil)
+	}
+}
+
+// blocked returns true if an earlier change of the same resource, or of a
+// resource given change depends on, failed or was skipped. Examples are the
+// deletion of a resource which is recreated, or the Service of a Route.
+func (r *applyReport) blocked(change *openshift.Change) bool {
+	for _, u := range r.unapplied {
+		if u.Kind == change.Kind && u.Name == change.Name {
+			return true
+		}
+		if change.DependsOn(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// print writes a table with the result of each change, followed by the
+// errors of all failed changes and hooks.
+func (r *applyReport) print(w io.Writer) {
+	fmt.Fprint(w, "\nResults:\n\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tKIND\tNAME\tRESULT")
+	for _, ar := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", ar.Action, ar.Kind, ar.Name, ar.Result)
+	}
+	tw.Flush()
+	fmt.Fprintf(
+		w,
+		"\n%d succeeded, %d failed, %d skipped.\n",
+		r.Summary.Succeeded,
+		r.Summary.Failed,
+		r.Summary.Skipped,
+	)
+	errorsPrinted := false
+	for _, ar := range r.Results {
+		if len(ar.Error) == 0 {
+			continue
+		}
+		if !errorsPrinted {
+			fmt.Fprint(w, "\nErrors:\n")
+			errorsPrinted = true
+		}
+		fmt.Fprintf(w, "* %s %s/%s: %s\n", ar.Action, ar.Kind, ar.Name, ar.Error)
+	}
+}
+
+// write saves the report as JSON to given file.
+func (r *applyReport) write(filename string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Could not serialize report: %s", err)
+	}
+	err = os.WriteFile(filename, b, 0644)
+	if err != nil {
+		return fmt.Errorf("Could not write report '%s': %s", filename, err)
+	}
+	return nil
+}
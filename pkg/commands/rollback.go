@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// Rollback restores the state of a snapshot taken before apply. It prints the
+// changes required to do so and asks for confirmation before applying them.
+// If no snapshot is given, the available snapshots are listed.
+func Rollback(nonInteractive bool, rollbackOptions *cli.RollbackOptions, ocClient cli.ClientApplier, stdin io.Reader) error {
+	if len(rollbackOptions.Snapshot) == 0 {
+		return listBackups(os.Stdout, rollbackOptions)
+	}
+
+	filename, err := resolveSnapshot(rollbackOptions)
+	if err != nil {
+		return err
+	}
+	backup, err := openshift.ReadBackup(filename)
+	if err != nil {
+		return err
+	}
+	if backup.Namespace != rollbackOptions.Namespace {
+		return fmt.Errorf(
+			"Snapshot '%s' was taken in OCP namespace %s, not %s",
+			filename,
+			backup.Namespace,
+			rollbackOptions.Namespace,
+		)
+	}
+	rollbackOptions.Selector = backup.Selector
+
+	err = openshift.RegisterKinds(rollbackOptions.Kinds, rollbackOptions.DiscoverAPIResources)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Restoring snapshot %s (taken %s) in OCP namespace %s.\n\n",
+		filename,
+		backup.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+		backup.Namespace,
+	)
+
+	platformBasedList, err := assemblePlatformBasedResourceList(backup.Filter(), rollbackOptions.CompareOptions, ocClient)
+	if err != nil {
+		return err
+	}
+	changeset := backup.Changeset(platformBasedList)
+	if changeset.Blank() {
+		fmt.Println("Current state matches snapshot, nothing to restore.")
+		return nil
+	}
+
+	var buf bytes.Buffer
+	printChangeset(&buf, changeset, secretDisplay(rollbackOptions.CompareOptions))
+	fmt.Print(buf.String())
+
+	if !nonInteractive {
+		a := cli.AskForAction("Restore snapshot?", []string{"y=yes", "n=no"}, bufio.NewReader(stdin))
+		if a != "y" {
+			return nil
+		}
+		fmt.Println("")
+	}
+
+	err = apply(rollbackOptions.CompareOptions, changeset, ocClient)
+	if err != nil {
+		return fmt.Errorf("Rollback aborted: %s", err)
+	}
+	return nil
+}
+
+// resolveSnapshot returns the file of the snapshot to restore, which is
+// either given as a path, as a file within the backup directory, or as
+// "latest".
+func resolveSnapshot(rollbackOptions *cli.RollbackOptions) (string, error) {
+	snapshot := rollbackOptions.Snapshot
+	if snapshot == "latest" {
+		files, err := openshift.ListBackups(rollbackOptions.BackupDir, rollbackOptions.Namespace)
+		if err != nil {
+			return "", err
+		}
+		if len(files) == 0 {
+			return "", fmt.Errorf("No snapshots of OCP namespace %s found in %s", rollbackOptions.Namespace, rollbackOptions.BackupDir)
+		}
+		return files[len(files)-1], nil
+	}
+	if _, err := os.Stat(snapshot); err == nil {
+		return snapshot, nil
+	}
+	inBackupDir := filepath.Join(rollbackOptions.BackupDir, snapshot)
+	if _, err := os.Stat(inBackupDir); err == nil {
+		return inBackupDir, nil
+	}
+	return "", fmt.Errorf("Snapshot '%s' does not exist", snapshot)
+}
+
+func listBackups(w io.Writer, rollbackOptions *cli.RollbackOptions) error {
+	files, err := openshift.ListBackups(rollbackOptions.BackupDir, rollbackOptions.Namespace)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(w, "No snapshots of OCP namespace %s found in %s.\n", rollbackOptions.Namespace, rollbackOptions.BackupDir)
+		return nil
+	}
+	fmt.Fprintf(w, "Snapshots of OCP namespace %s (restore with 'tailor rollback <snapshot>'):\n\n", rollbackOptions.Namespace)
+	for _, file := range files {
+		backup, err := openshift.ReadBackup(file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(
+			w,
+			"%s  (taken %s, %d resource(s))\n",
+			filepath.Base(file),
+			backup.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			len(backup.Resources),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestApplyAndRollback(t *testing.T) {
+	backupDir := t.TempDir()
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:       []string{},
+		BackupDir:        backupDir,
+	}
+	ocClient := &mockOcApplyClient{
+		currentFixture: "current-list.yml",
+		desiredFixture: "template-dir/desired-list.yml",
+	}
+	var stdin bytes.Buffer
+	_, err := Apply(true, compareOptions, ocClient, &stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := openshift.ListBackups(backupDir, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Want one snapshot, got: %v", files)
+	}
+	backup, err := openshift.ReadBackup(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup.Resources) == 0 {
+		t.Fatal("Want touched resources in snapshot")
+	}
+
+	// The mock client still returns the state before apply, so there is
+	// nothing to restore.
+	rollbackOptions := &cli.RollbackOptions{
+		CompareOptions: compareOptions,
+		Snapshot:       "latest",
+	}
+	err = Rollback(true, rollbackOptions, ocClient, &stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rollbackOptions.Snapshot = "does-not-exist.json"
+	err = Rollback(true, rollbackOptions, ocClient, &stdin)
+	if err == nil {
+		t.Fatal("Want error for unknown snapshot")
+	}
+}
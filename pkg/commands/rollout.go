@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// rolloutPollInterval is the time between two checks of the rollout state.
+var rolloutPollInterval = 2 * time.Second
+
+// waitForRollouts waits until the workloads among the given changes are
+// rolled out, reporting the outcome per resource. An error is returned if
+// any rollout fails or does not complete within timeout.
+func waitForRollouts(changes []*openshift.Change, timeout time.Duration, ocClient cli.OcClientGetter) error {
+	workloads := []*openshift.Change{}
+	for _, change := range changes {
+		if change.Action != "Delete" && openshift.HasRollout(change.Kind) {
+			workloads = append(workloads, change)
+		}
+	}
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\nWaiting for rollout of %d resource(s) (timeout %s) ...\n", len(workloads), timeout)
+	deadline := time.Now().Add(timeout)
+	states := make([]string, len(workloads))
+	messages := make([]string, len(workloads))
+	unsuccessful := []string{}
+	for {
+		pending := 0
+		for i, change := range workloads {
+			if states[i] == openshift.RolloutComplete || states[i] == openshift.RolloutFailed {
+				continue
+			}
+			config, err := ocClient.Get(change.Kind, change.Name)
+			if err == nil {
+				states[i], messages[i], err = openshift.RolloutState(change.Kind, config)
+			}
+			if err != nil {
+				states[i], messages[i] = openshift.RolloutPending, err.Error()
+			}
+			cli.VerboseMsg(change.ItemName(), states[i]+":", messages[i])
+			switch states[i] {
+			case openshift.RolloutComplete:
+				fmt.Printf("Rollout of %s ... done (%s)\n", change.ItemName(), messages[i])
+			case openshift.RolloutFailed:
+				fmt.Printf("Rollout of %s ... failed (%s)\n", change.ItemName(), messages[i])
+				unsuccessful = append(unsuccessful, change.ItemName())
+			default:
+				pending++
+			}
+		}
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			for i, change := range workloads {
+				if states[i] == openshift.RolloutPending {
+					fmt.Printf("Rollout of %s ... timed out (%s)\n", change.ItemName(), messages[i])
+					unsuccessful = append(unsuccessful, change.ItemName())
+				}
+			}
+			break
+		}
+		time.Sleep(rolloutPollInterval)
+	}
+
+	if len(unsuccessful) > 0 {
+		return fmt.Errorf("Rollout did not complete for %s", strings.Join(unsuccessful, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// mockOcGetClient returns the configured states of a resource one after the
+// other, repeating the last one.
+type mockOcGetClient struct {
+	states map[string][]string
+}
+
+func (c *mockOcGetClient) Get(kind string, name string) ([]byte, error) {
+	states := c.states[kind+"/"+name]
+	state := states[0]
+	if len(states) > 1 {
+		c.states[kind+"/"+name] = states[1:]
+	}
+	return []byte(state), nil
+}
+
+func TestWaitForRollouts(t *testing.T) {
+	defer func(interval time.Duration) { rolloutPollInterval = interval }(rolloutPollInterval)
+	rolloutPollInterval = time.Millisecond
+
+	progressing := `{"spec": {"replicas": 1}, "status": {"replicas": 1, "updatedReplicas": 0}}`
+	available := `{"spec": {"replicas": 1}, "status": {"replicas": 1, "updatedReplicas": 1, "availableReplicas": 1}}`
+	failed := `{"status": {"conditions": [{"type": "Progressing", "reason": "ProgressDeadlineExceeded"}]}}`
+
+	tests := map[string]struct {
+		states  map[string][]string
+		wantErr string
+	}{
+		"all rolled out": {
+			states: map[string][]string{
+				"Deployment/foo": {progressing, progressing, available},
+				"Deployment/bar": {available},
+			},
+			wantErr: "",
+		},
+		"one failing": {
+			states: map[string][]string{
+				"Deployment/foo": {progressing, failed},
+				"Deployment/bar": {available},
+			},
+			wantErr: "Rollout did not complete for deployment/foo",
+		},
+		"one stalling": {
+			states: map[string][]string{
+				"Deployment/foo": {available},
+				"Deployment/bar": {progressing},
+			},
+			wantErr: "Rollout did not complete for deployment/bar",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			changes := []*openshift.Change{
+				{Action: "Update", Kind: "Deployment", Name: "foo"},
+				{Action: "Create", Kind: "Deployment", Name: "bar"},
+				{Action: "Delete", Kind: "Deployment", Name: "baz"},
+				{Action: "Update", Kind: "ConfigMap", Name: "foo"},
+			}
+			err := waitForRollouts(changes, 50*time.Millisecond, &mockOcGetClient{states: tc.states})
+			if len(tc.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Want no error, got: %s", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Want error %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
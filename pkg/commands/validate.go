@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// Validate checks the processed templates (desired state) against an OpenAPI
+// schema, without comparing them to the current state.
+func Validate(validateOptions *cli.ValidateOptions) error {
+	ocClient, err := cli.NewClient(validateOptions.Namespace)
+	if err != nil {
+		return err
+	}
+	return validate(os.Stdout, validateOptions, ocClient)
+}
+
+func validate(w io.Writer, validateOptions *cli.ValidateOptions, ocClient cli.OcClientProcessor) error {
+	err := openshift.RegisterKinds(validateOptions.Kinds, validateOptions.DiscoverAPIResources)
+	if err != nil {
+		return err
+	}
+
+	filter, err := openshift.NewResourceFilter(validateOptions.Resource, validateOptions.Selector, validateOptions.Excludes)
+	if err != nil {
+		return err
+	}
+
+	templateBasedList, err := assembleTemplateBasedResourceList(filter, validateOptions.CompareOptions, ocClient)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Validated %d resource(s) against schema %s.\n", templateBasedList.Length(), validateOptions.Schema)
+	return nil
+}
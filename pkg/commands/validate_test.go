@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		desiredFixture string
+		wantOut        string
+		wantErr        string
+	}{
+		"valid": {
+			desiredFixture: "template-dir/desired-list.yml",
+			wantOut:        "Validated 2 resource(s) against schema openshift-4.\n",
+		},
+		"invalid": {
+			desiredFixture: "invalid-list.yml",
+			wantErr:        "Found 1 schema violation(s):\n* desired-list.yml: ImageStream/foo /spec/lookupPolicy/locale: unknown field",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			validateOptions := &cli.ValidateOptions{
+				CompareOptions: &cli.CompareOptions{
+					GlobalOptions:    globalOptions,
+					NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+					TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+					ParamFiles:       []string{},
+					Excludes:         []string{},
+					Schema:           "openshift-4",
+				},
+			}
+			ocClient := &mockOcApplyClient{
+				t:              t,
+				desiredFixture: tc.desiredFixture,
+			}
+			var out bytes.Buffer
+			err := validate(&out, validateOptions, ocClient)
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tc.wantOut {
+				t.Fatalf("Want output '%s', got: '%s'", tc.wantOut, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package openshift
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+const backupFormatVersion = 1
+
+// backupTimeRegex matches the part of a backup filename following the
+// namespace (see Filename). Snapshots taken before microseconds were added
+// have no fraction.
+var backupTimeRegex = regexp.MustCompile(`^-[0-9]{8}-[0-9]{6}(\.[0-9]{6})?\.json$`)
+
+// Backup is a snapshot of all resources touched by an apply, taken before
+// any change is applied. It allows to restore the state prior to the apply.
+type Backup struct {
+	Version   int               `json:"version"`
+	Namespace string            `json:"namespace"`
+	Selector  string            `json:"selector"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Resources []*BackupResource `json:"resources"`
+}
+
+// BackupResource is the state of a single resource before the apply. The
+// state is empty for resources which did not exist yet.
+type BackupResource struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	State  string `json:"state"`
+}
+
+// NewBackup creates a backup of the current state of all resources touched
+// by given changeset.
+func NewBackup(namespace string, selector string, changeset *Changeset, createdAt time.Time) *Backup {
+	b := &Backup{
+		Version:   backupFormatVersion,
+		Namespace: namespace,
+		Selector:  selector,
+		CreatedAt: createdAt.UTC(),
+		Resources: []*BackupResource{},
+	}
+	seen := map[string]bool{}
+	for _, c := range changeset.Changes() {
+		// A recreation consists of a delete and a create change, the
+		// delete change holds the state of the existing resource.
+		if seen[c.fullName()] {
+			continue
+		}
+		seen[c.fullName()] = true
+		b.Resources = append(b.Resources, &BackupResource{
+			Action: c.Action,
+			Kind:   c.Kind,
+			Name:   c.Name,
+			State:  c.CurrentState,
+		})
+	}
+	return b
+}
+
+// ReadBackup reads a backup from given file.
+func ReadBackup(filename string) (*Backup, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read snapshot '%s': %s", filename, err)
+	}
+	b := &Backup{}
+	err = json.Unmarshal(content, b)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse snapshot '%s': %s", filename, err)
+	}
+	if b.Version != backupFormatVersion {
+		return nil, fmt.Errorf("Snapshot '%s' has unsupported version %d", filename, b.Version)
+	}
+	return b, nil
+}
+
+// Filename returns the name of the file the backup is saved as, which sorts
+// chronologically for the same namespace. The time is precise to the
+// microsecond so that the snapshots of consecutive runs do not collide.
+func (b *Backup) Filename() string {
+	return b.Namespace + "-" + b.CreatedAt.Format("20060102-150405.000000") + ".json"
+}
+
+// Write saves the backup into given directory and returns the path of the
+// file. As the backup contains secret values, it is only readable by the
+// owner, and the directory is ignored by git. Existing snapshots are never
+// overwritten.
+func (b *Backup) Write(dir string) (string, error) {
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Could not serialize snapshot: %s", err)
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("Could not create snapshot directory: %s", err)
+	}
+	err = writeBackupGitignore(dir)
+	if err != nil {
+		return "", fmt.Errorf("Could not create .gitignore in snapshot directory: %s", err)
+	}
+	filename := filepath.Join(dir, b.Filename())
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("Could not create snapshot file: %s", err)
+	}
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("Could not write snapshot file: %s", err)
+	}
+	return filename, nil
+}
+
+// writeBackupGitignore ignores all files of the backup directory, so that
+// snapshots do not end up in the repository if the directory is inside it.
+func writeBackupGitignore(dir string) error {
+	filename := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+	return os.WriteFile(filename, []byte("*\n"), 0600)
+}
+
+// ListBackups returns the files of all backups of given namespace in dir,
+// oldest first. Backups of other namespaces sharing the prefix (e.g.
+// "foo-dev" for "foo") are not included.
+func ListBackups(dir string, namespace string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	files := []string{}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, namespace) && backupTimeRegex.MatchString(strings.TrimPrefix(name, namespace)) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Filter returns a filter targeting all kinds which are part of the backup.
+func (b *Backup) Filter() *ResourceFilter {
+	kinds := []string{}
+	for _, r := range b.Resources {
+		if !utils.Includes(kinds, r.Kind) {
+			kinds = append(kinds, r.Kind)
+		}
+	}
+	sort.Strings(kinds)
+	return &ResourceFilter{Kinds: kinds}
+}
+
+// Changeset calculates the changes needed to bring the resources in given
+// platform based list back to the state of the backup: deleted resources are
+// recreated, updated resources are reverted and created resources are
+// deleted.
+func (b *Backup) Changeset(platformBasedList *ResourceList) *Changeset {
+	changeset := &Changeset{
+		Create: []*Change{},
+		Update: []*Change{},
+		Delete: []*Change{},
+		Noop:   []*Change{},
+	}
+	for _, r := range b.Resources {
+		item, err := platformBasedList.getItem(r.Kind, r.Name)
+		exists := err == nil
+		c := &Change{Kind: r.Kind, Name: r.Name, DesiredState: r.State}
+		if exists {
+			c.CurrentState = item.YamlConfig()
+			c.ResourceVersion = item.ResourceVersion
+		}
+		switch {
+		case len(r.State) == 0 && !exists:
+			c.Action = "Noop"
+		case len(r.State) == 0:
+			c.Action = "Delete"
+		case !exists:
+			c.Action = "Create"
+		case strings.TrimSpace(c.CurrentState) == strings.TrimSpace(r.State):
+			c.Action = "Noop"
+		default:
+			c.Action = "Update"
+		}
+		changeset.Add(c)
+	}
+	changeset.orderByDependencies()
+	return changeset
+}
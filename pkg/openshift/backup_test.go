@@ -0,0 +1,122 @@
+package openshift
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBackupChangeset(t *testing.T) {
+	configMap := func(name string, value string) string {
+		return "apiVersion: v1\ndata:\n  foo: " + value + "\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n"
+	}
+	changeset := &Changeset{}
+	changeset.Add(
+		&Change{Action: "Delete", Kind: "ConfigMap", Name: "deleted", CurrentState: configMap("deleted", "bar")},
+		&Change{Action: "Update", Kind: "ConfigMap", Name: "updated", CurrentState: configMap("updated", "bar")},
+		&Change{Action: "Update", Kind: "ConfigMap", Name: "untouched", CurrentState: configMap("untouched", "bar")},
+		&Change{Action: "Create", Kind: "ConfigMap", Name: "created"},
+		&Change{Action: "Delete", Kind: "ConfigMap", Name: "recreated", CurrentState: configMap("recreated", "bar")},
+		&Change{Action: "Create", Kind: "ConfigMap", Name: "recreated"},
+	)
+
+	dir := t.TempDir()
+	createdAt := time.Date(2020, 5, 5, 11, 25, 28, 0, time.UTC)
+	filename, err := NewBackup("foo", "app=foo", changeset, createdAt).Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "foo-20200505-112528.000000.json"); filename != want {
+		t.Fatalf("Want file %s, got: %s", want, filename)
+	}
+	_, err = NewBackup("foo", "app=foo", changeset, createdAt).Write(dir)
+	if err == nil {
+		t.Fatal("Want error when snapshot file exists already")
+	}
+	_, err = NewBackup("foo-dev", "app=foo", changeset, createdAt).Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".gitignore")); err != nil {
+		t.Fatalf("Want .gitignore in snapshot directory: %s", err)
+	}
+	files, err := ListBackups(dir, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{filename}, files); diff != "" {
+		t.Fatalf("Backups mismatch (-want +got):\n%s", diff)
+	}
+	backup, err := ReadBackup(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup.Resources) != 5 {
+		t.Fatalf("Want 5 resources in backup, got: %d", len(backup.Resources))
+	}
+
+	// State after apply, where "untouched" has not been changed.
+	platformInput := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: updated
+  data:
+    foo: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: untouched
+  data:
+    foo: bar
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: created
+  data:
+    foo: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: recreated
+  data:
+    foo: baz
+`)
+	list, err := NewPlatformBasedResourceList(backup.Filter(), platformInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := backup.Changeset(list)
+	names := func(changes []*Change) []string {
+		n := []string{}
+		for _, c := range changes {
+			n = append(n, c.Name)
+		}
+		sort.Strings(n)
+		return n
+	}
+	want := map[string][]string{
+		"Create": {"deleted"},
+		"Update": {"recreated", "updated"},
+		"Delete": {"created"},
+		"Noop":   {"untouched"},
+	}
+	gotNames := map[string][]string{
+		"Create": names(got.Create),
+		"Update": names(got.Update),
+		"Delete": names(got.Delete),
+		"Noop":   names(got.Noop),
+	}
+	if diff := cmp.Diff(want, gotNames); diff != "" {
+		t.Fatalf("Changeset mismatch (-want +got):\n%s", diff)
+	}
+	if got.Create[0].DesiredState != configMap("deleted", "bar") {
+		t.Fatalf("Want deleted resource to be recreated from backup, got:\n%s", got.Create[0].DesiredState)
+	}
+}
@@ -1,6 +1,13 @@
 package openshift
 
 import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/opendevstack/tailor/pkg/utils"
 	"github.com/pmezard/go-difflib/difflib"
 )
 
@@ -30,20 +37,24 @@ var (
 // Change is a description of a drift between current and desired state, and
 // the required patches to bring them back in sync.
 type Change struct {
-	Action       string
-	Kind         string
-	Name         string
-	CurrentState string
-	DesiredState string
+	Action          string `json:"action"`
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	CurrentState    string `json:"currentState"`
+	DesiredState    string `json:"desiredState"`
+	ResourceVersion string `json:"resourceVersion"`
+	// ValidationError is the error of a server-side dry-run of the change.
+	ValidationError string `json:"-"`
 }
 
 // NewChange creates a new change for given template/platform item.
 func NewChange(templateItem *ResourceItem, platformItem *ResourceItem) *Change {
 	c := &Change{
-		Kind:         templateItem.Kind,
-		Name:         templateItem.Name,
-		CurrentState: platformItem.YamlConfig(),
-		DesiredState: templateItem.YamlConfig(),
+		Kind:            templateItem.Kind,
+		Name:            templateItem.Name,
+		CurrentState:    platformItem.YamlConfig(),
+		DesiredState:    templateItem.YamlConfig(),
+		ResourceVersion: platformItem.ResourceVersion,
 	}
 
 	if platformItem.YamlConfig() != templateItem.YamlConfig() {
@@ -60,14 +71,38 @@ func (c *Change) ItemName() string {
 	return kindToShortMapping[c.Kind] + "/" + c.Name
 }
 
-// Diff returns a unified diff text for the change.
-func (c *Change) Diff(revealSecrets bool) string {
-	if c.isSecret() && !revealSecrets {
-		return "Secret drift is hidden. Use --reveal-secrets to see details.\n"
+// Matches returns true if the change relates to a resource selected by
+// target, which is either a kind (e.g. "dc") or a kind and a name (e.g.
+// "dc:foo"). Like for --preserve, the name may be a glob or a regular
+// expression.
+func (c *Change) Matches(target string) (bool, error) {
+	parts := strings.SplitN(target, ":", 2)
+	if KindMapping[strings.ToLower(parts[0])] != c.Kind {
+		return false, nil
+	}
+	if len(parts) == 1 {
+		return true, nil
+	}
+	return preservedNameMatches(parts[1], c.Name)
+}
+
+// Diff returns a unified diff text for the change. The drift of Secret
+// resources is shown according to secrets.
+func (c *Change) Diff(secrets SecretDisplay) string {
+	currentState, desiredState := c.CurrentState, c.DesiredState
+	if c.isSecret() && secrets != SecretsRevealed {
+		if secrets != SecretsRedacted {
+			return secretDriftHidden
+		}
+		var err error
+		currentState, desiredState, err = c.redactedStates()
+		if err != nil {
+			return secretDriftHidden
+		}
 	}
 	diff := difflib.UnifiedDiff{
-		A:        difflib.SplitLines(c.CurrentState),
-		B:        difflib.SplitLines(c.DesiredState),
+		A:        difflib.SplitLines(currentState),
+		B:        difflib.SplitLines(desiredState),
 		FromFile: "Current State (OpenShift cluster)",
 		ToFile:   "Desired State (Processed template)",
 		Context:  3,
@@ -76,17 +111,70 @@ func (c *Change) Diff(revealSecrets bool) string {
 	return text
 }
 
+// PathChange describes the drift of a single path within a resource. Op is
+// one of "add", "remove" or "replace". Path is in RFC 6901 format.
+type PathChange struct {
+	Op      string      `json:"op"`
+	Path    string      `json:"path"`
+	Current interface{} `json:"current,omitempty"`
+	Desired interface{} `json:"desired,omitempty"`
+}
+
+// PathChanges returns the drift between current and desired state on a
+// per-path basis. Paths are sorted to have a stable order.
+func (c *Change) PathChanges() ([]*PathChange, error) {
+	return pathChanges(c.CurrentState, c.DesiredState)
+}
+
+// RedactedPathChanges is like PathChanges, but values of Secret resources
+// are redacted.
+func (c *Change) RedactedPathChanges() ([]*PathChange, error) {
+	if !c.isSecret() {
+		return c.PathChanges()
+	}
+	currentState, desiredState, err := c.redactedStates()
+	if err != nil {
+		return nil, err
+	}
+	return pathChanges(currentState, desiredState)
+}
+
+func (c *Change) redactedStates() (string, string, error) {
+	currentState, err := redactedState(c.CurrentState)
+	if err != nil {
+		return "", "", err
+	}
+	desiredState, err := redactedState(c.DesiredState)
+	if err != nil {
+		return "", "", err
+	}
+	return currentState, desiredState, nil
+}
+
+func pathChanges(currentState, desiredState string) ([]*PathChange, error) {
+	current, err := stateToMap(currentState)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := stateToMap(desiredState)
+	if err != nil {
+		return nil, err
+	}
+	return diffValues("", current, desired), nil
+}
+
 func (c *Change) isSecret() bool {
 	return kindToShortMapping[c.Kind] == "secret"
 }
 
 func recreateChanges(templateItem, platformItem *ResourceItem) []*Change {
 	deleteChange := &Change{
-		Action:       "Delete",
-		Kind:         templateItem.Kind,
-		Name:         templateItem.Name,
-		CurrentState: platformItem.YamlConfig(),
-		DesiredState: "",
+		Action:          "Delete",
+		Kind:            templateItem.Kind,
+		Name:            templateItem.Name,
+		CurrentState:    platformItem.YamlConfig(),
+		DesiredState:    "",
+		ResourceVersion: platformItem.ResourceVersion,
 	}
 	createChange := &Change{
 		Action:       "Create",
@@ -97,3 +185,73 @@ func recreateChanges(templateItem, platformItem *ResourceItem) []*Change {
 	}
 	return []*Change{deleteChange, createChange}
 }
+
+func stateToMap(state string) (interface{}, error) {
+	if len(state) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	var f interface{}
+	err := yaml.Unmarshal([]byte(state), &f)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return map[string]interface{}{}, nil
+	}
+	return f, nil
+}
+
+// diffValues compares current and desired value recursively and returns the
+// changes required to transform current into desired.
+func diffValues(path string, current, desired interface{}) []*PathChange {
+	pathChanges := []*PathChange{}
+	switch c := current.(type) {
+	case map[string]interface{}:
+		if d, ok := desired.(map[string]interface{}); ok {
+			keys := []string{}
+			for k := range c {
+				keys = append(keys, k)
+			}
+			for k := range d {
+				if _, ok := c[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				p := path + "/" + utils.JSONPointerPath(k)
+				cv, inCurrent := c[k]
+				dv, inDesired := d[k]
+				if !inDesired {
+					pathChanges = append(pathChanges, &PathChange{Op: "remove", Path: p, Current: cv})
+				} else if !inCurrent {
+					pathChanges = append(pathChanges, &PathChange{Op: "add", Path: p, Desired: dv})
+				} else {
+					pathChanges = append(pathChanges, diffValues(p, cv, dv)...)
+				}
+			}
+			return pathChanges
+		}
+	case []interface{}:
+		if d, ok := desired.([]interface{}); ok {
+			if key, ok := mergeKeyFor(fieldOf(path), c, d); ok {
+				return diffKeyedArrays(path, key, c, d)
+			}
+			for i := 0; i < len(c) || i < len(d); i++ {
+				p := path + "/" + strconv.Itoa(i)
+				if i >= len(d) {
+					pathChanges = append(pathChanges, &PathChange{Op: "remove", Path: p, Current: c[i]})
+				} else if i >= len(c) {
+					pathChanges = append(pathChanges, &PathChange{Op: "add", Path: p, Desired: d[i]})
+				} else {
+					pathChanges = append(pathChanges, diffValues(p, c[i], d[i])...)
+				}
+			}
+			return pathChanges
+		}
+	}
+	if !reflect.DeepEqual(current, desired) {
+		pathChanges = append(pathChanges, &PathChange{Op: "replace", Path: path, Current: current, Desired: desired})
+	}
+	return pathChanges
+}
@@ -3,6 +3,8 @@ package openshift
 import (
 	"bytes"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestDiff(t *testing.T) {
@@ -157,7 +159,7 @@ func TestDiff(t *testing.T) {
 				t.Fatal(err)
 			}
 			change := changes[0]
-			actualDiff := change.Diff(true)
+			actualDiff := change.Diff(SecretsRevealed)
 			if actualDiff != tt.expectedDiff {
 				t.Fatalf(
 					"Diff()\n===== expected =====\n%s\n===== actual =====\n%s",
@@ -169,6 +171,84 @@ func TestDiff(t *testing.T) {
 	}
 }
 
+func TestPathChanges(t *testing.T) {
+	tests := map[string]struct {
+		current string
+		desired string
+		want    []*PathChange
+	}{
+		"Modifying a data field": {
+			current: "data: {foo: bar}",
+			desired: "data: {foo: baz}",
+			want: []*PathChange{
+				{Op: "replace", Path: "/data/foo", Current: "bar", Desired: "baz"},
+			},
+		},
+		"Adding and removing fields": {
+			current: "data: {foo: bar, a/b: c}",
+			desired: "data: {baz: qux}",
+			want: []*PathChange{
+				{Op: "remove", Path: "/data/a~1b", Current: "c"},
+				{Op: "add", Path: "/data/baz", Desired: "qux"},
+				{Op: "remove", Path: "/data/foo", Current: "bar"},
+			},
+		},
+		"Changing array elements": {
+			current: "items: [a, b, c]",
+			desired: "items: [a, x]",
+			want: []*PathChange{
+				{Op: "replace", Path: "/items/1", Current: "b", Desired: "x"},
+				{Op: "remove", Path: "/items/2", Current: "c"},
+			},
+		},
+		"Inserting a keyed array element": {
+			current: "containers: [{name: a, image: a}, {name: b, image: b}, {name: c, image: c}]",
+			desired: "containers: [{name: a, image: a}, {name: x, image: x}, {name: b, image: b}, {name: c, image: z}]",
+			want: []*PathChange{
+				{Op: "add", Path: "/containers/1", Desired: map[string]interface{}{"name": "x", "image": "x"}},
+				{Op: "replace", Path: "/containers/3/image", Current: "c", Desired: "z"},
+			},
+		},
+		"Reordering an array of which the order matters": {
+			current: "env: [{name: A, value: a}, {name: B, value: $(A)}]",
+			desired: "env: [{name: B, value: $(A)}, {name: A, value: a}]",
+			want: []*PathChange{
+				{Op: "replace", Path: "/env/0/name", Current: "A", Desired: "B"},
+				{Op: "replace", Path: "/env/0/value", Current: "a", Desired: "$(A)"},
+				{Op: "replace", Path: "/env/1/name", Current: "B", Desired: "A"},
+				{Op: "replace", Path: "/env/1/value", Current: "$(A)", Desired: "a"},
+			},
+		},
+		"Reordering and removing keyed array elements": {
+			current: "ports: [{containerPort: 8080}, {containerPort: 8443, protocol: TCP}, {containerPort: 9090}]",
+			desired: "ports: [{containerPort: 8443, protocol: TCP}, {containerPort: 8080}]",
+			want: []*PathChange{
+				{Op: "remove", Path: "/ports/2", Current: map[string]interface{}{"containerPort": float64(9090)}},
+			},
+		},
+		"Creating a resource": {
+			current: "",
+			desired: "kind: ConfigMap",
+			want: []*PathChange{
+				{Op: "add", Path: "/kind", Desired: "ConfigMap"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Change{CurrentState: tt.current, DesiredState: tt.desired}
+			got, err := c.PathChanges()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("Path changes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func getConfigMapForDiff(annotations, data []byte) []byte {
 	config := []byte(
 		`apiVersion: v1
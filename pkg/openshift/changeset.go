@@ -1,10 +1,12 @@
 package openshift
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/utils"
 	"github.com/xeipuuv/gojsonpointer"
 )
@@ -34,13 +36,16 @@ var (
 )
 
 type Changeset struct {
-	Create []*Change
-	Update []*Change
-	Delete []*Change
-	Noop   []*Change
+	Create []*Change `json:"create"`
+	Update []*Change `json:"update"`
+	Delete []*Change `json:"delete"`
+	Noop   []*Change `json:"noop"`
 }
 
-func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly bool, allowRecreate bool, preservePaths []string) (*Changeset, error) {
+// NewChangeset calculates the changes between current and desired state. If
+// ownership is given, only resources marked with it are deleted. Protected
+// resources are only deleted (or recreated) if allowProtectedDeletion is true.
+func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly bool, allowRecreate bool, preservePaths []string, ownership *Ownership, allowProtectedDeletion bool) (*Changeset, error) {
 	changeset := &Changeset{
 		Create: []*Change{},
 		Delete: []*Change{},
@@ -52,12 +57,17 @@ func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly
 	if !upsertOnly {
 		for _, item := range platformBasedList.Items {
 			if _, err := templateBasedList.getItem(item.Kind, item.Name); err != nil {
+				if ownership != nil && !ownership.Owns(item) {
+					cli.VerboseMsg("Not deleting", item.FullName(), "as it is not owned by", ownership.Value)
+					continue
+				}
 				change := &Change{
-					Action:       "Delete",
-					Kind:         item.Kind,
-					Name:         item.Name,
-					CurrentState: item.YamlConfig(),
-					DesiredState: "",
+					Action:          "Delete",
+					Kind:            item.Kind,
+					Name:            item.Name,
+					CurrentState:    item.YamlConfig(),
+					DesiredState:    "",
+					ResourceVersion: item.ResourceVersion,
 				}
 				changeset.Add(change)
 			}
@@ -101,13 +111,22 @@ func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly
 				// Preserved paths can be either:
 				// - globally (e.g. /spec/name)
 				// - per-kind (e.g. bc:/spec/name)
-				// - per-resource (e.g. bc:foo:/spec/name)
+				// - per-resource (e.g. bc:foo:/spec/name), where the name
+				//   may be a glob (e.g. bc:foo-*:/spec/name) or a regular
+				//   expression (e.g. bc:/^foo-[0-9]+$/:/spec/name)
+				nameMatches := false
+				if len(pathParts) == 3 {
+					nameMatches, err = preservedNameMatches(pathParts[1], templateItem.Name)
+					if err != nil {
+						return changeset, err
+					}
+				}
 				if len(pathParts) == 1 ||
 					(len(pathParts) == 2 &&
 						templateItem.Kind == KindMapping[strings.ToLower(pathParts[0])]) ||
 					(len(pathParts) == 3 &&
 						templateItem.Kind == KindMapping[strings.ToLower(pathParts[0])] &&
-						templateItem.Name == strings.ToLower(pathParts[1])) {
+						nameMatches) {
 					// We only care about the last part (the JSON path) as we
 					// are already "inside" the item
 					actualReservePaths = append(actualReservePaths, pathParts[len(pathParts)-1])
@@ -121,11 +140,34 @@ func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly
 			changeset.Add(changes...)
 		}
 	}
+	changeset.orderByDependencies()
+
+	if !allowProtectedDeletion {
+		protected := []string{}
+		for _, change := range changeset.Delete {
+			item, err := platformBasedList.getItem(change.Kind, change.Name)
+			if err == nil && item.IsProtected() {
+				protected = append(protected, change.ItemName())
+			}
+		}
+		if len(protected) > 0 {
+			return changeset, deletionProtectionError(protected)
+		}
+	}
 
 	return changeset, nil
 }
 
 func calculateChanges(templateItem *ResourceItem, platformItem *ResourceItem, preservePaths []string, allowRecreate bool) ([]*Change, error) {
+	// Match array elements by merge key instead of index. As this reorders
+	// arrays of the platform item, its paths need to be collected again.
+	platformItem.Config = alignKeyedArrays("", platformItem.Config, templateItem.Config).(map[string]interface{})
+	platformItem.Paths = []string{}
+	platformItem.walkMap(platformItem.Config, "")
+
+	// Resolve wildcards and element selectors against the current state.
+	preservePaths = resolvePreservePaths(preservePaths, platformItem.Config)
+
 	err := templateItem.prepareForComparisonWithPlatformItem(platformItem, preservePaths)
 	if err != nil {
 		return nil, err
@@ -194,6 +236,19 @@ func calculateChanges(templateItem *ResourceItem, platformItem *ResourceItem, pr
 			default:
 				if templateItemVal == platformItemVal {
 					comparedPaths[path] = true
+				} else if equal, reason := normalizedEqual(path, templateItemVal, platformItemVal); equal {
+					// Use the current representation to avoid false drift.
+					cli.DebugMsg(
+						"Normalized", path, "of", templateItem.ShortName()+":",
+						"desired", formatValue(templateItemVal),
+						"equals current", formatValue(platformItemVal),
+						"("+reason+")",
+					)
+					_, err := pathPointer.Set(templateItem.Config, platformItemVal)
+					if err != nil {
+						return nil, err
+					}
+					comparedPaths[path] = true
 				} else {
 					if templateItem.isImmutableField(path) {
 						if allowRecreate {
@@ -291,6 +346,40 @@ func (c *Changeset) ExactlyOne() bool {
 	return len(c.Create)+len(c.Update)+len(c.Delete) == 1
 }
 
+// Targeted returns the number of existing resources targeted by the
+// changeset, which is the base for percentages given via --max-deletions.
+func (c *Changeset) Targeted() int {
+	return len(c.Noop) + len(c.Update) + len(c.Delete)
+}
+
+// Deletions returns the number of resources which are deleted, not counting
+// resources which are recreated.
+func (c *Changeset) Deletions() int {
+	deletions := 0
+	for _, d := range c.Delete {
+		recreated := false
+		for _, cr := range c.Create {
+			if cr.Kind == d.Kind && cr.Name == d.Name {
+				recreated = true
+			}
+		}
+		if !recreated {
+			deletions++
+		}
+	}
+	return deletions
+}
+
+// Changes returns all changes across Delete, Create, Update in the order in
+// which they are applied.
+func (c *Changeset) Changes() []*Change {
+	changes := []*Change{}
+	changes = append(changes, c.Delete...)
+	changes = append(changes, c.Create...)
+	changes = append(changes, c.Update...)
+	return changes
+}
+
 // Add adds given changes to the changeset.
 func (c *Changeset) Add(changes ...*Change) {
 	for _, change := range changes {
@@ -316,6 +405,113 @@ func (c *Changeset) Add(changes ...*Change) {
 	}
 }
 
+type changesetReport struct {
+	Summary changesetSummary `json:"summary"`
+	Create  []*changeReport  `json:"create"`
+	Update  []*changeReport  `json:"update"`
+	Delete  []*changeReport  `json:"delete"`
+	Noop    []*changeReport  `json:"noop"`
+}
+
+type changesetSummary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+	Noop   int `json:"noop"`
+}
+
+type changeReport struct {
+	Action   string        `json:"action"`
+	Kind     string        `json:"kind"`
+	Name     string        `json:"name"`
+	Redacted bool          `json:"redacted,omitempty"`
+	Changes  []*PathChange `json:"changes"`
+	Error    string        `json:"validationError,omitempty"`
+}
+
+// Invalid returns the changes which failed server-side validation.
+func (c *Changeset) Invalid() []*Change {
+	invalid := []*Change{}
+	for _, change := range c.Changes() {
+		if len(change.ValidationError) > 0 {
+			invalid = append(invalid, change)
+		}
+	}
+	return invalid
+}
+
+// JSON serializes the changeset into a machine-readable format. Values of
+// Secret resources are shown according to secrets.
+func (c *Changeset) JSON(secrets SecretDisplay) ([]byte, error) {
+	r := &changesetReport{
+		Summary: changesetSummary{
+			Create: len(c.Create),
+			Update: len(c.Update),
+			Delete: len(c.Delete),
+			Noop:   len(c.Noop),
+		},
+	}
+	var err error
+	if r.Create, err = newChangeReports(c.Create, secrets); err != nil {
+		return nil, err
+	}
+	if r.Update, err = newChangeReports(c.Update, secrets); err != nil {
+		return nil, err
+	}
+	if r.Delete, err = newChangeReports(c.Delete, secrets); err != nil {
+		return nil, err
+	}
+	if r.Noop, err = newChangeReports(c.Noop, secrets); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func newChangeReports(changes []*Change, secrets SecretDisplay) ([]*changeReport, error) {
+	reports := []*changeReport{}
+	for _, change := range changes {
+		redacted := change.isSecret() && secrets != SecretsRevealed
+		var pathChanges []*PathChange
+		var err error
+		if redacted && secrets == SecretsRedacted {
+			pathChanges, err = change.RedactedPathChanges()
+		} else {
+			pathChanges, err = change.PathChanges()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Could not calculate changes of %s: %s", change.ItemName(), err)
+		}
+		if redacted && secrets == SecretsHidden {
+			for _, pc := range pathChanges {
+				pc.Current = nil
+				pc.Desired = nil
+			}
+		}
+		reports = append(reports, &changeReport{
+			Action:   change.Action,
+			Kind:     change.Kind,
+			Name:     change.Name,
+			Redacted: redacted,
+			Changes:  pathChanges,
+			Error:    change.ValidationError,
+		})
+	}
+	return reports, nil
+}
+
+func deletionProtectionError(itemNames []string) error {
+	return fmt.Errorf(
+		"The following resources are protected by the annotation %s=true, "+
+			"but would need to be deleted (or recreated): %s.\n\n"+
+			"You may pick one of the following options to resolve this:\n\n"+
+			"* pass --allow-protected-deletion to give permission to delete the resources\n"+
+			"* change the templates to be in sync with the cluster state\n"+
+			"* exclude the resources from comparison via --exclude",
+		protectAnnotation,
+		strings.Join(itemNames, ", "),
+	)
+}
+
 func recreateProtectionError(path string, itemName string) error {
 	return fmt.Errorf(
 		"Path '%s' of '%s' is immutable.\n"+
@@ -60,6 +60,8 @@ func TestNewChangesetCreationOfResources(t *testing.T) {
 				upsertOnly,
 				allowRecreate,
 				preservePaths,
+				nil,
+				false,
 			)
 			if err != nil {
 				t.Fatal(err)
@@ -156,7 +158,7 @@ func TestCalculateChangesManagedAnnotations(t *testing.T) {
 			}
 			if len(tc.expectedDiffGoldenFile) > 0 {
 				want := strings.TrimSpace(getGoldenDiff(t, "item-managed-annotations", tc.expectedDiffGoldenFile+".txt"))
-				got := strings.TrimSpace(actualChange.Diff(true))
+				got := strings.TrimSpace(actualChange.Diff(SecretsRevealed))
 				if diff := cmp.Diff(want, got); diff != "" {
 					t.Errorf("Change diff mismatch (-want +got):\n%s", diff)
 				}
@@ -212,7 +214,7 @@ func TestCalculateChangesAppliedConfiguration(t *testing.T) {
 			}
 			actualChange := changes[0]
 			if actualChange.Action != tc.expectedAction {
-				t.Fatalf("Expected change action to be: %s, got: %s. Diff:\n%s", tc.expectedAction, actualChange.Action, actualChange.Diff(true))
+				t.Fatalf("Expected change action to be: %s, got: %s. Diff:\n%s", tc.expectedAction, actualChange.Action, actualChange.Diff(SecretsRevealed))
 			}
 		})
 	}
@@ -251,7 +253,7 @@ func TestCalculateChangesOmittedFields(t *testing.T) {
 			}
 			if len(tc.expectedDiffGoldenFile) > 0 {
 				want := strings.TrimSpace(getGoldenDiff(t, "item-omitted-fields", tc.expectedDiffGoldenFile+".txt"))
-				got := strings.TrimSpace(actualChange.Diff(true))
+				got := strings.TrimSpace(actualChange.Diff(SecretsRevealed))
 				if diff := cmp.Diff(want, got); diff != "" {
 					t.Errorf("Change diff mismatch (-want +got):\n%s", diff)
 				}
@@ -292,7 +294,7 @@ func TestEmptyValuesDoNotCauseDrift(t *testing.T) {
 			}
 			actualChange := changes[0]
 			if actualChange.Action != tc.expectedAction {
-				t.Fatalf("Expected change action to be: %s, got: %s. Diff was: %s", tc.expectedAction, actualChange.Action, actualChange.Diff(false))
+				t.Fatalf("Expected change action to be: %s, got: %s. Diff was: %s", tc.expectedAction, actualChange.Action, actualChange.Diff(SecretsHidden))
 			}
 		})
 	}
@@ -644,7 +646,7 @@ func getChangeset(t *testing.T, filter *ResourceFilter, platformInput, templateI
 	if err != nil {
 		t.Error("Could not create template based list:", err)
 	}
-	changeset, err := NewChangeset(platformBasedList, templateBasedList, upsertOnly, allowRecreate, preservePaths)
+	changeset, err := NewChangeset(platformBasedList, templateBasedList, upsertOnly, allowRecreate, preservePaths, nil, false)
 	if err != nil {
 		t.Error("Could not create changeset:", err)
 	}
@@ -655,3 +657,266 @@ func getGoldenDiff(t *testing.T, folder string, filename string) string {
 	b := helper.ReadGoldenFile(t, folder+"/"+filename)
 	return string(b)
 }
+
+func TestChangesetJSON(t *testing.T) {
+	cs := &Changeset{}
+	cs.Add(
+		&Change{
+			Action:       "Update",
+			Kind:         "Secret",
+			Name:         "foo",
+			CurrentState: "data: {password: czNjcjN0}",
+			DesiredState: "data: {password: bmV3}",
+		},
+		&Change{
+			Action:       "Create",
+			Kind:         "ConfigMap",
+			Name:         "bar",
+			DesiredState: "data: {foo: bar}",
+		},
+	)
+
+	tests := map[string]struct {
+		secrets      SecretDisplay
+		wantContains []string
+		wantMissing  []string
+	}{
+		"Secrets hidden": {
+			secrets:      SecretsHidden,
+			wantContains: []string{`"redacted": true`, `"path": "/data/password"`, `"foo": "bar"`},
+			wantMissing:  []string{"czNjcjN0", "bmV3", "redacted sha256:"},
+		},
+		"Secrets redacted": {
+			secrets:      SecretsRedacted,
+			wantContains: []string{`"redacted": true`, `"path": "/data/password"`, "redacted sha256:", `"foo": "bar"`},
+			wantMissing:  []string{"czNjcjN0", "bmV3"},
+		},
+		"Secrets revealed": {
+			secrets:      SecretsRevealed,
+			wantContains: []string{"czNjcjN0", "bmV3", `"foo": "bar"`},
+			wantMissing:  []string{`"redacted"`},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			b, err := cs.JSON(tc.secrets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(b)
+			for _, w := range tc.wantContains {
+				if !strings.Contains(got, w) {
+					t.Errorf("Want %s in JSON, got:\n%s", w, got)
+				}
+			}
+			for _, w := range tc.wantMissing {
+				if strings.Contains(got, w) {
+					t.Errorf("Want no %s in JSON, got:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateChangesKeyedArrays(t *testing.T) {
+	dc := func(podSpec string) []byte {
+		return []byte(`apiVersion: apps.openshift.io/v1
+kind: DeploymentConfig
+metadata:
+  name: foo
+spec:
+  template:
+    spec: ` + podSpec)
+	}
+	tests := map[string]struct {
+		current      string
+		desired      string
+		wantAction   string
+		wantDiffPart string
+	}{
+		"reordered env vars": {
+			current:    `{containers: [{name: app, env: [{name: A, value: a}, {name: B, value: $(A)}]}]}`,
+			desired:    `{containers: [{name: app, env: [{name: B, value: $(A)}, {name: A, value: a}]}]}`,
+			wantAction: "Update",
+			wantDiffPart: `+        - name: B
++          value: $(A)
+         - name: A
+           value: a
+-        - name: B
+-          value: $(A)
+`,
+		},
+		"reordered init containers": {
+			current:    `{initContainers: [{name: migrate, image: foo}, {name: seed, image: bar}]}`,
+			desired:    `{initContainers: [{name: seed, image: bar}, {name: migrate, image: foo}]}`,
+			wantAction: "Update",
+			wantDiffPart: `+      - image: bar
++        name: seed
+       - image: foo
+         name: migrate
+-      - image: bar
+-        name: seed
+`,
+		},
+		"reordered containers": {
+			current:    `{containers: [{name: app, image: foo}, {name: sidecar, image: bar}]}`,
+			desired:    `{containers: [{name: sidecar, image: bar}, {name: app, image: foo}]}`,
+			wantAction: "Noop",
+		},
+		"inserted container": {
+			current:    `{containers: [{name: app, image: foo}, {name: sidecar, image: bar}]}`,
+			desired:    `{containers: [{name: app, image: foo}, {name: proxy, image: baz}, {name: sidecar, image: bar}]}`,
+			wantAction: "Update",
+			wantDiffPart: `       - image: foo
+         name: app
++      - image: baz
++        name: proxy
+       - image: bar
+         name: sidecar
+`,
+		},
+		"modified mount of reordered volume mounts": {
+			current:    `{containers: [{name: app, volumeMounts: [{mountPath: /a, name: a}, {mountPath: /b, name: b, readOnly: true}]}]}`,
+			desired:    `{containers: [{name: app, volumeMounts: [{mountPath: /b, name: b}, {mountPath: /a, name: a}]}]}`,
+			wantAction: "Update",
+			wantDiffPart: `-          readOnly: true
+         - mountPath: /a
+`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			platformItem := getItem(t, dc(tc.current), "platform")
+			templateItem := getItem(t, dc(tc.desired), "template")
+			changes, err := calculateChanges(templateItem, platformItem, []string{}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changes[0].Action != tc.wantAction {
+				t.Fatalf("Want action %s, got: %s\n%s", tc.wantAction, changes[0].Action, changes[0].Diff(SecretsRevealed))
+			}
+			if diff := changes[0].Diff(SecretsRevealed); !strings.Contains(diff, tc.wantDiffPart) {
+				t.Fatalf("Want diff to contain:\n%s\ngot:\n%s", tc.wantDiffPart, diff)
+			}
+		})
+	}
+}
+
+func TestNewChangesetProtection(t *testing.T) {
+	platformInput := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: PersistentVolumeClaim
+  metadata:
+    name: data
+    annotations:
+      tailor.opendevstack.org/protect: "true"
+  spec:
+    accessModes:
+    - ReadWriteOnce
+    resources:
+      requests:
+        storage: 1Gi
+- apiVersion: v1
+  kind: PersistentVolumeClaim
+  metadata:
+    name: cache
+  spec:
+    accessModes:
+    - ReadWriteOnce
+    resources:
+      requests:
+        storage: 1Gi
+`)
+	tests := map[string]struct {
+		templateInput          []byte
+		allowProtectedDeletion bool
+		wantErr                bool
+	}{
+		"deleting unprotected resource": {
+			templateInput: []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: PersistentVolumeClaim
+  metadata:
+    name: data
+    annotations:
+      tailor.opendevstack.org/protect: "true"
+  spec:
+    accessModes:
+    - ReadWriteOnce
+    resources:
+      requests:
+        storage: 1Gi
+`),
+			wantErr: false,
+		},
+		"deleting protected resource": {
+			templateInput: []byte(`apiVersion: v1
+kind: List
+items: []
+`),
+			wantErr: true,
+		},
+		"deleting protected resource with override": {
+			templateInput: []byte(`apiVersion: v1
+kind: List
+items: []
+`),
+			allowProtectedDeletion: true,
+			wantErr:                false,
+		},
+		"recreating protected resource": {
+			templateInput: []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: PersistentVolumeClaim
+  metadata:
+    name: data
+    annotations:
+      tailor.opendevstack.org/protect: "true"
+  spec:
+    accessModes:
+    - ReadWriteOnce
+    resources:
+      requests:
+        storage: 2Gi
+- apiVersion: v1
+  kind: PersistentVolumeClaim
+  metadata:
+    name: cache
+  spec:
+    accessModes:
+    - ReadWriteOnce
+    resources:
+      requests:
+        storage: 1Gi
+`),
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			filter := &ResourceFilter{Kinds: []string{"PersistentVolumeClaim"}}
+			platformBasedList, err := NewPlatformBasedResourceList(filter, platformInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			templateBasedList, err := NewTemplateBasedResourceList(filter, tc.templateInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = NewChangeset(platformBasedList, templateBasedList, false, true, []string{}, nil, tc.allowProtectedDeletion)
+			if tc.wantErr && err == nil {
+				t.Fatal("Want error, got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("Want no error, got: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package openshift
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+// namedReferenceFields map fields referencing other resources to the kind of
+// the referenced resource and the fields which may hold its name. A secret
+// is named by "secretName" in a volume, but by "name" in a BuildConfig.
+var namedReferenceFields = map[string][]string{
+	"configMap":             {"ConfigMap", "name"},
+	"configMapRef":          {"ConfigMap", "name"},
+	"configMapKeyRef":       {"ConfigMap", "name"},
+	"secret":                {"Secret", "secretName", "name"},
+	"secretRef":             {"Secret", "name"},
+	"secretKeyRef":          {"Secret", "name"},
+	"sourceSecret":          {"Secret", "name"},
+	"pushSecret":            {"Secret", "name"},
+	"pullSecret":            {"Secret", "name"},
+	"persistentVolumeClaim": {"PersistentVolumeClaim", "claimName"},
+}
+
+// orderByDependencies sorts the changes of the changeset so that resources
+// are created and updated after the resources they reference (e.g. a
+// DeploymentConfig after the ConfigMap it mounts, or a Route after its
+// Service), and deleted before them. Apart from that, the order by kind is
+// kept. Dependency cycles are reported, and their members ordered by kind.
+func (c *Changeset) orderByDependencies() {
+	c.Create = orderChanges("creation", c.Create, false)
+	c.Update = orderChanges("update", c.Update, false)
+	c.Delete = orderChanges("deletion", c.Delete, true)
+}
+
+func orderChanges(label string, changes []*Change, reverse bool) []*Change {
+	if len(changes) < 2 {
+		return changes
+	}
+	nodes := make([]*Change, len(changes))
+	copy(nodes, changes)
+	if reverse {
+		reverseChanges(nodes)
+	}
+
+	deps := changeDependencies(nodes)
+	for i, indices := range deps {
+		for _, j := range indices {
+			cli.DebugMsg(nodes[i].ItemName(), "depends on", nodes[j].ItemName())
+		}
+	}
+
+	// Dependencies within a cycle are ignored, so that the members of a cycle
+	// are ordered by kind, as soon as their other dependencies are met.
+	for _, component := range cyclicComponents(deps) {
+		names := []string{}
+		for _, i := range findCycle(deps, component) {
+			names = append(names, nodes[i].ItemName())
+		}
+		cli.PrintYellowf(
+			"Warning: Detected dependency cycle %s, ordering by kind instead.\n",
+			strings.Join(names, " -> "),
+		)
+		for _, i := range component {
+			external := []int{}
+			for _, j := range deps[i] {
+				if !includes(component, j) {
+					external = append(external, j)
+				}
+			}
+			deps[i] = external
+		}
+	}
+
+	ordered := []*Change{}
+	done := make([]bool, len(nodes))
+	for len(ordered) < len(nodes) {
+		for i := range nodes {
+			if !done[i] && allDone(deps[i], done) {
+				done[i] = true
+				ordered = append(ordered, nodes[i])
+				break
+			}
+		}
+	}
+
+	if reverse {
+		reverseChanges(ordered)
+	}
+	names := []string{}
+	for _, change := range ordered {
+		names = append(names, change.ItemName())
+	}
+	cli.DebugMsg("Order of "+label+":", strings.Join(names, ", "))
+	return ordered
+}
+
+// Batches groups given changes, which need to be ordered by dependencies
+// already, into consecutive batches. Changes within one batch do not depend on
+// each other and may therefore be applied concurrently, while each change
+// depends only on changes of earlier batches.
+func Batches(changes []*Change) [][]*Change {
+	deps := changeDependencies(changes)
+	levels := make([]int, len(changes))
+	batches := [][]*Change{}
+	for i, change := range changes {
+		for j := range changes[:i] {
+			if (includes(deps[i], j) || includes(deps[j], i)) && levels[j] >= levels[i] {
+				levels[i] = levels[j] + 1
+			}
+		}
+		if levels[i] == len(batches) {
+			batches = append(batches, []*Change{})
+		}
+		batches[levels[i]] = append(batches[levels[i]], change)
+	}
+	return batches
+}
+
+// DependsOn returns true if change has to be applied after other: if change
+// creates or updates a resource referencing the resource of other, or if
+// change deletes a resource referenced by the resource deleted by other.
+func (c *Change) DependsOn(other *Change) bool {
+	if c.Action == "Delete" {
+		if other.Action != "Delete" {
+			return false
+		}
+		return utils.Includes(resourceReferences(other.CurrentState), c.fullName())
+	}
+	return utils.Includes(resourceReferences(c.DesiredState), other.fullName())
+}
+
+// changeDependencies returns for each change the indices of the changes
+// referenced by it.
+func changeDependencies(changes []*Change) [][]int {
+	index := map[string]int{}
+	for i, change := range changes {
+		index[change.fullName()] = i
+	}
+	deps := make([][]int, len(changes))
+	for i, change := range changes {
+		state := change.DesiredState
+		if change.Action == "Delete" {
+			state = change.CurrentState
+		}
+		for _, ref := range resourceReferences(state) {
+			if j, ok := index[ref]; ok && j != i {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+	return deps
+}
+
+func includes(indices []int, i int) bool {
+	for _, index := range indices {
+		if index == i {
+			return true
+		}
+	}
+	return false
+}
+
+func allDone(indices []int, done []bool) bool {
+	for _, i := range indices {
+		if !done[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// cyclicComponents returns the strongly connected components with more than
+// one node, that is the groups of nodes which depend on each other. Each
+// component is sorted by index.
+func cyclicComponents(deps [][]int) [][]int {
+	index := 0
+	indices := make([]int, len(deps))
+	lowlinks := make([]int, len(deps))
+	visited := make([]bool, len(deps))
+	onStack := make([]bool, len(deps))
+	stack := []int{}
+	components := [][]int{}
+
+	var connect func(v int)
+	connect = func(v int) {
+		visited[v] = true
+		indices[v], lowlinks[v] = index, index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range deps[v] {
+			if !visited[w] {
+				connect(w)
+				if lowlinks[w] < lowlinks[v] {
+					lowlinks[v] = lowlinks[w]
+				}
+			} else if onStack[w] && indices[w] < lowlinks[v] {
+				lowlinks[v] = indices[w]
+			}
+		}
+		if lowlinks[v] == indices[v] {
+			component := []int{}
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			if len(component) > 1 {
+				sort.Ints(component)
+				components = append(components, component)
+			}
+		}
+	}
+	for v := range deps {
+		if !visited[v] {
+			connect(v)
+		}
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i][0] < components[j][0] })
+	return components
+}
+
+// findCycle follows the dependencies within given component from its first
+// node until a node is visited twice, and returns the nodes of that cycle
+// (with the first node repeated at the end).
+func findCycle(deps [][]int, component []int) []int {
+	current := component[0]
+	path := []int{}
+	seen := map[int]int{}
+	for {
+		if pos, ok := seen[current]; ok {
+			return append(path[pos:], current)
+		}
+		seen[current] = len(path)
+		path = append(path, current)
+		for _, j := range deps[current] {
+			if includes(component, j) {
+				current = j
+				break
+			}
+		}
+	}
+}
+
+func reverseChanges(changes []*Change) {
+	for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+		changes[i], changes[j] = changes[j], changes[i]
+	}
+}
+
+// resourceReferences returns the kind/name of all resources referenced in
+// given state, such as ConfigMaps and Secrets mounted by pods, the Service
+// targeted by a Route, or the ServiceAccount bound by a RoleBinding.
+func resourceReferences(state string) []string {
+	if len(state) == 0 {
+		return []string{}
+	}
+	s, err := stateToMap(state)
+	if err != nil {
+		cli.DebugMsg("Could not determine references:", err.Error())
+		return []string{}
+	}
+	refs := map[string]bool{}
+	collectReferences(s, refs, true)
+	references := []string{}
+	for ref := range refs {
+		references = append(references, ref)
+	}
+	sort.Strings(references)
+	return references
+}
+
+func collectReferences(v interface{}, refs map[string]bool, root bool) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		// Object references such as {kind: Service, name: foo}. The
+		// resource itself is skipped as its name is below metadata.
+		if kind, ok := vv["kind"].(string); ok && !root {
+			if name, ok := vv["name"].(string); ok {
+				if kind == "ImageStreamTag" {
+					kind = "ImageStream"
+					name = strings.SplitN(name, ":", 2)[0]
+				}
+				refs[kind+"/"+name] = true
+			}
+		}
+		for k, child := range vv {
+			if field, ok := namedReferenceFields[k]; ok {
+				if m, ok := child.(map[string]interface{}); ok {
+					for _, nameField := range field[1:] {
+						if name, ok := m[nameField].(string); ok {
+							refs[field[0]+"/"+name] = true
+							break
+						}
+					}
+				}
+			}
+			switch k {
+			case "serviceAccountName", "serviceAccount":
+				if name, ok := child.(string); ok {
+					refs["ServiceAccount/"+name] = true
+				}
+			case "imagePullSecrets", "secrets":
+				if list, ok := child.([]interface{}); ok {
+					for _, e := range list {
+						if m, ok := e.(map[string]interface{}); ok {
+							if name, ok := m["name"].(string); ok {
+								refs["Secret/"+name] = true
+							}
+						}
+					}
+				}
+			}
+			collectReferences(child, refs, false)
+		}
+	case []interface{}:
+		for _, child := range vv {
+			collectReferences(child, refs, false)
+		}
+	}
+}
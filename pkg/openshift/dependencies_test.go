@@ -0,0 +1,221 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResourceReferences(t *testing.T) {
+	tests := map[string]struct {
+		state string
+		want  []string
+	}{
+		"Pod template": {
+			state: `kind: DeploymentConfig
+metadata: {name: foo}
+spec:
+  template:
+    spec:
+      serviceAccountName: deployer
+      imagePullSecrets: [{name: registry}]
+      containers:
+      - name: foo
+        envFrom: [{configMapRef: {name: env}}]
+        env:
+        - name: PASSWORD
+          valueFrom: {secretKeyRef: {name: credentials, key: password}}
+      volumes:
+      - {name: config, configMap: {name: config}}
+      - {name: certs, secret: {secretName: certs}}
+      - {name: data, persistentVolumeClaim: {claimName: data}}
+  triggers:
+  - type: ImageChange
+    imageChangeParams:
+      from: {kind: ImageStreamTag, name: "foo:latest"}
+`,
+			want: []string{
+				"ConfigMap/config",
+				"ConfigMap/env",
+				"ImageStream/foo",
+				"PersistentVolumeClaim/data",
+				"Secret/certs",
+				"Secret/credentials",
+				"Secret/registry",
+				"ServiceAccount/deployer",
+			},
+		},
+		"BuildConfig source": {
+			state: `kind: BuildConfig
+metadata: {name: foo}
+spec:
+  source:
+    secrets:
+    - secret: {name: settings}
+      destinationDir: settings
+    configMaps:
+    - configMap: {name: settings}
+      destinationDir: config
+    sourceSecret: {name: git}
+`,
+			want: []string{"ConfigMap/settings", "Secret/git", "Secret/settings"},
+		},
+		"Route": {
+			state: `kind: Route
+metadata: {name: foo}
+spec:
+  to: {kind: Service, name: foo}
+  alternateBackends: [{kind: Service, name: bar}]
+`,
+			want: []string{"Service/bar", "Service/foo"},
+		},
+		"RoleBinding": {
+			state: `kind: RoleBinding
+metadata: {name: foo}
+roleRef: {kind: Role, name: editor}
+subjects: [{kind: ServiceAccount, name: deployer}]
+`,
+			want: []string{"Role/editor", "ServiceAccount/deployer"},
+		},
+		"No references": {
+			state: "kind: ConfigMap\nmetadata: {name: foo}\ndata: {name: bar}\n",
+			want:  []string{},
+		},
+		"Empty state": {
+			state: "",
+			want:  []string{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := resourceReferences(tc.state)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("References mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOrderChanges(t *testing.T) {
+	route := "kind: Route\nspec: {to: {kind: Service, name: foo}}\n"
+	service := "kind: Service\nspec: {selector: {app: foo}}\n"
+	dc := "kind: DeploymentConfig\nspec: {template: {spec: {volumes: [{configMap: {name: foo}}]}}}\n"
+	configMap := "kind: ConfigMap\ndata: {foo: bar}\n"
+	cycleA := "kind: ConfigMap\nspec: {ref: {kind: ConfigMap, name: b}}\n"
+	cycleB := "kind: ConfigMap\nspec: {ref: {kind: ConfigMap, name: a}}\n"
+	tests := map[string]struct {
+		action  string
+		changes []*Change
+		want    []string
+	}{
+		"Create referenced resources first": {
+			action: "Create",
+			changes: []*Change{
+				{Kind: "Route", Name: "foo", DesiredState: route},
+				{Kind: "DeploymentConfig", Name: "foo", DesiredState: dc},
+				{Kind: "Service", Name: "foo", DesiredState: service},
+				{Kind: "ConfigMap", Name: "foo", DesiredState: configMap},
+			},
+			want: []string{"Service/foo", "Route/foo", "ConfigMap/foo", "DeploymentConfig/foo"},
+		},
+		"Delete referencing resources first": {
+			action: "Delete",
+			changes: []*Change{
+				{Kind: "Service", Name: "foo", CurrentState: service},
+				{Kind: "ConfigMap", Name: "foo", CurrentState: configMap},
+				{Kind: "Route", Name: "foo", CurrentState: route},
+				{Kind: "DeploymentConfig", Name: "foo", CurrentState: dc},
+			},
+			want: []string{"Route/foo", "Service/foo", "DeploymentConfig/foo", "ConfigMap/foo"},
+		},
+		"Keep order without references": {
+			action: "Create",
+			changes: []*Change{
+				{Kind: "ConfigMap", Name: "foo", DesiredState: configMap},
+				{Kind: "Service", Name: "foo", DesiredState: service},
+			},
+			want: []string{"ConfigMap/foo", "Service/foo"},
+		},
+		"Order cycle members by kind as soon as possible": {
+			action: "Create",
+			changes: []*Change{
+				{Kind: "ConfigMap", Name: "a", DesiredState: cycleA},
+				{Kind: "ConfigMap", Name: "b", DesiredState: cycleB},
+				{Kind: "Route", Name: "foo", DesiredState: route},
+				{Kind: "Service", Name: "foo", DesiredState: service},
+			},
+			want: []string{"ConfigMap/a", "ConfigMap/b", "Service/foo", "Route/foo"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, c := range tc.changes {
+				c.Action = tc.action
+			}
+			ordered := orderChanges("test", tc.changes, tc.action == "Delete")
+			got := []string{}
+			for _, c := range ordered {
+				got = append(got, c.fullName())
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Order mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBatches(t *testing.T) {
+	route := "kind: Route\nspec: {to: {kind: Service, name: foo}}\n"
+	service := "kind: Service\nspec: {selector: {app: foo}}\n"
+	configMap := "kind: ConfigMap\ndata: {foo: bar}\n"
+	tests := map[string]struct {
+		action  string
+		changes []*Change
+		want    [][]string
+	}{
+		"Independent changes": {
+			action: "Create",
+			changes: []*Change{
+				{Kind: "ConfigMap", Name: "foo", DesiredState: configMap},
+				{Kind: "Service", Name: "foo", DesiredState: service},
+			},
+			want: [][]string{{"ConfigMap/foo", "Service/foo"}},
+		},
+		"Dependent changes": {
+			action: "Create",
+			changes: []*Change{
+				{Kind: "ConfigMap", Name: "foo", DesiredState: configMap},
+				{Kind: "Service", Name: "foo", DesiredState: service},
+				{Kind: "Route", Name: "foo", DesiredState: route},
+			},
+			want: [][]string{{"ConfigMap/foo", "Service/foo"}, {"Route/foo"}},
+		},
+		"Dependent deletions": {
+			action: "Delete",
+			changes: []*Change{
+				{Kind: "Route", Name: "foo", CurrentState: route},
+				{Kind: "Service", Name: "foo", CurrentState: service},
+				{Kind: "ConfigMap", Name: "foo", CurrentState: configMap},
+			},
+			want: [][]string{{"Route/foo", "ConfigMap/foo"}, {"Service/foo"}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, c := range tc.changes {
+				c.Action = tc.action
+			}
+			got := [][]string{}
+			for _, batch := range Batches(tc.changes) {
+				names := []string{}
+				for _, c := range batch {
+					names = append(names, c.fullName())
+				}
+				got = append(got, names)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Batches mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -19,12 +19,63 @@ var (
 
 // ExportAsTemplateFile exports resources in template format.
 func ExportAsTemplateFile(filter *ResourceFilter, withAnnotations bool, namespace string, withHardcodedNamespace bool, trimAnnotations []string, ocClient cli.OcClientExporter) (string, error) {
+	items, err := exportItems(filter, withAnnotations, namespace, withHardcodedNamespace, trimAnnotations, ocClient)
+	if err != nil || items == nil {
+		return "", err
+	}
+	return exportTemplate(items, withHardcodedNamespace)
+}
+
+// ExportAsTemplateFiles exports resources in template format, with one
+// template per group. Resources are grouped by their "app" label (groupBy
+// "app", with resources without label in group "_unlabelled") or by their
+// kind (groupBy "kind"). The templates are keyed by group name.
+func ExportAsTemplateFiles(filter *ResourceFilter, withAnnotations bool, namespace string, withHardcodedNamespace bool, trimAnnotations []string, groupBy string, ocClient cli.OcClientExporter) (map[string]string, error) {
+	items, err := exportItems(filter, withAnnotations, namespace, withHardcodedNamespace, trimAnnotations, ocClient)
+	if err != nil {
+		return nil, err
+	}
+	groups := map[string][]*ResourceItem{}
+	for _, i := range items {
+		// Labels matching the namespace have been parameterized already.
+		group := strings.Replace(exportGroup(i, groupBy), "${TAILOR_NAMESPACE}", namespace, -1)
+		groups[group] = append(groups[group], i)
+	}
+	templates := map[string]string{}
+	for group, groupItems := range groups {
+		t, err := exportTemplate(groupItems, withHardcodedNamespace)
+		if err != nil {
+			return nil, err
+		}
+		templates[group] = t
+	}
+	return templates, nil
+}
+
+// unlabelledGroup is the group of resources without app label. Label values
+// cannot start with an underscore, so it does not collide with any app.
+const unlabelledGroup = "_unlabelled"
+
+// exportGroup returns the name of the group given item is exported in.
+func exportGroup(i *ResourceItem, groupBy string) string {
+	if groupBy == "kind" {
+		return strings.ToLower(i.Kind)
+	}
+	if app, ok := i.Labels["app"].(string); ok && len(app) > 0 {
+		return app
+	}
+	return unlabelledGroup
+}
+
+// exportItems exports the resources matching filter, with the namespace
+// replaced by a parameter and annotations trimmed as requested.
+func exportItems(filter *ResourceFilter, withAnnotations bool, namespace string, withHardcodedNamespace bool, trimAnnotations []string, ocClient cli.OcClientExporter) ([]*ResourceItem, error) {
 	outBytes, err := ocClient.Export(filter.ConvertToKinds(), filter.Label)
 	if err != nil {
-		return "", fmt.Errorf("Could not export %s resources: %s", filter.String(), err)
+		return nil, fmt.Errorf("Could not export %s resources: %s", filter.String(), err)
 	}
 	if len(outBytes) == 0 {
-		return "", nil
+		return nil, nil
 	}
 
 	if !withHardcodedNamespace {
@@ -39,10 +90,10 @@ func ExportAsTemplateFile(filter *ResourceFilter, withAnnotations bool, namespac
 
 	list, err := NewPlatformBasedResourceList(filter, outBytes)
 	if err != nil {
-		return "", fmt.Errorf("Could not create resource list from export: %s", err)
+		return nil, fmt.Errorf("Could not create resource list from export: %s", err)
 	}
 
-	objects := []map[string]interface{}{}
+	items := []*ResourceItem{}
 	for _, i := range list.Items {
 		if withAnnotations {
 			cli.DebugMsg("All annotations will be kept in template item")
@@ -59,6 +110,15 @@ func ExportAsTemplateFile(filter *ResourceFilter, withAnnotations bool, namespac
 				}
 			}
 		}
+		items = append(items, i)
+	}
+	return items, nil
+}
+
+// exportTemplate returns a template containing given items.
+func exportTemplate(items []*ResourceItem, withHardcodedNamespace bool) (string, error) {
+	objects := []map[string]interface{}{}
+	for _, i := range items {
 		objects = append(objects, i.Config)
 	}
 
@@ -3,6 +3,7 @@ package openshift
 import (
 	"testing"
 
+	"github.com/ghodss/yaml"
 	"github.com/google/go-cmp/cmp"
 	"github.com/opendevstack/tailor/internal/test/helper"
 )
@@ -114,3 +115,60 @@ func TestExportAsTemplateFile(t *testing.T) {
 		})
 	}
 }
+
+func TestExportAsTemplateFiles(t *testing.T) {
+	tests := map[string]struct {
+		groupBy   string
+		wantNames map[string][]string
+	}{
+		"Group by app": {
+			groupBy: "app",
+			wantNames: map[string][]string{
+				"db":          {"db"},
+				"foo":         {"foo", "foo"},
+				"_unlabelled": {"settings"},
+			},
+		},
+		"Group by kind": {
+			groupBy: "kind",
+			wantNames: map[string][]string{
+				"buildconfig": {"foo"},
+				"configmap":   {"settings"},
+				"imagestream": {"foo"},
+				"service":     {"db"},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &mockOcExportClient{t: t, fixture: "mixed.yml"}
+			filter := newResourceFilterOrFatal(t, "is,bc,svc,cm", "", []string{})
+			templates, err := ExportAsTemplateFiles(filter, false, "foo", true, []string{}, tc.groupBy, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actualNames := map[string][]string{}
+			for group, template := range templates {
+				var tmpl struct {
+					Objects []struct {
+						Metadata struct {
+							Name string `json:"name"`
+						} `json:"metadata"`
+					} `json:"objects"`
+				}
+				if err := yaml.Unmarshal([]byte(template), &tmpl); err != nil {
+					t.Fatal(err)
+				}
+				for _, o := range tmpl.Objects {
+					actualNames[group] = append(actualNames[group], o.Metadata.Name)
+				}
+			}
+
+			if diff := cmp.Diff(tc.wantNames, actualNames); diff != "" {
+				t.Fatalf("Exported templates mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/xeipuuv/gojsonpointer"
 )
 
+const protectAnnotation = "tailor.opendevstack.org/protect"
+
 var (
 	annotationsPath             = "/metadata/annotations"
 	platformManagedSimpleFields = []string{
@@ -89,6 +91,7 @@ type ResourceItem struct {
 	Source                   string
 	Kind                     string
 	Name                     string
+	ResourceVersion          string
 	Labels                   map[string]interface{}
 	Annotations              map[string]interface{}
 	Paths                    []string
@@ -116,6 +119,12 @@ func (i *ResourceItem) ShortName() string {
 	return kindToShortMapping[i.Kind] + "/" + i.Name
 }
 
+// IsProtected returns true if the item is annotated to be protected against
+// deletion.
+func (i *ResourceItem) IsProtected() bool {
+	return i.Annotations[protectAnnotation] == "true"
+}
+
 func (i *ResourceItem) HasLabel(label string) bool {
 	labelParts := strings.Split(label, "=")
 	if _, ok := i.Labels[labelParts[0]]; !ok {
@@ -162,6 +171,15 @@ func (i *ResourceItem) parseConfig(m map[string]interface{}) error {
 		i.Name = generateName.(string)
 	}
 
+	// Extract resource version (only present in platform items)
+	resourceVersionPointer, _ := gojsonpointer.NewJsonPointer("/metadata/resourceVersion")
+	resourceVersion, _, err := resourceVersionPointer.Get(m)
+	if err == nil {
+		if rv, ok := resourceVersion.(string); ok {
+			i.ResourceVersion = rv
+		}
+	}
+
 	// Determine if item is comparable and therefore relevant for Tailor
 	i.Comparable = true
 	// Secrets of type "kubernetes.io/dockercfg" and
@@ -0,0 +1,123 @@
+package openshift
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+var (
+	// Kinds which are not built-in are created after, and deleted before,
+	// all built-in kinds (see kindOrder).
+	registeredKindOrder = "s"
+	// clusterScopedKinds maps aliases to kinds which cannot be managed as
+	// they do not live in a namespace.
+	clusterScopedKinds = map[string]string{}
+	// The kinds are registered once per process, see RegisterKinds.
+	registerKindsOnce sync.Once
+	registerKindsErr  error
+)
+
+// RegisterKinds adds the given kinds to the kinds managed by default. Each of
+// those is either a known kind or alias (e.g. "netpol"), or a kind with
+// optional aliases in the form "Kind:alias:alias" (which is useful if the
+// cluster cannot be reached). Only if kinds beyond the built-in ones are
+// given, the resources served by the cluster are discovered via discover, so
+// that they can be referenced (by kind, plural or short name) in filters and
+// preserve paths. The kinds are registered once per process, later calls
+// return the outcome of the first one, so it is safe to call concurrently.
+func RegisterKinds(kinds []string, discover func() []cli.APIResource) error {
+	registerKindsOnce.Do(func() {
+		apiResources := []cli.APIResource{}
+		if requiresDiscovery(kinds) {
+			apiResources = discover()
+		}
+		registerKindsErr = registerKinds(apiResources, kinds)
+	})
+	return registerKindsErr
+}
+
+// requiresDiscovery returns true if any of given kinds is not built-in.
+func requiresDiscovery(kinds []string) bool {
+	for _, k := range kinds {
+		k = strings.TrimSpace(k)
+		if len(k) == 0 {
+			continue
+		}
+		if _, ok := KindMapping[strings.ToLower(strings.Split(k, ":")[0])]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func registerKinds(apiResources []cli.APIResource, kinds []string) error {
+	for _, r := range apiResources {
+		if len(r.Kind) == 0 {
+			continue
+		}
+		aliases := append([]string{r.Name}, r.ShortNames...)
+		if !r.Namespaced {
+			for _, alias := range append(aliases, r.Kind) {
+				alias = strings.ToLower(alias)
+				_, known := KindMapping[alias]
+				if _, ok := clusterScopedKinds[alias]; !ok && !known {
+					clusterScopedKinds[alias] = r.Kind
+				}
+			}
+			continue
+		}
+		registerKind(r.Kind, aliases...)
+	}
+
+	for _, k := range kinds {
+		k = strings.TrimSpace(k)
+		if len(k) == 0 {
+			continue
+		}
+		parts := strings.Split(k, ":")
+		kind, ok := KindMapping[strings.ToLower(parts[0])]
+		if !ok {
+			if scopedKind, ok := clusterScopedKinds[strings.ToLower(parts[0])]; ok {
+				return fmt.Errorf("Kind %s is cluster-scoped and cannot be managed", scopedKind)
+			}
+			if !unicode.IsUpper([]rune(parts[0])[0]) {
+				return fmt.Errorf("Unknown kind %s, specify it as Kind (e.g. NetworkPolicy)", parts[0])
+			}
+			kind = parts[0]
+		}
+		registerKind(kind, parts[1:]...)
+		if !utils.Includes(availableKinds, kind) {
+			cli.DebugMsg("Managing additional kind", kind)
+			availableKinds = append(availableKinds, kind)
+		}
+	}
+	return nil
+}
+
+// registerKind makes kind known under its lowercase name and given aliases.
+// Existing aliases are never overwritten, so built-in kinds take precedence.
+func registerKind(kind string, aliases ...string) {
+	for _, alias := range append([]string{kind}, aliases...) {
+		alias = strings.ToLower(alias)
+		if _, ok := KindMapping[alias]; !ok && len(alias) > 0 {
+			KindMapping[alias] = kind
+		}
+	}
+	if _, ok := kindToShortMapping[kind]; !ok {
+		short := strings.ToLower(kind)
+		for _, alias := range aliases {
+			if KindMapping[strings.ToLower(alias)] == kind && len(alias) < len(short) {
+				short = strings.ToLower(alias)
+			}
+		}
+		kindToShortMapping[kind] = short
+	}
+	if _, ok := kindOrder[kind]; !ok {
+		kindOrder[kind] = registeredKindOrder
+	}
+}
@@ -0,0 +1,171 @@
+package openshift
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+// resetKindsAfterTest restores the built-in kinds once the test is done.
+func resetKindsAfterTest(t *testing.T) {
+	copyMap := func(m map[string]string) map[string]string {
+		c := map[string]string{}
+		for k, v := range m {
+			c[k] = v
+		}
+		return c
+	}
+	savedKindMapping := copyMap(KindMapping)
+	savedKindToShortMapping := copyMap(kindToShortMapping)
+	savedKindOrder := copyMap(kindOrder)
+	savedClusterScopedKinds := copyMap(clusterScopedKinds)
+	savedAvailableKinds := append([]string{}, availableKinds...)
+	registerKindsOnce = sync.Once{}
+	t.Cleanup(func() {
+		registerKindsOnce = sync.Once{}
+		KindMapping = savedKindMapping
+		kindToShortMapping = savedKindToShortMapping
+		kindOrder = savedKindOrder
+		clusterScopedKinds = savedClusterScopedKinds
+		availableKinds = savedAvailableKinds
+	})
+}
+
+func TestRegisterKinds(t *testing.T) {
+	apiResources := []cli.APIResource{
+		{Kind: "NetworkPolicy", Name: "networkpolicies", GroupVersion: "networking.k8s.io/v1", ShortNames: []string{"netpol"}, Namespaced: true},
+		{Kind: "Role", Name: "roles", GroupVersion: "rbac.authorization.k8s.io/v1", Namespaced: true},
+		{Kind: "ImageStreamMirror", Name: "imagestreammirrors", GroupVersion: "example.com/v1", ShortNames: []string{"is"}, Namespaced: true},
+		{Kind: "ClusterRole", Name: "clusterroles", GroupVersion: "rbac.authorization.k8s.io/v1", Namespaced: false},
+	}
+
+	tests := map[string]struct {
+		kinds          []string
+		kindArg        string
+		wantFilter     []string
+		wantConverted  string
+		wantShortName  string
+		wantErr        string
+		checkShortKind string
+	}{
+		"discovered kind by short name": {
+			kinds:          []string{},
+			kindArg:        "netpol,role",
+			wantFilter:     []string{"NetworkPolicy", "Role"},
+			wantConverted:  "NetworkPolicy,Role",
+			checkShortKind: "NetworkPolicy",
+			wantShortName:  "netpol",
+		},
+		"built-in short names take precedence": {
+			kinds:         []string{},
+			kindArg:       "is",
+			wantFilter:    []string{"ImageStream"},
+			wantConverted: "ImageStream",
+		},
+		"managed kinds are targeted by default": {
+			kinds:         []string{"netpol", "Role"},
+			wantFilter:    []string{},
+			wantConverted: strings.Join(append(append([]string{}, availableKinds...), "NetworkPolicy", "Role"), ","),
+		},
+		"configured kind with alias": {
+			kinds:          []string{"PodDisruptionBudget:pdb"},
+			kindArg:        "pdb",
+			wantFilter:     []string{"PodDisruptionBudget"},
+			wantConverted:  "PodDisruptionBudget",
+			checkShortKind: "PodDisruptionBudget",
+			wantShortName:  "pdb",
+		},
+		"cluster-scoped kind": {
+			kinds:   []string{"clusterroles"},
+			wantErr: "Kind ClusterRole is cluster-scoped and cannot be managed",
+		},
+		"unknown lowercase kind": {
+			kinds:   []string{"foo"},
+			wantErr: "Unknown kind foo",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resetKindsAfterTest(t)
+			err := registerKinds(apiResources, tc.kinds)
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Want error %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := NewResourceFilter(tc.kindArg, "", []string{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantFilter, filter.Kinds); diff != "" {
+				t.Fatalf("Filter kinds mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantConverted, filter.ConvertToKinds()); diff != "" {
+				t.Fatalf("Converted kinds mismatch (-want +got):\n%s", diff)
+			}
+			if len(tc.checkShortKind) > 0 {
+				item := &ResourceItem{Kind: tc.checkShortKind, Name: "foo"}
+				if want := tc.wantShortName + "/foo"; item.ShortName() != want {
+					t.Fatalf("Want short name %s, got: %s", want, item.ShortName())
+				}
+				if kindOrder[tc.checkShortKind] <= kindOrder["Route"] {
+					t.Fatalf("Want %s to be ordered after built-in kinds", tc.checkShortKind)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterKindsOnce(t *testing.T) {
+	tests := map[string]struct {
+		kinds        []string
+		wantDiscover bool
+	}{
+		"built-in kinds": {
+			kinds:        []string{"Route", "svc"},
+			wantDiscover: false,
+		},
+		"additional kind": {
+			kinds:        []string{"Route", "netpol"},
+			wantDiscover: true,
+		},
+		"additional kind with alias": {
+			kinds:        []string{"PodDisruptionBudget:pdb"},
+			wantDiscover: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resetKindsAfterTest(t)
+			discovered := 0
+			discover := func() []cli.APIResource {
+				discovered++
+				return []cli.APIResource{
+					{Kind: "NetworkPolicy", Name: "networkpolicies", ShortNames: []string{"netpol"}, Namespaced: true},
+				}
+			}
+			err := RegisterKinds(tc.kinds, discover)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Registering again has no effect.
+			err = RegisterKinds([]string{"foo"}, discover)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (discovered > 0) != tc.wantDiscover {
+				t.Fatalf("Want discovery to be %v, got %d discoveries", tc.wantDiscover, discovered)
+			}
+			if discovered > 1 {
+				t.Fatalf("Want at most one discovery, got: %d", discovered)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package openshift
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// mergeKeys are used to match elements of arrays of objects, similar to
+// strategic merge patch, e.g. containers by "name", ports by "containerPort"
+// and volume mounts by "mountPath".
+var mergeKeys = []string{"name", "containerPort", "mountPath"}
+
+// unorderedArrays are the fields holding arrays in which the order of
+// elements does not matter. Other arrays, such as initContainers (which run
+// in sequence) or env (which may reference earlier entries via $(VAR)), are
+// compared by index so that reordering them is reported as drift.
+var unorderedArrays = map[string]bool{
+	"containers":    true,
+	"ports":         true,
+	"volumeMounts":  true,
+	"volumeDevices": true,
+	"volumes":       true,
+}
+
+// mergeKeyFor returns the merge key shared by all elements of both arrays
+// held by field. A key qualifies if each element is an object with a unique
+// scalar value for it. If the order of field matters or no key qualifies,
+// the arrays are compared by index.
+func mergeKeyFor(field string, current, desired []interface{}) (string, bool) {
+	if !unorderedArrays[field] || len(current) == 0 || len(desired) == 0 {
+		return "", false
+	}
+	for _, key := range mergeKeys {
+		if hasUniqueKey(current, key) && hasUniqueKey(desired, key) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func hasUniqueKey(a []interface{}, key string) bool {
+	seen := map[string]bool{}
+	for _, e := range a {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		v, ok := m[key]
+		if !ok {
+			return false
+		}
+		switch v.(type) {
+		case map[string]interface{}, []interface{}, nil:
+			return false
+		}
+		k := fmt.Sprintf("%v", v)
+		if seen[k] {
+			return false
+		}
+		seen[k] = true
+	}
+	return true
+}
+
+// mergeKeyValue returns the value of key of the array element e as a string.
+func mergeKeyValue(e interface{}, key string) string {
+	return fmt.Sprintf("%v", e.(map[string]interface{})[key])
+}
+
+// fieldOf returns the name of the field a JSON pointer path refers to.
+func fieldOf(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// alignKeyedArrays reorders all arrays in current which can be matched by a
+// merge key with the corresponding array in desired: matching elements are
+// moved into the order of desired, followed by elements only present in
+// current. As a consequence, reordering elements does not cause drift, and
+// an insertion only shows up as such. field is the name of the field holding
+// current, if any.
+func alignKeyedArrays(field string, current, desired interface{}) interface{} {
+	switch c := current.(type) {
+	case map[string]interface{}:
+		if d, ok := desired.(map[string]interface{}); ok {
+			for k, cv := range c {
+				if dv, ok := d[k]; ok {
+					c[k] = alignKeyedArrays(k, cv, dv)
+				}
+			}
+		}
+	case []interface{}:
+		d, ok := desired.([]interface{})
+		if !ok {
+			return c
+		}
+		key, ok := mergeKeyFor(field, c, d)
+		if !ok {
+			for i := 0; i < len(c) && i < len(d); i++ {
+				c[i] = alignKeyedArrays("", c[i], d[i])
+			}
+			return c
+		}
+		currentByKey := map[string]interface{}{}
+		for _, e := range c {
+			currentByKey[mergeKeyValue(e, key)] = e
+		}
+		aligned := []interface{}{}
+		matched := map[string]bool{}
+		for _, de := range d {
+			k := mergeKeyValue(de, key)
+			if ce, ok := currentByKey[k]; ok {
+				aligned = append(aligned, alignKeyedArrays("", ce, de))
+				matched[k] = true
+			}
+		}
+		for _, ce := range c {
+			if !matched[mergeKeyValue(ce, key)] {
+				aligned = append(aligned, ce)
+			}
+		}
+		return aligned
+	}
+	return current
+}
+
+// diffKeyedArrays compares arrays by merge key. Paths of modified or added
+// elements refer to the index in desired, paths of removed elements to the
+// index in current.
+func diffKeyedArrays(path string, key string, current, desired []interface{}) []*PathChange {
+	pathChanges := []*PathChange{}
+	currentIndexByKey := map[string]int{}
+	for i, e := range current {
+		currentIndexByKey[mergeKeyValue(e, key)] = i
+	}
+	matched := map[string]bool{}
+	for j, de := range desired {
+		p := path + "/" + strconv.Itoa(j)
+		k := mergeKeyValue(de, key)
+		i, ok := currentIndexByKey[k]
+		if !ok {
+			pathChanges = append(pathChanges, &PathChange{Op: "add", Path: p, Desired: de})
+			continue
+		}
+		matched[k] = true
+		if !reflect.DeepEqual(current[i], de) {
+			pathChanges = append(pathChanges, diffValues(p, current[i], de)...)
+		}
+	}
+	for i, ce := range current {
+		if !matched[mergeKeyValue(ce, key)] {
+			p := path + "/" + strconv.Itoa(i)
+			pathChanges = append(pathChanges, &PathChange{Op: "remove", Path: p, Current: ce})
+		}
+	}
+	return pathChanges
+}
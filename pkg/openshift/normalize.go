@@ -0,0 +1,136 @@
+package openshift
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// quantityPaths are paths of fields holding Kubernetes resource
+	// quantities, such as "500m" CPU or "1Gi" memory.
+	quantityPaths = []*regexp.Regexp{
+		regexp.MustCompile(`/resources/(limits|requests)/[^/]+$`),
+		regexp.MustCompile(`^/spec/hard/[^/]+$`),
+		regexp.MustCompile(`^/spec/limits/[0-9]+/(max|min|default|defaultRequest|maxLimitRequestRatio)/[^/]+$`),
+		regexp.MustCompile(`/emptyDir/sizeLimit$`),
+	}
+	// scalarPaths are paths of fields holding a number or boolean, for which
+	// a string representation is accepted as well. Fields which may hold
+	// either a string or a number (such as targetPort) must not be listed.
+	scalarPaths = []*regexp.Regexp{
+		regexp.MustCompile(`/ports/[0-9]+/(port|containerPort|hostPort|nodePort)$`),
+		regexp.MustCompile(`/(replicas|minReplicas|maxReplicas|targetCPUUtilizationPercentage)$`),
+		regexp.MustCompile(`/(revisionHistoryLimit|minReadySeconds|progressDeadlineSeconds|activeDeadlineSeconds|terminationGracePeriodSeconds)$`),
+		regexp.MustCompile(`/(successfulJobsHistoryLimit|failedJobsHistoryLimit|backoffLimit|completions|parallelism)$`),
+		regexp.MustCompile(`/(initialDelaySeconds|timeoutSeconds|periodSeconds|successThreshold|failureThreshold)$`),
+		regexp.MustCompile(`/(runAsUser|runAsGroup|fsGroup|defaultMode|mode)$`),
+		regexp.MustCompile(`/(local|suspend|paused|readOnly|privileged|runAsNonRoot|readOnlyRootFilesystem|allowPrivilegeEscalation|stdin|tty|automountServiceAccountToken|immutable)$`),
+	}
+	quantityRegex = regexp.MustCompile(`^([+-]?[0-9.]+(?:[eE][+-]?[0-9]+)?)(Ki|Mi|Gi|Ti|Pi|Ei|n|u|m|k|M|G|T|P|E)?$`)
+
+	quantityMultipliers = map[string]*big.Rat{
+		"":   big.NewRat(1, 1),
+		"n":  big.NewRat(1, 1000000000),
+		"u":  big.NewRat(1, 1000000),
+		"m":  big.NewRat(1, 1000),
+		"k":  new(big.Rat).SetInt64(1000),
+		"M":  new(big.Rat).SetInt64(1000 * 1000),
+		"G":  new(big.Rat).SetInt64(1000 * 1000 * 1000),
+		"T":  new(big.Rat).SetInt64(1000 * 1000 * 1000 * 1000),
+		"P":  new(big.Rat).SetInt64(1000 * 1000 * 1000 * 1000 * 1000),
+		"E":  new(big.Rat).SetInt64(1000 * 1000 * 1000 * 1000 * 1000 * 1000),
+		"Ki": new(big.Rat).SetInt64(1 << 10),
+		"Mi": new(big.Rat).SetInt64(1 << 20),
+		"Gi": new(big.Rat).SetInt64(1 << 30),
+		"Ti": new(big.Rat).SetInt64(1 << 40),
+		"Pi": new(big.Rat).SetInt64(1 << 50),
+		"Ei": new(big.Rat).SetInt64(1 << 60),
+	}
+)
+
+// normalizedEqual returns true if desired and current value at path differ
+// in representation only, e.g. "0.5" and "500m" CPU, "1Gi" and "1024Mi"
+// memory, or "80" and 80 port, or "true" and true. Strings are only compared
+// to numbers and booleans at scalarPaths. The returned reason explains the
+// normalization which was applied.
+func normalizedEqual(path string, desired, current interface{}) (bool, string) {
+	if matchesPath(quantityPaths, path) {
+		d, dOk := parseQuantity(desired)
+		c, cOk := parseQuantity(current)
+		if dOk && cOk && d.Cmp(c) == 0 {
+			return true, "equal quantities"
+		}
+	}
+	if !matchesPath(scalarPaths, path) {
+		return false, ""
+	}
+	ds, dIsString := desired.(string)
+	cs, cIsString := current.(string)
+	if dIsString == cIsString {
+		return false, ""
+	}
+	s, other := ds, current
+	if cIsString {
+		s, other = cs, desired
+	}
+	switch o := other.(type) {
+	case float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err == nil && f == o {
+			return true, "string and number"
+		}
+	case int:
+		i, err := strconv.Atoi(s)
+		if err == nil && i == o {
+			return true, "string and number"
+		}
+	case bool:
+		if s == strconv.FormatBool(o) {
+			return true, "string and boolean"
+		}
+	}
+	return false, ""
+}
+
+func matchesPath(paths []*regexp.Regexp, path string) bool {
+	for _, re := range paths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuantity parses a Kubernetes resource quantity into an exact number.
+func parseQuantity(v interface{}) (*big.Rat, bool) {
+	var s string
+	switch vv := v.(type) {
+	case string:
+		s = strings.TrimSpace(vv)
+	case float64:
+		s = strconv.FormatFloat(vv, 'f', -1, 64)
+	case int:
+		s = strconv.Itoa(vv)
+	default:
+		return nil, false
+	}
+	match := quantityRegex.FindStringSubmatch(s)
+	if match == nil {
+		return nil, false
+	}
+	number, ok := new(big.Rat).SetString(match[1])
+	if !ok {
+		return nil, false
+	}
+	return number.Mul(number, quantityMultipliers[match[2]]), true
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprintf("%v", v)
+}
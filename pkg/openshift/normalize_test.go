@@ -0,0 +1,154 @@
+package openshift
+
+import (
+	"testing"
+)
+
+func TestNormalizedEqual(t *testing.T) {
+	tests := map[string]struct {
+		path    string
+		desired interface{}
+		current interface{}
+		want    bool
+	}{
+		"decimal and milli CPU": {
+			path:    "/spec/template/spec/containers/0/resources/requests/cpu",
+			desired: 0.5,
+			current: "500m",
+			want:    true,
+		},
+		"whole and milli CPU": {
+			path:    "/spec/template/spec/containers/0/resources/limits/cpu",
+			desired: "1",
+			current: "1000m",
+			want:    true,
+		},
+		"binary memory units": {
+			path:    "/spec/template/spec/containers/0/resources/limits/memory",
+			desired: "1Gi",
+			current: "1024Mi",
+			want:    true,
+		},
+		"different memory": {
+			path:    "/spec/template/spec/containers/0/resources/limits/memory",
+			desired: "1G",
+			current: "1Gi",
+			want:    false,
+		},
+		"storage request": {
+			path:    "/spec/resources/requests/storage",
+			desired: "2Gi",
+			current: "2048Mi",
+			want:    true,
+		},
+		"quota": {
+			path:    "/spec/hard/requests.cpu",
+			desired: "2",
+			current: "2000m",
+			want:    true,
+		},
+		"quantity outside of quantity path": {
+			path:    "/data/size",
+			desired: "1Gi",
+			current: "1024Mi",
+			want:    false,
+		},
+		"string and number": {
+			path:    "/spec/ports/0/port",
+			desired: "80",
+			current: float64(80),
+			want:    true,
+		},
+		"string and different number": {
+			path:    "/spec/ports/0/port",
+			desired: "8080",
+			current: float64(80),
+			want:    false,
+		},
+		"string and boolean": {
+			path:    "/spec/lookupPolicy/local",
+			desired: true,
+			current: "true",
+			want:    true,
+		},
+		"string and number outside of scalar path": {
+			path:    "/spec/ports/0/targetPort",
+			desired: "8080",
+			current: float64(8080),
+			want:    false,
+		},
+		"string and boolean outside of scalar path": {
+			path:    "/metadata/annotations/enabled",
+			desired: "true",
+			current: true,
+			want:    false,
+		},
+		"capitalised boolean": {
+			path:    "/spec/lookupPolicy/local",
+			desired: "True",
+			current: true,
+			want:    false,
+		},
+		"different strings": {
+			path:    "/data/foo",
+			desired: "80",
+			current: "80.0",
+			want:    false,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, _ := normalizedEqual(tc.path, tc.desired, tc.current)
+			if got != tc.want {
+				t.Fatalf("Want %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCalculateChangesNamedTargetPort(t *testing.T) {
+	svc := func(targetPort string) []byte {
+		return []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+spec:
+  ports:
+  - port: 80
+    targetPort: ` + targetPort)
+	}
+	platformItem := getItem(t, svc("8080"), "platform")
+	templateItem := getItem(t, svc(`"8080"`), "template")
+	changes, err := calculateChanges(templateItem, platformItem, []string{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes[0].Action != "Update" {
+		t.Fatalf("Want Update, got: %s", changes[0].Action)
+	}
+}
+
+func TestCalculateChangesNormalization(t *testing.T) {
+	pvc := func(storage string) []byte {
+		return []byte(`apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: foo
+spec:
+  accessModes:
+  - ReadWriteOnce
+  resources:
+    requests:
+      storage: ` + storage)
+	}
+	platformItem := getItem(t, pvc("1024Mi"), "platform")
+	templateItem := getItem(t, pvc("1Gi"), "template")
+	// Storage is immutable, so a difference would require recreation.
+	changes, err := calculateChanges(templateItem, platformItem, []string{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes[0].Action != "Noop" {
+		t.Fatalf("Want Noop, got: %s\n%s", changes[0].Action, changes[0].Diff(SecretsRevealed))
+	}
+}
@@ -0,0 +1,86 @@
+package openshift
+
+import (
+	"encoding/json"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+// Ownership marks resources as managed by a Tailor instance, using a label
+// (or annotation) with the ID of the instance as value.
+type Ownership struct {
+	Key        string
+	Value      string
+	Annotation bool
+}
+
+// NewOwnership returns the ownership configured in given options, or nil
+// if no owner is configured.
+func NewOwnership(globalOptions *cli.GlobalOptions) *Ownership {
+	if len(globalOptions.Owner) == 0 {
+		return nil
+	}
+	return &Ownership{
+		Key:        globalOptions.OwnerKey,
+		Value:      globalOptions.Owner,
+		Annotation: globalOptions.OwnerAnnotation,
+	}
+}
+
+// Owner returns the owner the given item is marked with, if any.
+func (o *Ownership) Owner(item *ResourceItem) string {
+	markers := item.Labels
+	if o.Annotation {
+		markers = item.Annotations
+	}
+	owner, _ := markers[o.Key].(string)
+	return owner
+}
+
+// Owns returns true if the given item is marked with this owner.
+func (o *Ownership) Owns(item *ResourceItem) bool {
+	return o.Owner(item) == o.Value
+}
+
+// Stamp marks all items of given list with this owner.
+func (o *Ownership) Stamp(list *ResourceList) error {
+	for i, item := range list.Items {
+		field := "labels"
+		if o.Annotation {
+			field = "annotations"
+		}
+		metadata, ok := item.Config["metadata"].(map[string]interface{})
+		if !ok {
+			metadata = map[string]interface{}{}
+			item.Config["metadata"] = metadata
+		}
+		markers, ok := metadata[field].(map[string]interface{})
+		if !ok {
+			markers = map[string]interface{}{}
+			metadata[field] = markers
+		}
+		markers[o.Key] = o.Value
+		// Parse again so that labels, annotations and paths reflect the mark.
+		stamped, err := NewResourceItem(item.Config, item.Source)
+		if err != nil {
+			return err
+		}
+		list.Items[i] = stamped
+	}
+	return nil
+}
+
+// MergePatch returns a JSON merge patch marking a resource with this owner.
+func (o *Ownership) MergePatch() string {
+	field := "labels"
+	if o.Annotation {
+		field = "annotations"
+	}
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			field: map[string]string{o.Key: o.Value},
+		},
+	}
+	b, _ := json.Marshal(patch)
+	return string(b)
+}
@@ -0,0 +1,110 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewChangesetOwnership(t *testing.T) {
+	platformInput := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: owned
+    labels:
+      tailor.opendevstack.org/owner: foo
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foreign
+    labels:
+      tailor.opendevstack.org/owner: bar
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: unowned
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: adopted
+`)
+	templateInput := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: adopted
+`)
+	tests := map[string]struct {
+		ownership  *Ownership
+		wantDelete []string
+		wantUpdate []string
+	}{
+		"without ownership": {
+			ownership:  nil,
+			wantDelete: []string{"owned", "foreign", "unowned"},
+			wantUpdate: []string{},
+		},
+		"with ownership label": {
+			ownership:  &Ownership{Key: "tailor.opendevstack.org/owner", Value: "foo"},
+			wantDelete: []string{"owned"},
+			wantUpdate: []string{"adopted"},
+		},
+		"with ownership annotation": {
+			ownership:  &Ownership{Key: "tailor.opendevstack.org/owner", Value: "foo", Annotation: true},
+			wantDelete: []string{},
+			wantUpdate: []string{"adopted"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			filter := &ResourceFilter{Kinds: []string{"ConfigMap"}}
+			platformBasedList, err := NewPlatformBasedResourceList(filter, platformInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			templateBasedList, err := NewTemplateBasedResourceList(filter, templateInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.ownership != nil {
+				err = tc.ownership.Stamp(templateBasedList)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			cs, err := NewChangeset(platformBasedList, templateBasedList, false, false, []string{}, tc.ownership, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := func(changes []*Change) []string {
+				n := []string{}
+				for _, c := range changes {
+					n = append(n, c.Name)
+				}
+				return n
+			}
+			if diff := cmp.Diff(tc.wantDelete, names(cs.Delete)); diff != "" {
+				t.Errorf("Delete mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantUpdate, names(cs.Update)); diff != "" {
+				t.Errorf("Update mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOwnershipMergePatch(t *testing.T) {
+	label := &Ownership{Key: "tailor.opendevstack.org/owner", Value: "foo"}
+	if got, want := label.MergePatch(), `{"metadata":{"labels":{"tailor.opendevstack.org/owner":"foo"}}}`; got != want {
+		t.Errorf("Want %s, got: %s", want, got)
+	}
+	annotation := &Ownership{Key: "owner", Value: "foo bar", Annotation: true}
+	if got, want := annotation.MergePatch(), `{"metadata":{"annotations":{"owner":"foo bar"}}}`; got != want {
+		t.Errorf("Want %s, got: %s", want, got)
+	}
+}
@@ -0,0 +1,137 @@
+package openshift
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+// planFormatVersion is increased whenever the plan format changes, so that
+// plans written by another version of Tailor are rejected. Version 2 added
+// the number of targeted resources.
+const planFormatVersion = 2
+
+// Plan is a changeset which has been calculated and saved for later
+// application. It records the resource versions of all targeted resources
+// at the time of calculation so that it can be detected whether the cluster
+// state has changed in the meantime.
+type Plan struct {
+	Version          int               `json:"version"`
+	Namespace        string            `json:"namespace"`
+	Selector         string            `json:"selector"`
+	ResourceVersions map[string]string `json:"resourceVersions"`
+	// Targeted is the number of existing resources targeted when the plan
+	// was created, see Changeset.Targeted.
+	Targeted  int        `json:"targeted"`
+	Changeset *Changeset `json:"changeset"`
+}
+
+// NewPlan creates a plan for given changeset. Noop changes are not part of
+// the plan as there is nothing to apply for them.
+func NewPlan(namespace string, selector string, changeset *Changeset) *Plan {
+	p := &Plan{
+		Version:          planFormatVersion,
+		Namespace:        namespace,
+		Selector:         selector,
+		ResourceVersions: map[string]string{},
+		Targeted:         changeset.Targeted(),
+		Changeset: &Changeset{
+			Create: changeset.Create,
+			Update: changeset.Update,
+			Delete: changeset.Delete,
+			Noop:   []*Change{},
+		},
+	}
+	for _, changes := range [][]*Change{changeset.Delete, changeset.Create, changeset.Update} {
+		for _, c := range changes {
+			// A recreation consists of a delete and a create change, the
+			// delete change holds the version of the existing resource.
+			if _, ok := p.ResourceVersions[c.fullName()]; !ok {
+				p.ResourceVersions[c.fullName()] = c.ResourceVersion
+			}
+		}
+	}
+	return p
+}
+
+// ReadPlan reads a plan from given file.
+func ReadPlan(filename string) (*Plan, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read plan '%s': %s", filename, err)
+	}
+	p := &Plan{}
+	err = json.Unmarshal(b, p)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse plan '%s': %s", filename, err)
+	}
+	if p.Version != planFormatVersion {
+		return nil, fmt.Errorf("Plan '%s' has unsupported version %d", filename, p.Version)
+	}
+	if p.Changeset == nil {
+		return nil, fmt.Errorf("Plan '%s' does not contain a changeset", filename)
+	}
+	return p, nil
+}
+
+// Write saves the plan to given file. As the plan may contain secret values,
+// the file is only readable by the owner.
+func (p *Plan) Write(filename string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Could not serialize plan: %s", err)
+	}
+	return os.WriteFile(filename, b, 0600)
+}
+
+// Filter returns a filter targeting all kinds which are part of the plan.
+func (p *Plan) Filter() *ResourceFilter {
+	kinds := []string{}
+	for fullName := range p.ResourceVersions {
+		kind := strings.SplitN(fullName, "/", 2)[0]
+		if !utils.Includes(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	return &ResourceFilter{Kinds: kinds}
+}
+
+// Verify checks that the resource versions of all targeted resources in the
+// given platform based list are the same as when the plan was created.
+func (p *Plan) Verify(platformBasedList *ResourceList) error {
+	fullNames := []string{}
+	for fullName := range p.ResourceVersions {
+		fullNames = append(fullNames, fullName)
+	}
+	sort.Strings(fullNames)
+
+	changed := []string{}
+	for _, fullName := range fullNames {
+		nameParts := strings.SplitN(fullName, "/", 2)
+		currentVersion := ""
+		item, err := platformBasedList.getItem(nameParts[0], nameParts[1])
+		if err == nil {
+			currentVersion = item.ResourceVersion
+		}
+		if currentVersion != p.ResourceVersions[fullName] {
+			changed = append(changed, fullName)
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf(
+			"The following resources changed since the plan was created: %s.\n"+
+				"Run diff again to create an up-to-date plan",
+			strings.Join(changed, ", "),
+		)
+	}
+	return nil
+}
+
+func (c *Change) fullName() string {
+	return c.Kind + "/" + c.Name
+}
@@ -0,0 +1,113 @@
+package openshift
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPlanVerify(t *testing.T) {
+	platformInput := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+    resourceVersion: "42"
+  data: {}
+`)
+	tests := map[string]struct {
+		changeset   *Changeset
+		wantChanged string
+	}{
+		"Update of unchanged resource": {
+			changeset: &Changeset{
+				Update: []*Change{{Action: "Update", Kind: "ConfigMap", Name: "foo", ResourceVersion: "42"}},
+			},
+		},
+		"Update of changed resource": {
+			changeset: &Changeset{
+				Update: []*Change{{Action: "Update", Kind: "ConfigMap", Name: "foo", ResourceVersion: "41"}},
+			},
+			wantChanged: "ConfigMap/foo",
+		},
+		"Create of resource created in the meantime": {
+			changeset: &Changeset{
+				Create: []*Change{{Action: "Create", Kind: "ConfigMap", Name: "foo"}},
+			},
+			wantChanged: "ConfigMap/foo",
+		},
+		"Delete of resource deleted in the meantime": {
+			changeset: &Changeset{
+				Delete: []*Change{{Action: "Delete", Kind: "ConfigMap", Name: "bar", ResourceVersion: "7"}},
+			},
+			wantChanged: "ConfigMap/bar",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := NewPlan("foo", "", tc.changeset)
+			if diff := cmp.Diff([]string{"ConfigMap"}, p.Filter().Kinds); diff != "" {
+				t.Fatalf("Filter kinds mismatch (-want +got):\n%s", diff)
+			}
+			list, err := NewPlatformBasedResourceList(p.Filter(), platformInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.Verify(list)
+			if len(tc.wantChanged) == 0 {
+				if err != nil {
+					t.Fatalf("Want no error, got: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantChanged) {
+				t.Fatalf("Want error mentioning %s, got: %v", tc.wantChanged, err)
+			}
+		})
+	}
+}
+
+func TestPlanWriteAndRead(t *testing.T) {
+	filename := t.TempDir() + "/tailor.plan"
+	cs := &Changeset{
+		Create: []*Change{{Action: "Create", Kind: "ConfigMap", Name: "foo", DesiredState: "kind: ConfigMap\n"}},
+		Noop:   []*Change{{Action: "Noop", Kind: "ConfigMap", Name: "bar"}},
+	}
+	err := NewPlan("foo", "app=foo", cs).Write(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ReadPlan(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Namespace != "foo" || p.Selector != "app=foo" {
+		t.Fatalf("Unexpected namespace/selector: %s/%s", p.Namespace, p.Selector)
+	}
+	if diff := cmp.Diff(cs.Create, p.Changeset.Create); diff != "" {
+		t.Fatalf("Changes mismatch (-want +got):\n%s", diff)
+	}
+	// Noop changes are dropped, but still count as targeted.
+	if p.Targeted != cs.Targeted() || p.Targeted != 1 {
+		t.Fatalf("Want 1 targeted resource, got: %d", p.Targeted)
+	}
+}
+
+func TestReadPlanUnsupportedVersion(t *testing.T) {
+	filename := t.TempDir() + "/tailor.plan"
+	// Version 1 plans lack the number of targeted resources.
+	err := os.WriteFile(filename, []byte(`{"version": 1, "changeset": {}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadPlan(filename)
+	want := "Plan '" + filename + "' has unsupported version 1"
+	if err == nil || err.Error() != want {
+		t.Fatalf("Want error '%s', got: %v", want, err)
+	}
+}
@@ -0,0 +1,141 @@
+package openshift
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+var selectorSegmentRegex = regexp.MustCompile(`^(.+)\[([^=\]]+)=([^\]]*)\]$`)
+
+// preservedNameMatches returns true if name matches pattern, which is either
+// an exact name, a glob (e.g. "api-*") or a regular expression enclosed in
+// slashes (e.g. "/^api-[0-9]+$/"). Exact names and globs are matched case
+// insensitively, regular expressions as given.
+func preservedNameMatches(pattern string, name string) (bool, error) {
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return false, fmt.Errorf("%s is not a valid regular expression: %s", pattern, err)
+		}
+		return re.MatchString(name), nil
+	}
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	if err != nil {
+		return false, fmt.Errorf("%s is not a valid pattern: %s", pattern, err)
+	}
+	return matched, nil
+}
+
+// resolvePreservePaths resolves path expressions into RFC 6901 pointers
+// within config. Expressions may contain "*" segments, matching every key of
+// an object or element of an array, and element selectors such as
+// "containers[name=app]", matching the elements of an array with the given
+// field value. Plain pointers are returned as-is.
+func resolvePreservePaths(paths []string, config map[string]interface{}) []string {
+	resolved := []string{}
+	for _, p := range paths {
+		if !isPathExpression(p) {
+			resolved = append(resolved, p)
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(p, "/"), "/")
+		pointers := resolveSegments(config, segments, "")
+		cli.DebugMsg("Resolved preserve path", p, "to", "["+strings.Join(pointers, ", ")+"]")
+		resolved = append(resolved, pointers...)
+	}
+	return resolved
+}
+
+func isPathExpression(p string) bool {
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "*" || selectorSegmentRegex.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveSegments(value interface{}, segments []string, pointer string) []string {
+	if len(segments) == 0 {
+		return []string{pointer}
+	}
+	segment, rest := segments[0], segments[1:]
+
+	if segment == "*" {
+		pointers := []string{}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			keys := []string{}
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				pointers = append(pointers, resolveSegments(v[k], rest, pointer+"/"+utils.JSONPointerPath(k))...)
+			}
+		case []interface{}:
+			for i, e := range v {
+				pointers = append(pointers, resolveSegments(e, rest, pointer+"/"+strconv.Itoa(i))...)
+			}
+		}
+		return pointers
+	}
+
+	if match := selectorSegmentRegex.FindStringSubmatch(segment); match != nil {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{}
+		}
+		key := decodePointerSegment(match[1])
+		elements, ok := m[key].([]interface{})
+		if !ok {
+			return []string{}
+		}
+		pointers := []string{}
+		for i, e := range elements {
+			em, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if fv, ok := em[match[2]]; ok && fmt.Sprintf("%v", fv) == match[3] {
+				elementPointer := pointer + "/" + match[1] + "/" + strconv.Itoa(i)
+				pointers = append(pointers, resolveSegments(e, rest, elementPointer)...)
+			}
+		}
+		return pointers
+	}
+
+	var child interface{}
+	found := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, found = v[decodePointerSegment(segment)]
+	case []interface{}:
+		i, err := strconv.Atoi(segment)
+		if err == nil && i >= 0 && i < len(v) {
+			child, found = v[i], true
+		}
+	}
+	if !found {
+		// A missing last segment still needs to be preserved, which
+		// removes it from the desired state.
+		if len(rest) == 0 {
+			if _, ok := value.(map[string]interface{}); ok {
+				return []string{pointer + "/" + segment}
+			}
+		}
+		return []string{}
+	}
+	return resolveSegments(child, rest, pointer+"/"+segment)
+}
+
+func decodePointerSegment(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
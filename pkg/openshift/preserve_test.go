@@ -0,0 +1,168 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolvePreservePaths(t *testing.T) {
+	config := `spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1
+        env:
+        - name: FOO
+          value: foo
+        - name: BUILD_ID
+          value: "42"
+      - name: sidecar
+        image: sidecar:1
+        env:
+        - name: BUILD_ID
+          value: "43"
+`
+	tests := map[string]struct {
+		path string
+		want []string
+	}{
+		"plain pointer": {
+			path: "/spec/replicas",
+			want: []string{"/spec/replicas"},
+		},
+		"wildcard over array": {
+			path: "/spec/template/spec/containers/*/image",
+			want: []string{
+				"/spec/template/spec/containers/0/image",
+				"/spec/template/spec/containers/1/image",
+			},
+		},
+		"element selector": {
+			path: "/spec/template/spec/containers[name=app]/image",
+			want: []string{"/spec/template/spec/containers/0/image"},
+		},
+		"wildcard and element selector": {
+			path: "/spec/template/spec/containers/*/env[name=BUILD_ID]/value",
+			want: []string{
+				"/spec/template/spec/containers/0/env/1/value",
+				"/spec/template/spec/containers/1/env/0/value",
+			},
+		},
+		"missing last segment": {
+			path: "/spec/template/spec/containers/*/command",
+			want: []string{
+				"/spec/template/spec/containers/0/command",
+				"/spec/template/spec/containers/1/command",
+			},
+		},
+		"no matching element": {
+			path: "/spec/template/spec/containers[name=proxy]/image",
+			want: []string{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var m map[string]interface{}
+			err := yaml.Unmarshal([]byte(config), &m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := resolvePreservePaths([]string{tc.path}, m)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Paths mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPreservedNameMatches(t *testing.T) {
+	tests := map[string]struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		"exact":              {pattern: "api", name: "api", want: true},
+		"exact uppercase":    {pattern: "API", name: "api", want: true},
+		"mixed case name":    {pattern: "api-*", name: "Api-V2", want: true},
+		"mixed case both":    {pattern: "API-v*", name: "Api-V2", want: true},
+		"regex case":         {pattern: "/^api-/", name: "Api-V2", want: false},
+		"glob":               {pattern: "api-*", name: "api-v2", want: true},
+		"glob not matching":  {pattern: "api-*", name: "web-v2", want: false},
+		"regex":              {pattern: "/^api-[0-9]+$/", name: "api-2", want: true},
+		"regex not matching": {pattern: "/^api-[0-9]+$/", name: "api-v2", want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := preservedNameMatches(tc.pattern, tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("Want %t, got: %t", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := preservedNameMatches("/[/", "api"); err == nil {
+		t.Fatal("Want error for invalid regular expression")
+	}
+}
+
+func TestConfigPreservePathExpressions(t *testing.T) {
+	templateInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: api-v1
+  spec:
+    replicas: 1
+    template:
+      spec:
+        containers:
+        - name: app
+          image: app:latest
+          env:
+          - name: BUILD_ID
+            value: "0"
+          - name: FOO
+            value: foo`)
+
+	platformInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: api-v1
+  spec:
+    replicas: 3
+    template:
+      spec:
+        containers:
+        - name: app
+          image: app@sha256:abc
+          env:
+          - name: BUILD_ID
+            value: "42"
+          - name: FOO
+            value: foo`)
+
+	filter := &ResourceFilter{
+		Kinds: []string{"DeploymentConfig"},
+	}
+	changeset := getChangeset(t, filter, platformInput, templateInput, false, true, []string{
+		"dc:api-*:/spec/replicas",
+		"dc:/spec/template/spec/containers/*/image",
+		"dc:/spec/template/spec/containers[name=app]/env[name=BUILD_ID]/value",
+	})
+	if len(changeset.Update) != 0 {
+		t.Fatalf("Want no updates, got: %s", changeset.Update[0].Diff(SecretsRevealed))
+	}
+}
@@ -0,0 +1,94 @@
+package openshift
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/ghodss/yaml"
+)
+
+// SecretDisplay defines how drift of Secret resources is shown.
+type SecretDisplay int
+
+const (
+	// SecretsHidden hides the drift of Secret resources.
+	SecretsHidden SecretDisplay = iota
+	// SecretsRedacted shows the drift of Secret resources with values
+	// replaced by salted hashes.
+	SecretsRedacted
+	// SecretsRevealed shows the drift of Secret resources in clear text.
+	SecretsRevealed
+)
+
+const secretDriftHidden = "Secret drift is hidden. Use --reveal-secrets to see details.\n"
+
+var (
+	redactionSalt     []byte
+	redactionSaltErr  error
+	redactionSaltOnce sync.Once
+)
+
+// redactValue replaces v with a salted hash. The salt is random per run, so
+// equal values can be recognised within one diff, but hashes cannot be
+// compared across runs or looked up.
+func redactValue(v interface{}) (string, error) {
+	redactionSaltOnce.Do(func() {
+		redactionSalt = make([]byte, 32)
+		if _, err := rand.Read(redactionSalt); err != nil {
+			redactionSaltErr = fmt.Errorf("Could not generate salt: %s", err)
+		}
+	})
+	if redactionSaltErr != nil {
+		return "", redactionSaltErr
+	}
+	mac := hmac.New(sha256.New, redactionSalt)
+	mac.Write([]byte(fmt.Sprintf("%v", v)))
+	return "<redacted sha256:" + hex.EncodeToString(mac.Sum(nil))[:12] + ">", nil
+}
+
+// redactedState returns the state of a Secret with all values of data and
+// stringData (as well as the last applied configuration, which contains
+// them) replaced by salted hashes. Keys, type, labels and annotations are
+// kept, so that structural changes remain visible.
+func redactedState(state string) (string, error) {
+	if len(state) == 0 {
+		return "", nil
+	}
+	s, err := stateToMap(state)
+	if err != nil {
+		return "", err
+	}
+	m, ok := s.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("Unexpected state of type %T", s)
+	}
+	for _, field := range []string{"data", "stringData"} {
+		if values, ok := m[field].(map[string]interface{}); ok {
+			for k, v := range values {
+				values[k], err = redactValue(v)
+				if err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	if metadata, ok := m["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			if v, ok := annotations["kubectl.kubernetes.io/last-applied-configuration"]; ok {
+				annotations["kubectl.kubernetes.io/last-applied-configuration"], err = redactValue(v)
+				if err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
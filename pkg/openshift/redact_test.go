@@ -0,0 +1,124 @@
+package openshift
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactedDiff(t *testing.T) {
+	tests := map[string]struct {
+		currentState string
+		desiredState string
+		wantChanged  []string
+		wantMissing  []string
+	}{
+		"Modifying a value": {
+			currentState: "kind: Secret\ndata: {password: czNjcjN0, user: Zm9v}\n",
+			desiredState: "kind: Secret\ndata: {password: bmV3, user: Zm9v}\n",
+			wantChanged:  []string{"-  password: <redacted", "+  password: <redacted"},
+			wantMissing:  []string{"czNjcjN0", "bmV3", "Zm9v", "user"},
+		},
+		"Adding a key": {
+			currentState: "kind: Secret\ndata: {password: czNjcjN0}\n",
+			desiredState: "kind: Secret\ndata: {password: czNjcjN0, token: dG9rZW4=}\n",
+			wantChanged:  []string{"+  token: <redacted"},
+			wantMissing:  []string{"czNjcjN0", "dG9rZW4=", "password"},
+		},
+		"Removing a key": {
+			currentState: "kind: Secret\nstringData: {password: s3cr3t, token: t0k3n}\n",
+			desiredState: "kind: Secret\nstringData: {password: s3cr3t}\n",
+			wantChanged:  []string{"-  token: <redacted"},
+			wantMissing:  []string{"s3cr3t", "t0k3n", "password"},
+		},
+		"Modifying the type": {
+			currentState: "kind: Secret\ntype: Opaque\ndata: {password: czNjcjN0}\n",
+			desiredState: "kind: Secret\ntype: kubernetes.io/basic-auth\ndata: {password: czNjcjN0}\n",
+			wantChanged:  []string{"-type: Opaque", "+type: kubernetes.io/basic-auth"},
+			wantMissing:  []string{"czNjcjN0", "password"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Change{
+				Action:       "Update",
+				Kind:         "Secret",
+				Name:         "foo",
+				CurrentState: tc.currentState,
+				DesiredState: tc.desiredState,
+			}
+			got := c.Diff(SecretsRedacted)
+			for _, w := range tc.wantChanged {
+				if !strings.Contains(got, w) {
+					t.Errorf("Want %s in diff, got:\n%s", w, got)
+				}
+			}
+			for _, w := range tc.wantMissing {
+				if regexp.MustCompile(`(?m)^[-+].*` + regexp.QuoteMeta(w)).MatchString(got) {
+					t.Errorf("Want no %s in changed lines, got:\n%s", w, got)
+				}
+			}
+			if strings.Contains(got, "czNjcjN0") || strings.Contains(got, "s3cr3t") {
+				t.Errorf("Want no secret values in diff, got:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	redact := func(v string) string {
+		r, err := redactValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	if redact("foo") != redact("foo") {
+		t.Fatal("Want equal values to have equal hashes")
+	}
+	if redact("foo") == redact("bar") {
+		t.Fatal("Want different values to have different hashes")
+	}
+}
+
+func TestSecretDiffDisplay(t *testing.T) {
+	c := &Change{
+		Action:       "Update",
+		Kind:         "Secret",
+		Name:         "foo",
+		CurrentState: "kind: Secret\ndata: {password: czNjcjN0}\n",
+		DesiredState: "kind: Secret\ndata: {password: bmV3}\n",
+	}
+	tests := map[string]struct {
+		secrets     SecretDisplay
+		wantContain string
+		wantMissing string
+	}{
+		"Hidden": {
+			secrets:     SecretsHidden,
+			wantContain: "Secret drift is hidden",
+			wantMissing: "password",
+		},
+		"Redacted": {
+			secrets:     SecretsRedacted,
+			wantContain: "+  password: <redacted sha256:",
+			wantMissing: "bmV3",
+		},
+		"Revealed": {
+			secrets:     SecretsRevealed,
+			wantContain: "+data: {password: bmV3}",
+			wantMissing: "<redacted",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := c.Diff(tc.secrets)
+			if !strings.Contains(got, tc.wantContain) {
+				t.Errorf("Want %s in diff, got:\n%s", tc.wantContain, got)
+			}
+			if strings.Contains(got, tc.wantMissing) {
+				t.Errorf("Want no %s in diff, got:\n%s", tc.wantMissing, got)
+			}
+		})
+	}
+}
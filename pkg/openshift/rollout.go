@@ -0,0 +1,206 @@
+package openshift
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Possible states of a rollout.
+const (
+	RolloutPending  = "Pending"
+	RolloutComplete = "Complete"
+	RolloutFailed   = "Failed"
+)
+
+// rolloutCheckers determine the rollout state of workload kinds, following
+// the logic of "oc rollout status".
+var rolloutCheckers = map[string]func(m map[string]interface{}) (string, string){
+	"DeploymentConfig": deploymentConfigRollout,
+	"Deployment":       deploymentRollout,
+	"StatefulSet":      statefulSetRollout,
+	"DaemonSet":        daemonSetRollout,
+}
+
+// HasRollout returns true if resources of given kind are rolled out.
+func HasRollout(kind string) bool {
+	_, ok := rolloutCheckers[kind]
+	return ok
+}
+
+// RolloutState returns the state of the rollout of the given resource (in
+// JSON) together with a message describing the progress.
+func RolloutState(kind string, config []byte) (string, string, error) {
+	checker, ok := rolloutCheckers[kind]
+	if !ok {
+		return "", "", fmt.Errorf("%s is not a workload", kind)
+	}
+	var m map[string]interface{}
+	err := json.Unmarshal(config, &m)
+	if err != nil {
+		return "", "", err
+	}
+	if generation, _ := intAt(m, "metadata", "generation"); generation > 0 {
+		if observed, _ := intAt(m, "status", "observedGeneration"); observed < generation {
+			return RolloutPending, "waiting for controller to observe the change", nil
+		}
+	}
+	state, message := checker(m)
+	return state, message, nil
+}
+
+// JobState returns the state of the given Job (in JSON) together with a
+// message describing the progress.
+func JobState(config []byte) (string, string, error) {
+	var m map[string]interface{}
+	err := json.Unmarshal(config, &m)
+	if err != nil {
+		return "", "", err
+	}
+	status, _ := m["status"].(map[string]interface{})
+	conditions, _ := status["conditions"].([]interface{})
+	for _, c := range conditions {
+		condition, _ := c.(map[string]interface{})
+		if condition["status"] != "True" {
+			continue
+		}
+		message, _ := condition["message"].(string)
+		switch condition["type"] {
+		case "Complete":
+			return RolloutComplete, "job completed", nil
+		case "Failed":
+			reason, _ := condition["reason"].(string)
+			return RolloutFailed, strings.TrimSpace(reason + " " + message), nil
+		}
+	}
+	active, _ := intAt(m, "status", "active")
+	return RolloutPending, fmt.Sprintf("%d pod(s) active", active), nil
+}
+
+func deploymentConfigRollout(m map[string]interface{}) (string, string) {
+	if latestVersion, _ := intAt(m, "status", "latestVersion"); latestVersion == 0 {
+		return RolloutComplete, "no rollout triggered"
+	}
+	if reason, message, ok := progressingCondition(m); ok {
+		switch reason {
+		case "ProgressDeadlineExceeded", "RolloutCancelled":
+			return RolloutFailed, message
+		case "NewReplicationControllerAvailable":
+			return replicasRollout(m)
+		}
+	}
+	return RolloutPending, "waiting for latest deployment to complete"
+}
+
+func deploymentRollout(m map[string]interface{}) (string, string) {
+	if reason, message, ok := progressingCondition(m); ok && reason == "ProgressDeadlineExceeded" {
+		return RolloutFailed, message
+	}
+	return replicasRollout(m)
+}
+
+func statefulSetRollout(m map[string]interface{}) (string, string) {
+	if strategy, _ := stringAt(m, "spec", "updateStrategy", "type"); strategy == "OnDelete" {
+		return RolloutComplete, "rollout of OnDelete strategy is not tracked"
+	}
+	replicas := specReplicas(m)
+	if ready, _ := intAt(m, "status", "readyReplicas"); ready < replicas {
+		return RolloutPending, fmt.Sprintf("%d of %d replicas ready", ready, replicas)
+	}
+	if partition, ok := intAt(m, "spec", "updateStrategy", "rollingUpdate", "partition"); ok && partition > 0 {
+		updated, _ := intAt(m, "status", "updatedReplicas")
+		if updated < replicas-partition {
+			return RolloutPending, fmt.Sprintf("%d of %d replicas updated", updated, replicas-partition)
+		}
+		return RolloutComplete, fmt.Sprintf("%d replicas updated (partition %d)", updated, partition)
+	}
+	current, _ := stringAt(m, "status", "currentRevision")
+	update, _ := stringAt(m, "status", "updateRevision")
+	if current != update {
+		updated, _ := intAt(m, "status", "updatedReplicas")
+		return RolloutPending, fmt.Sprintf("%d of %d replicas updated", updated, replicas)
+	}
+	return RolloutComplete, fmt.Sprintf("%d replicas ready", replicas)
+}
+
+func daemonSetRollout(m map[string]interface{}) (string, string) {
+	desired, _ := intAt(m, "status", "desiredNumberScheduled")
+	if updated, _ := intAt(m, "status", "updatedNumberScheduled"); updated < desired {
+		return RolloutPending, fmt.Sprintf("%d of %d pods updated", updated, desired)
+	}
+	if available, _ := intAt(m, "status", "numberAvailable"); available < desired {
+		return RolloutPending, fmt.Sprintf("%d of %d pods available", available, desired)
+	}
+	return RolloutComplete, fmt.Sprintf("%d pods available", desired)
+}
+
+// replicasRollout checks that all replicas are updated and available, and
+// that no old replicas are left.
+func replicasRollout(m map[string]interface{}) (string, string) {
+	replicas := specReplicas(m)
+	updated, _ := intAt(m, "status", "updatedReplicas")
+	if updated < replicas {
+		return RolloutPending, fmt.Sprintf("%d of %d replicas updated", updated, replicas)
+	}
+	if total, _ := intAt(m, "status", "replicas"); total > updated {
+		return RolloutPending, fmt.Sprintf("%d old replicas pending termination", total-updated)
+	}
+	if available, _ := intAt(m, "status", "availableReplicas"); available < updated {
+		return RolloutPending, fmt.Sprintf("%d of %d updated replicas available", available, updated)
+	}
+	return RolloutComplete, fmt.Sprintf("%d replicas available", replicas)
+}
+
+func specReplicas(m map[string]interface{}) int64 {
+	if replicas, ok := intAt(m, "spec", "replicas"); ok {
+		return replicas
+	}
+	return 1
+}
+
+func progressingCondition(m map[string]interface{}) (string, string, bool) {
+	status, _ := m["status"].(map[string]interface{})
+	conditions, _ := status["conditions"].([]interface{})
+	for _, c := range conditions {
+		condition, _ := c.(map[string]interface{})
+		if condition["type"] == "Progressing" {
+			reason, _ := condition["reason"].(string)
+			message, _ := condition["message"].(string)
+			return reason, message, true
+		}
+	}
+	return "", "", false
+}
+
+func valueAt(m map[string]interface{}, keys ...string) (interface{}, bool) {
+	var v interface{} = m
+	for _, k := range keys {
+		vm, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok = vm[k]
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func intAt(m map[string]interface{}, keys ...string) (int64, bool) {
+	v, ok := valueAt(m, keys...)
+	if !ok {
+		return 0, false
+	}
+	f, ok := v.(float64)
+	return int64(f), ok
+}
+
+func stringAt(m map[string]interface{}, keys ...string) (string, bool) {
+	v, ok := valueAt(m, keys...)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
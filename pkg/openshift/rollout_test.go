@@ -0,0 +1,145 @@
+package openshift
+
+import (
+	"testing"
+)
+
+func TestRolloutState(t *testing.T) {
+	tests := map[string]struct {
+		kind        string
+		config      string
+		wantState   string
+		wantMessage string
+	}{
+		"generation not observed yet": {
+			kind:        "Deployment",
+			config:      `{"metadata": {"generation": 2}, "status": {"observedGeneration": 1}}`,
+			wantState:   RolloutPending,
+			wantMessage: "waiting for controller to observe the change",
+		},
+		"deployment with replicas being updated": {
+			kind:        "Deployment",
+			config:      `{"metadata": {"generation": 2}, "spec": {"replicas": 3}, "status": {"observedGeneration": 2, "replicas": 3, "updatedReplicas": 1}}`,
+			wantState:   RolloutPending,
+			wantMessage: "1 of 3 replicas updated",
+		},
+		"deployment with old replicas": {
+			kind:        "Deployment",
+			config:      `{"spec": {"replicas": 2}, "status": {"replicas": 3, "updatedReplicas": 2, "availableReplicas": 2}}`,
+			wantState:   RolloutPending,
+			wantMessage: "1 old replicas pending termination",
+		},
+		"deployment rolled out": {
+			kind:        "Deployment",
+			config:      `{"status": {"replicas": 1, "updatedReplicas": 1, "availableReplicas": 1}}`,
+			wantState:   RolloutComplete,
+			wantMessage: "1 replicas available",
+		},
+		"deployment exceeding progress deadline": {
+			kind:        "Deployment",
+			config:      `{"status": {"conditions": [{"type": "Progressing", "reason": "ProgressDeadlineExceeded", "message": "ReplicaSet \"foo-1\" has timed out progressing."}]}}`,
+			wantState:   RolloutFailed,
+			wantMessage: `ReplicaSet "foo-1" has timed out progressing.`,
+		},
+		"deploymentconfig without deployment": {
+			kind:        "DeploymentConfig",
+			config:      `{"status": {"latestVersion": 0}}`,
+			wantState:   RolloutComplete,
+			wantMessage: "no rollout triggered",
+		},
+		"deploymentconfig in progress": {
+			kind:        "DeploymentConfig",
+			config:      `{"status": {"latestVersion": 2, "conditions": [{"type": "Progressing", "reason": "ReplicationControllerUpdated"}]}}`,
+			wantState:   RolloutPending,
+			wantMessage: "waiting for latest deployment to complete",
+		},
+		"deploymentconfig rolled out": {
+			kind:        "DeploymentConfig",
+			config:      `{"spec": {"replicas": 2}, "status": {"latestVersion": 2, "replicas": 2, "updatedReplicas": 2, "availableReplicas": 2, "conditions": [{"type": "Available"}, {"type": "Progressing", "reason": "NewReplicationControllerAvailable"}]}}`,
+			wantState:   RolloutComplete,
+			wantMessage: "2 replicas available",
+		},
+		"deploymentconfig failed": {
+			kind:        "DeploymentConfig",
+			config:      `{"status": {"latestVersion": 2, "conditions": [{"type": "Progressing", "reason": "ProgressDeadlineExceeded", "message": "replication controller \"foo-2\" has failed progressing"}]}}`,
+			wantState:   RolloutFailed,
+			wantMessage: `replication controller "foo-2" has failed progressing`,
+		},
+		"statefulset with revisions differing": {
+			kind:        "StatefulSet",
+			config:      `{"spec": {"replicas": 2}, "status": {"readyReplicas": 2, "updatedReplicas": 1, "currentRevision": "foo-1", "updateRevision": "foo-2"}}`,
+			wantState:   RolloutPending,
+			wantMessage: "1 of 2 replicas updated",
+		},
+		"statefulset rolled out": {
+			kind:        "StatefulSet",
+			config:      `{"spec": {"replicas": 2}, "status": {"readyReplicas": 2, "updatedReplicas": 2, "currentRevision": "foo-2", "updateRevision": "foo-2"}}`,
+			wantState:   RolloutComplete,
+			wantMessage: "2 replicas ready",
+		},
+		"statefulset with partition": {
+			kind:        "StatefulSet",
+			config:      `{"spec": {"replicas": 3, "updateStrategy": {"type": "RollingUpdate", "rollingUpdate": {"partition": 2}}}, "status": {"readyReplicas": 3, "updatedReplicas": 1, "currentRevision": "foo-1", "updateRevision": "foo-2"}}`,
+			wantState:   RolloutComplete,
+			wantMessage: "1 replicas updated (partition 2)",
+		},
+		"daemonset with pods unavailable": {
+			kind:        "DaemonSet",
+			config:      `{"status": {"desiredNumberScheduled": 3, "updatedNumberScheduled": 3, "numberAvailable": 2}}`,
+			wantState:   RolloutPending,
+			wantMessage: "2 of 3 pods available",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			state, message, err := RolloutState(tc.kind, []byte(tc.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state != tc.wantState {
+				t.Errorf("Want state %s, got: %s", tc.wantState, state)
+			}
+			if message != tc.wantMessage {
+				t.Errorf("Want message %q, got: %q", tc.wantMessage, message)
+			}
+		})
+	}
+}
+
+func TestJobState(t *testing.T) {
+	tests := map[string]struct {
+		config      string
+		wantState   string
+		wantMessage string
+	}{
+		"running": {
+			config:      `{"status": {"active": 1}}`,
+			wantState:   RolloutPending,
+			wantMessage: "1 pod(s) active",
+		},
+		"completed": {
+			config:      `{"status": {"succeeded": 1, "conditions": [{"type": "Complete", "status": "True"}]}}`,
+			wantState:   RolloutComplete,
+			wantMessage: "job completed",
+		},
+		"failed": {
+			config:      `{"status": {"failed": 6, "conditions": [{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded", "message": "Job has reached the specified backoff limit"}]}}`,
+			wantState:   RolloutFailed,
+			wantMessage: "BackoffLimitExceeded Job has reached the specified backoff limit",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			state, message, err := JobState([]byte(tc.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state != tc.wantState {
+				t.Errorf("Want state %s, got: %s", tc.wantState, state)
+			}
+			if message != tc.wantMessage {
+				t.Errorf("Want message %q, got: %q", tc.wantMessage, message)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package openshift
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+// bundledSchemas are subsets of the OpenAPI schemas of OpenShift, covering the
+// kinds typically managed by Tailor. They are referenced by file name without
+// extension, e.g. "openshift-4".
+//
+//go:embed schemas/*.json
+var bundledSchemas embed.FS
+
+// lenientDefinitions accept numbers as well as strings, like the API server.
+var lenientDefinitions = []string{
+	"io.k8s.apimachinery.pkg.util.intstr.IntOrString",
+	"io.k8s.apimachinery.pkg.api.resource.Quantity",
+}
+
+// Schema holds the definitions of an OpenAPI (Swagger 2.0) document, such as
+// the one served by "oc get --raw /openapi/v2".
+type Schema struct {
+	Definitions map[string]*schemaNode `json:"definitions"`
+	// kinds maps "apiVersion/kind" and "kind" to a definition.
+	kinds map[string]string
+}
+
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Format               string                 `json:"format"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	Items                *schemaNode            `json:"items"`
+	AdditionalProperties *schemaOrBool          `json:"additionalProperties"`
+	GroupVersionKinds    []struct {
+		Group   string `json:"group"`
+		Version string `json:"version"`
+		Kind    string `json:"kind"`
+	} `json:"x-kubernetes-group-version-kind"`
+}
+
+// schemaOrBool is either a schema, or a boolean allowing any or no value.
+type schemaOrBool struct {
+	Allows bool
+	Schema *schemaNode
+}
+
+func (s *schemaOrBool) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &s.Allows); err == nil {
+		return nil
+	}
+	s.Allows = true
+	return json.Unmarshal(b, &s.Schema)
+}
+
+// SchemaViolation describes a field of a processed template item which does
+// not match the schema.
+type SchemaViolation struct {
+	Template string
+	Item     string
+	Pointer  string
+	Message  string
+}
+
+func (v *SchemaViolation) String() string {
+	return fmt.Sprintf("%s: %s %s: %s", v.Template, v.Item, v.Pointer, v.Message)
+}
+
+// BundledSchemaNames returns the names of the schemas bundled with Tailor.
+func BundledSchemaNames() []string {
+	entries, _ := bundledSchemas.ReadDir("schemas")
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return names
+}
+
+// LoadSchema loads the bundled schema with given name, or else the schema
+// from given JSON or YAML file.
+func LoadSchema(nameOrFile string) (*Schema, error) {
+	content, err := bundledSchemas.ReadFile("schemas/" + nameOrFile + ".json")
+	if err != nil {
+		content, err = os.ReadFile(nameOrFile)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Schema '%s' is neither bundled (%s) nor a readable file: %s",
+				nameOrFile,
+				strings.Join(BundledSchemaNames(), ", "),
+				err,
+			)
+		}
+	}
+	s := &Schema{}
+	err = yaml.Unmarshal(content, s)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse schema '%s': %s", nameOrFile, err)
+	}
+	if len(s.Definitions) == 0 {
+		return nil, fmt.Errorf("Schema '%s' does not contain any definitions", nameOrFile)
+	}
+	s.kinds = map[string]string{}
+	names := []string{}
+	for name := range s.Definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, gvk := range s.Definitions[name].GroupVersionKinds {
+			apiVersion := gvk.Version
+			if len(gvk.Group) > 0 {
+				apiVersion = gvk.Group + "/" + gvk.Version
+			}
+			s.kinds[apiVersion+"/"+gvk.Kind] = name
+			if _, ok := s.kinds[gvk.Kind]; !ok {
+				s.kinds[gvk.Kind] = name
+			}
+		}
+	}
+	return s, nil
+}
+
+// ValidateTemplate checks every item of the list, parsed from the processed
+// template, against the schema. Unknown fields and type mismatches are
+// returned as violations. Items of kinds not covered by the schema are skipped.
+func (s *Schema) ValidateTemplate(template string, list *ResourceList) []*SchemaViolation {
+	violations := []*SchemaViolation{}
+	for _, item := range list.Items {
+		name, ok := s.definitionFor(item)
+		if !ok {
+			cli.DebugMsg("No schema for", item.FullName(), "in", template)
+			continue
+		}
+		for _, v := range s.validate(item.Config, s.Definitions[name], "") {
+			v.Template = template
+			v.Item = item.FullName()
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+func (s *Schema) definitionFor(item *ResourceItem) (string, bool) {
+	if apiVersion, ok := item.Config["apiVersion"].(string); ok {
+		if name, ok := s.kinds[apiVersion+"/"+item.Kind]; ok {
+			return name, true
+		}
+	}
+	name, ok := s.kinds[item.Kind]
+	return name, ok
+}
+
+func (s *Schema) validate(v interface{}, node *schemaNode, pointer string) []*SchemaViolation {
+	lenient := false
+	for node != nil && len(node.Ref) > 0 {
+		name := strings.TrimPrefix(node.Ref, "#/definitions/")
+		for _, l := range lenientDefinitions {
+			if name == l {
+				lenient = true
+			}
+		}
+		node = s.Definitions[name]
+	}
+	// Unknown definitions and null values are not checked.
+	if node == nil || v == nil {
+		return nil
+	}
+	if node.Format == "int-or-string" {
+		lenient = true
+	}
+	if msg := typeMismatch(v, node.Type, lenient); len(msg) > 0 {
+		return []*SchemaViolation{{Pointer: pointerOrRoot(pointer), Message: msg}}
+	}
+
+	violations := []*SchemaViolation{}
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		keys := []string{}
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := pointer + "/" + strings.Replace(strings.Replace(k, "~", "~0", -1), "/", "~1", -1)
+			if prop, ok := node.Properties[k]; ok {
+				violations = append(violations, s.validate(vv[k], prop, p)...)
+			} else if node.AdditionalProperties != nil {
+				if node.AdditionalProperties.Allows {
+					violations = append(violations, s.validate(vv[k], node.AdditionalProperties.Schema, p)...)
+				} else {
+					violations = append(violations, &SchemaViolation{Pointer: p, Message: "unknown field"})
+				}
+			} else if len(node.Properties) > 0 {
+				violations = append(violations, &SchemaViolation{Pointer: p, Message: "unknown field"})
+			}
+		}
+	case []interface{}:
+		for i, e := range vv {
+			violations = append(violations, s.validate(e, node.Items, fmt.Sprintf("%s/%d", pointer, i))...)
+		}
+	}
+	return violations
+}
+
+// typeMismatch returns a message if v does not match the given type.
+func typeMismatch(v interface{}, typ string, lenient bool) string {
+	got := ""
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		got = "object"
+	case []interface{}:
+		got = "array"
+	case string:
+		got = "string"
+	case bool:
+		got = "boolean"
+	case float64:
+		got = "number"
+		if vv == math.Trunc(vv) {
+			got = "integer"
+		}
+	}
+	switch {
+	case len(typ) == 0 || typ == got:
+		return ""
+	case typ == "number" && got == "integer":
+		return ""
+	case lenient && typ == "string" && (got == "integer" || got == "number"):
+		return ""
+	}
+	return fmt.Sprintf("expected %s, got %s", typ, got)
+}
+
+func pointerOrRoot(pointer string) string {
+	if len(pointer) == 0 {
+		return "/"
+	}
+	return pointer
+}
@@ -0,0 +1,169 @@
+package openshift
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	dc := `apiVersion: v1
+kind: List
+items:
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: foo
+  spec:
+    %s
+    template:
+      spec:
+        containers:
+        - name: foo
+          image: foo:latest
+          ports:
+          - containerPort: 8080
+          readinessProbe:
+            httpGet: {path: /health, port: 8080}
+          resources:
+            limits: {cpu: 1, memory: 1Gi}
+          %s
+`
+	tests := map[string]struct {
+		schema    string
+		spec      string
+		container string
+		want      []string
+	}{
+		"valid": {
+			schema:    "openshift-4",
+			spec:      "replicas: 1",
+			container: "imagePullPolicy: Always",
+			want:      []string{},
+		},
+		"unknown fields": {
+			schema:    "openshift-4",
+			spec:      "replcas: 1",
+			container: "imagePulPolicy: Always",
+			want: []string{
+				"dc.yml: DeploymentConfig/foo /spec/replcas: unknown field",
+				"dc.yml: DeploymentConfig/foo /spec/template/spec/containers/0/imagePulPolicy: unknown field",
+			},
+		},
+		"type mismatches": {
+			schema:    "openshift-4",
+			spec:      `replicas: "1"`,
+			container: "args: foo",
+			want: []string{
+				"dc.yml: DeploymentConfig/foo /spec/replicas: expected integer, got string",
+				"dc.yml: DeploymentConfig/foo /spec/template/spec/containers/0/args: expected array, got string",
+			},
+		},
+		"field unknown to older version": {
+			schema:    "openshift-3.11",
+			spec:      "replicas: 1",
+			container: "startupProbe: {tcpSocket: {port: 8080}}",
+			want: []string{
+				"dc.yml: DeploymentConfig/foo /spec/template/spec/containers/0/startupProbe: unknown field",
+			},
+		},
+		"field known to newer version": {
+			schema:    "openshift-4",
+			spec:      "replicas: 1",
+			container: "startupProbe: {tcpSocket: {port: 8080}}",
+			want:      []string{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := LoadSchema(tc.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := NewResourceFilter("", "", []string{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			processed := []byte(fmt.Sprintf(dc, tc.spec, tc.container))
+			list, err := NewTemplateBasedResourceList(filter, processed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			violations := s.ValidateTemplate("dc.yml", list)
+			got := []string{}
+			for _, v := range violations {
+				got = append(got, v.String())
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Violations mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateTemplateWithSchemaFile(t *testing.T) {
+	schemaFile := t.TempDir() + "/openapi.yml"
+	err := os.WriteFile(schemaFile, []byte(`definitions:
+  com.example.v1.Widget:
+    type: object
+    x-kubernetes-group-version-kind: [{group: example.com, version: v1, kind: Widget}]
+    properties:
+      apiVersion: {type: string}
+      kind: {type: string}
+      metadata: {type: object}
+      spec:
+        type: object
+        properties:
+          size: {type: integer}
+          labels: {type: object, additionalProperties: {type: string}}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSchema(schemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := NewResourceFilter("", "", []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	processed := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: example.com/v1
+  kind: Widget
+  metadata: {name: foo}
+  spec: {size: 1.5, labels: {a/b: 1}, colour: red}
+- apiVersion: v1
+  kind: ConfigMap
+  metadata: {name: foo}
+  data: {foo: bar}
+`)
+	list, err := NewTemplateBasedResourceList(filter, processed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations := s.ValidateTemplate("widget.yml", list)
+	got := []string{}
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	want := []string{
+		"widget.yml: Widget/foo /spec/colour: unknown field",
+		"widget.yml: Widget/foo /spec/labels/a~1b: expected string, got integer",
+		"widget.yml: Widget/foo /spec/size: expected integer, got number",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Violations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadSchemaUnknown(t *testing.T) {
+	_, err := LoadSchema("openshift-2")
+	if err == nil {
+		t.Fatal("Want error for unknown schema, got none")
+	}
+}
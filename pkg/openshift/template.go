@@ -121,13 +121,37 @@ func templateContainsTailorNamespaceParam(filename string) (bool, error) {
 }
 
 func calculateParamFiles(name string, paramDir string, compareOptions *cli.CompareOptions) []string {
-	files := compareOptions.ParamFiles
+	files := []string{}
+	for _, f := range ParamFilesFor(name, paramDir, compareOptions) {
+		files = append(files, f.File)
+	}
+	return files
+}
+
+// ParamFile is a param file used to process a template.
+type ParamFile struct {
+	File string
+	// Origin describes why the file is used, e.g. "param-dir".
+	Origin string
+}
+
+// ParamFilesFor returns the param files used to process the template with
+// given name. These are the given param files, or else the file named after
+// the template in the param dir (or <namespace> folder), plus the
+// <namespace>.env file by convention.
+func ParamFilesFor(name string, paramDir string, compareOptions *cli.CompareOptions) []*ParamFile {
+	files := []*ParamFile{}
+	for _, f := range compareOptions.ParamFiles {
+		files = append(files, &ParamFile{File: f, Origin: "param-file"})
+	}
 	// If param-file is not given, we assume a param-dir
 	if len(files) == 0 {
+		origin := "param-dir"
 		// Prefer <namespace> folder over current directory
 		if paramDir == "." {
 			if _, err := os.Stat(compareOptions.Namespace); err == nil {
 				paramDir = compareOptions.Namespace
+				origin = "convention (<namespace> folder)"
 			}
 		}
 
@@ -140,15 +164,19 @@ func calculateParamFiles(name string, paramDir string, compareOptions *cli.Compa
 			f = paramDir + string(os.PathSeparator) + f
 		}
 		if compareOptions.FileExists(f) {
-			files = []string{f}
+			files = []*ParamFile{{File: f, Origin: origin}}
 		}
 	}
 	// Add <namespace>.env file if it exists
 	namespaceDotEnvFile := fmt.Sprintf("%s.env", compareOptions.Namespace)
-	if !utils.Includes(files, namespaceDotEnvFile) {
+	fileNames := []string{}
+	for _, f := range files {
+		fileNames = append(fileNames, f.File)
+	}
+	if !utils.Includes(fileNames, namespaceDotEnvFile) {
 		if compareOptions.FileExists(namespaceDotEnvFile) {
 			cli.DebugMsg(fmt.Sprintf("Adding param file '%s' by convention", namespaceDotEnvFile))
-			files = append(files, namespaceDotEnvFile)
+			files = append(files, &ParamFile{File: namespaceDotEnvFile, Origin: "convention (<namespace>.env)"})
 		}
 	}
 	return files